@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vibesql/vibe/internal/config"
+	"github.com/vibesql/vibe/internal/server"
+)
+
+const keysUsage = `usage:
+  vibe keys create <name> [--scope read-only|read-write|admin] [--role <role>] [flags]
+  vibe keys list [flags]
+  vibe keys revoke <id> [flags]`
+
+// runKeys manages the API keys in the configured keys file
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return runKeysCreate(args[1:])
+	case "list":
+		return runKeysList(args[1:])
+	case "revoke":
+		return runKeysRevoke(args[1:])
+	}
+	return fmt.Errorf("unknown keys command %q\n%s", args[0], keysUsage)
+}
+
+func runKeysCreate(args []string) error {
+	name, args, ok := positionalArg(args)
+	if !ok {
+		return fmt.Errorf("usage: vibe keys create <name> [--scope read-only|read-write|admin] [--role <role>] [flags]")
+	}
+
+	var scope, role string
+	cfg, err := config.LoadWithFlags(args, func(fs *flag.FlagSet) {
+		fs.StringVar(&scope, "scope", server.ScopeReadOnly, "Key scope: "+strings.Join(server.Scopes, ", "))
+		fs.StringVar(&role, "role", "", "PostgreSQL role the key's queries run as (default: postgres.role)")
+	})
+	if err != nil {
+		return err
+	}
+
+	store, err := server.LoadKeyStore(cfg.KeysFile)
+	if err != nil {
+		return err
+	}
+	first := len(store.Keys) == 0
+
+	key, secret, err := store.Create(name, scope)
+	if err != nil {
+		return err
+	}
+	key.Role = strings.TrimSpace(role)
+	if err := store.Save(cfg.KeysFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s key %s (%s) in %s\n", key.Scope, key.ID, key.Name, cfg.KeysFile)
+	if key.Role != "" {
+		fmt.Printf("Its queries run as PostgreSQL role %s.\n", key.Role)
+	}
+	fmt.Println()
+	fmt.Printf("  %s\n\n", secret)
+	fmt.Println("Store this key now; it cannot be shown again.")
+	if first {
+		fmt.Println("Every request now needs an API key in an 'Authorization: Bearer' header.")
+	}
+	return nil
+}
+
+func runKeysList(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	store, err := server.LoadKeyStore(cfg.KeysFile)
+	if err != nil {
+		return err
+	}
+	if len(store.Keys) == 0 {
+		fmt.Printf("No API keys in %s\n", cfg.KeysFile)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPE\tROLE\tCREATED\tSTATUS")
+	for _, key := range store.Keys {
+		status := "active"
+		if key.Revoked() {
+			status = "revoked " + key.RevokedAt.Local().Format(time.DateTime)
+		}
+		role := key.Role
+		if role == "" {
+			role = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Scope, role, key.CreatedAt.Local().Format(time.DateTime), status)
+	}
+	return w.Flush()
+}
+
+func runKeysRevoke(args []string) error {
+	id, args, ok := positionalArg(args)
+	if !ok {
+		return fmt.Errorf("usage: vibe keys revoke <id> [flags]")
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	store, err := server.LoadKeyStore(cfg.KeysFile)
+	if err != nil {
+		return err
+	}
+	key, err := store.Revoke(id)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(cfg.KeysFile); err != nil {
+		return err
+	}
+
+	fmt.Printf("Revoked %s key %s (%s)\n", key.Scope, key.ID, key.Name)
+	return nil
+}
+
+// positionalArg splits the leading non-flag argument from args
+func positionalArg(args []string) (string, []string, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args, false
+	}
+	return args[0], args[1:], true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/server"
+)
+
+func TestRunKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	var err error
+	output := captureOutput(func() {
+		err = runKeys([]string{"create", "ci", "--scope", "read-write", "--keys-file", path})
+	})
+	if err != nil {
+		t.Fatalf("keys create error = %v", err)
+	}
+	if !strings.Contains(output, "vibe_") || !strings.Contains(output, "cannot be shown again") {
+		t.Errorf("Expected the new key in output, got: %s", output)
+	}
+
+	store, err := server.LoadKeyStore(path)
+	if err != nil || len(store.Keys) != 1 {
+		t.Fatalf("Expected one stored key, got %v, %v", store, err)
+	}
+	id := store.Keys[0].ID
+
+	output = captureOutput(func() {
+		err = runKeys([]string{"list", "--keys-file", path})
+	})
+	if err != nil || !strings.Contains(output, id) || !strings.Contains(output, "read-write") || !strings.Contains(output, "active") {
+		t.Errorf("keys list = %q, %v", output, err)
+	}
+
+	output = captureOutput(func() {
+		err = runKeys([]string{"revoke", id, "--keys-file", path})
+	})
+	if err != nil || !strings.Contains(output, "Revoked") {
+		t.Errorf("keys revoke = %q, %v", output, err)
+	}
+
+	output = captureOutput(func() {
+		err = runKeys([]string{"list", "--keys-file", path})
+	})
+	if err != nil || !strings.Contains(output, "revoked") {
+		t.Errorf("Expected the key to be listed as revoked, got %q, %v", output, err)
+	}
+
+	output = captureOutput(func() {
+		err = runKeys([]string{"create", "bi", "--role", "analyst", "--keys-file", path})
+	})
+	if err != nil || !strings.Contains(output, "role analyst") {
+		t.Errorf("keys create --role = %q, %v", output, err)
+	}
+	output = captureOutput(func() {
+		err = runKeys([]string{"list", "--keys-file", path})
+	})
+	if err != nil || !strings.Contains(output, "analyst") {
+		t.Errorf("Expected the key's role to be listed, got %q, %v", output, err)
+	}
+}
+
+func TestRunKeys_InvalidArguments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	tests := [][]string{
+		nil,
+		{"rotate"},
+		{"create"},
+		{"create", "--scope", "admin"},
+		{"create", "ci", "--scope", "root", "--keys-file", path},
+		{"revoke"},
+		{"revoke", "missing", "--keys-file", path},
+	}
+
+	for _, args := range tests {
+		var err error
+		captureOutput(func() {
+			err = runKeys(args)
+		})
+		if err == nil {
+			t.Errorf("runKeys(%q) expected an error", args)
+		}
+	}
+}
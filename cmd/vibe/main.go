@@ -1,11 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/vibesql/vibe/internal/config"
+	"github.com/vibesql/vibe/internal/pgproxy"
 	"github.com/vibesql/vibe/internal/postgres"
 	"github.com/vibesql/vibe/internal/query"
 	"github.com/vibesql/vibe/internal/server"
@@ -19,14 +26,28 @@ Usage:
   vibe <command> [options]
 
 Commands:
-  serve      Start the HTTP server and embedded PostgreSQL
-  version    Print version information
-  help       Display this help message
+  serve          Start the HTTP server and embedded PostgreSQL
+  config print   Print the effective configuration and where each value came from
+  keys create    Create an API key: vibe keys create <name> --scope read-only|read-write|admin
+  keys list      List API keys
+  keys revoke    Revoke an API key: vibe keys revoke <id>
+  version        Print version information
+  help           Display this help message
+
+Configuration (highest precedence first):
+  1. Flags on 'vibe serve' / 'vibe config print' (run 'vibe serve -h' for the list)
+  2. Environment variables (VIBESQL_PORT, VIBESQL_DATA, ...)
+  3. Config file: --config, VIBESQL_CONFIG, or vibe.toml / vibe.yaml in the working directory
+  4. Built-in defaults
 
 Examples:
-  vibe serve           Start server on 127.0.0.1:5173
-  vibe version         Show version and build info
-  vibe help            Show this help
+  vibe serve                   Start server on 127.0.0.1:5173
+  vibe serve --port 8080       Start server on port 8080
+  vibe config print            Show effective settings and their sources
+  vibe keys create ci --scope read-write
+                               Create a read-write API key named "ci"
+  vibe version                 Show version and build info
+  vibe help                    Show this help
 
 For more information, visit: https://vibesql.dev
 `
@@ -42,10 +63,29 @@ func main() {
 
 	switch command {
 	case "serve":
-		if err := runServe(); err != nil {
+		if err := runServe(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
 			log.Printf("[FATAL] %v", err)
 			os.Exit(1)
 		}
+	case "config":
+		if err := runConfig(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "keys":
+		if err := runKeys(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "version":
 		printVersion()
 	case "help", "--help", "-h":
@@ -57,10 +97,19 @@ func main() {
 	}
 }
 
-func runServe() error {
+func runServe(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
 	log.Printf("[INFO] Starting VibeSQL %s", version.Get().Short())
+	if cfg.File != "" {
+		log.Printf("[INFO] Loaded configuration from %s", cfg.File)
+	}
+	log.Printf("[INFO] Safety profile: %s", cfg.SafetyProfile)
 
-	pgManager := postgres.NewManager("", 5433) // Use 5433 to avoid conflict with system PostgreSQL
+	pgManager := postgres.NewManager(cfg.DataDir, cfg.PostgresPort)
 	
 	log.Printf("[INFO] Starting PostgreSQL...")
 	startTime := time.Now()
@@ -77,7 +126,7 @@ func runServe() error {
 	pgStartupTime := time.Since(startTime)
 	log.Printf("[INFO] PostgreSQL started in %v", pgStartupTime)
 
-	conn, err := pgManager.CreateConnection()
+	conn, err := pgManager.CreateConnectionWithOptions(cfg.ConnectionOptions())
 	if err != nil {
 		return fmt.Errorf("failed to create database connection: %w", err)
 	}
@@ -87,9 +136,29 @@ func runServe() error {
 		}
 	}()
 
-	executor := query.NewExecutor(conn.DB())
+	if err := checkRoles(conn, cfg); err != nil {
+		return err
+	}
+
+	executor := query.NewExecutorWithLimits(conn.DB(), cfg.Limits())
+	defer executor.Close()
 
-	httpServer := server.NewServer(executor)
+	httpServer := server.NewServerWithOptions(executor, server.Options{
+		Host:                 cfg.Host,
+		Port:                 cfg.Port,
+		MaxConnections:       cfg.MaxConnections,
+		Limits:               cfg.Limits(),
+		Policy:               cfg.Policy(),
+		KeysFile:             cfg.KeysFile,
+		AllowUnauthenticated: cfg.AllowUnauthenticated,
+		HMACSecret:           cfg.HMACSecret,
+		HMACWindow:           cfg.HMACWindow,
+		TLS:                  cfg.TLSOptions(),
+		SocketPath:           cfg.SocketPath,
+		SocketMode:           cfg.SocketMode,
+		DisableTCP:           !cfg.TCP,
+	})
+	httpServer.SetDatabaseProbe(postgres.NewHealthProbe(pgManager, conn))
 	
 	log.Printf("[INFO] Starting HTTP server...")
 	if err := httpServer.Start(); err != nil {
@@ -101,9 +170,38 @@ func runServe() error {
 		}
 	}()
 
+	if cfg.ProxyEnabled {
+		user, password := pgManager.GetAppCredentials()
+		proxy := pgproxy.New(pgproxy.Options{
+			Addr:           net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.ProxyPort)),
+			Upstream:       net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.PostgresPort)),
+			User:           user,
+			Password:       password,
+			Auth:           server.NewAuthenticator(cfg.KeysFile, cfg.Policy()),
+			MaxConnections: cfg.ProxyMaxConnections,
+			TLS:            httpServer.TLSConfig("postgresql"),
+		})
+		if err := proxy.Start(); err != nil {
+			return fmt.Errorf("failed to start PostgreSQL proxy: %w", err)
+		}
+		defer func() {
+			if err := proxy.Stop(); err != nil {
+				log.Printf("[ERROR] Failed to stop PostgreSQL proxy: %v", err)
+			}
+		}()
+	}
+
 	totalStartupTime := time.Since(startTime)
 	log.Printf("[INFO] VibeSQL ready in %v", totalStartupTime)
-	log.Printf("[INFO] HTTP API: http://%s", httpServer.Addr())
+	if cfg.TCP {
+		log.Printf("[INFO] HTTP API: %s", httpServer.URL())
+	}
+	if cfg.SocketPath != "" {
+		log.Printf("[INFO] HTTP API socket: %s", cfg.SocketPath)
+	}
+	if cfg.ProxyEnabled {
+		log.Printf("[INFO] PostgreSQL protocol: %s", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.ProxyPort)))
+	}
 	log.Printf("[INFO] Press Ctrl+C to stop")
 
 	httpServer.WaitForShutdown()
@@ -112,6 +210,44 @@ func runServe() error {
 	return nil
 }
 
+// checkRoles checks the roles queries run as, postgres.role and those of
+// the API keys, before the server accepts queries. Keys created later are
+// checked by PostgreSQL when their queries switch role.
+func checkRoles(conn *postgres.Connection, cfg *config.Config) error {
+	var roles []string
+	if cfg.PostgresRole != "" {
+		roles = append(roles, cfg.PostgresRole)
+	}
+	if cfg.KeysFile != "" {
+		store, err := server.LoadKeyStore(cfg.KeysFile)
+		if err != nil {
+			return err
+		}
+		roles = append(roles, store.Roles()...)
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+
+	if err := postgres.CheckRoles(conn.DB(), roles); err != nil {
+		return fmt.Errorf("invalid PostgreSQL role: %w", err)
+	}
+	log.Printf("[INFO] Queries may run as PostgreSQL roles: %s", strings.Join(roles, ", "))
+	return nil
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: vibe config print [flags]")
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return err
+	}
+	return cfg.Print(os.Stdout)
+}
+
 func printVersion() {
 	info := version.Get()
 	fmt.Println(info.Full())
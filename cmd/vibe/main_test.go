@@ -189,6 +189,34 @@ func TestMainUnknownCommand(t *testing.T) {
 	}
 }
 
+func TestMainConfigPrint(t *testing.T) {
+	if os.Getenv("TEST_MAIN_CONFIG_PRINT") == "1" {
+		os.Args = []string{"vibe", "config", "print", "--port", "8080"}
+		main()
+		return
+	}
+
+	output := runMainWithArgs(t, []string{"vibe", "config", "print"}, "TEST_MAIN_CONFIG_PRINT")
+
+	if !strings.Contains(output, "server.port") || !strings.Contains(output, "flag (--port)") {
+		t.Errorf("Expected server.port from --port in output, got: %s", output)
+	}
+}
+
+func TestMainConfigMissingSubcommand(t *testing.T) {
+	if os.Getenv("TEST_MAIN_CONFIG_MISSING") == "1" {
+		os.Args = []string{"vibe", "config"}
+		main()
+		return
+	}
+
+	output := runMainWithArgsExpectError(t, []string{"vibe", "config"}, "TEST_MAIN_CONFIG_MISSING")
+
+	if !strings.Contains(output, "vibe config print") {
+		t.Errorf("Expected usage hint, got: %s", output)
+	}
+}
+
 func captureOutput(f func()) string {
 	old := os.Stdout
 	r, w, _ := os.Pipe()
@@ -0,0 +1,844 @@
+// Package config loads VibeSQL settings from defaults, an optional config
+// file (vibe.toml or vibe.yaml), environment variables and command-line
+// flags, in increasing order of precedence.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/pgproxy"
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+	"github.com/vibesql/vibe/internal/server"
+)
+
+const (
+	// EnvConfigFile names the config file to load instead of searching
+	// the working directory
+	EnvConfigFile = "VIBESQL_CONFIG"
+
+	defaultDataDir      = "./vibe-data"
+	defaultPostgresPort = 5433 // Avoids conflicts with a system PostgreSQL on 5432
+
+	// keysFileName is the default API keys file, inside the data directory
+	keysFileName = "vibe-keys.json"
+
+	// selfSignedDir is where the self-signed certificate is kept, inside
+	// the data directory
+	selfSignedDir = "vibe-tls"
+)
+
+// DefaultConfigFiles are searched for in the working directory, in order,
+// when no config file is given explicitly
+var DefaultConfigFiles = []string{"vibe.toml", "vibe.yaml", "vibe.yml"}
+
+// Config holds the effective settings for a VibeSQL server
+type Config struct {
+	// HTTP server
+	Host           string
+	Port           int
+	MaxConnections int
+	// SocketPath, when set, also serves the API on a Unix domain socket
+	SocketPath string
+	SocketMode os.FileMode
+	// TCP serves the API on Host and Port
+	TCP bool
+
+	// Embedded PostgreSQL
+	DataDir      string
+	PostgresPort int
+	MaxOpenConns int
+	MaxIdleConns int
+	// PostgresRole is the role queries run as, empty for the role vibe
+	// connects as
+	PostgresRole string
+
+	// PostgreSQL protocol proxy
+	ProxyEnabled        bool
+	ProxyPort           int
+	ProxyMaxConnections int
+
+	// Query limits
+	QueryTimeout  time.Duration
+	MaxResultRows int
+	MaxQuerySize  int
+	// MaxTransactions caps open interactive transactions and cursors,
+	// which each hold a pool connection
+	MaxTransactions int
+	ImportTimeout   time.Duration
+
+	// Safety policy
+	SafetyProfile string
+	SafetyAllow   []query.StatementClass
+	SafetyDeny    []query.StatementClass
+	// ForbiddenFunctions replaces the default forbidden functions when set
+	ForbiddenFunctions []string
+
+	// Authentication
+	KeysFile string
+	// AllowUnauthenticated serves a non-loopback address without API keys
+	// or request signing
+	AllowUnauthenticated bool
+	HMACSecret           string
+	HMACWindow           time.Duration
+
+	// TLS
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSSelfSigned   bool
+	TLSClientCAFile string
+
+	// File is the config file that was loaded, empty if none
+	File string
+
+	sources map[string]Source
+}
+
+// SourceKind identifies where a setting's value came from
+type SourceKind string
+
+const (
+	SourceDefault SourceKind = "default"
+	SourceFile    SourceKind = "file"
+	SourceEnv     SourceKind = "env"
+	SourceFlag    SourceKind = "flag"
+)
+
+// Source records where a setting's value came from
+type Source struct {
+	Kind SourceKind
+	// Name is the file path, environment variable or flag that set the value
+	Name string
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s (%s)", s.Kind, s.Name)
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	limits := query.DefaultLimits()
+	pool := postgres.DefaultConnectionOptions()
+
+	cfg := &Config{
+		Host:                server.DefaultHost,
+		Port:                server.DefaultPort,
+		MaxConnections:      server.MaxConnections,
+		SocketMode:          server.DefaultSocketMode,
+		TCP:                 true,
+		DataDir:             defaultDataDir,
+		PostgresPort:        defaultPostgresPort,
+		MaxOpenConns:        pool.MaxOpenConns,
+		MaxIdleConns:        pool.MaxIdleConns,
+		ProxyPort:           pgproxy.DefaultPort,
+		ProxyMaxConnections: pgproxy.DefaultMaxConnections,
+		QueryTimeout:        limits.QueryTimeout,
+		MaxResultRows:       limits.MaxResultRows,
+		MaxQuerySize:        limits.MaxQuerySize,
+		MaxTransactions:     limits.MaxTxSessions,
+		ImportTimeout:       limits.ImportTimeout,
+		SafetyProfile:       query.ProfileDefault,
+		KeysFile:            filepath.Join(defaultDataDir, keysFileName),
+		HMACWindow:          server.DefaultSignatureWindow,
+		sources:             make(map[string]Source, len(settings)),
+	}
+	for _, s := range settings {
+		cfg.sources[s.key] = Source{Kind: SourceDefault}
+	}
+	return cfg
+}
+
+// Source returns where the setting with the given key came from
+func (c *Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+// Load builds the effective configuration from args (the flags following
+// the command name) and the process environment
+func Load(args []string) (*Config, error) {
+	return load(args, os.Getenv, os.Stderr)
+}
+
+// LoadWithFlags is Load for commands with flags of their own, which define
+// adds to the flag set before args are parsed
+func LoadWithFlags(args []string, define func(fs *flag.FlagSet)) (*Config, error) {
+	return loadWithFlags(args, os.Getenv, os.Stderr, define)
+}
+
+func load(args []string, getenv func(string) string, output io.Writer) (*Config, error) {
+	return loadWithFlags(args, getenv, output, nil)
+}
+
+func loadWithFlags(args []string, getenv func(string) string, output io.Writer, define func(fs *flag.FlagSet)) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("vibe", flag.ContinueOnError)
+	fs.SetOutput(output)
+	if define != nil {
+		define(fs)
+	}
+	configFile := fs.String("config", "", "Path to a vibe.toml or vibe.yaml config file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		if s.boolean {
+			// Boolean flags may be given without a value
+			value := new(string)
+			fs.BoolFunc(s.flag, s.usage, func(v string) error {
+				*value = v
+				return nil
+			})
+			flagValues[s.flag] = value
+			continue
+		}
+		flagValues[s.flag] = fs.String(s.flag, "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	path, explicit := *configFile, *configFile != ""
+	if !explicit {
+		path = getenv(EnvConfigFile)
+		explicit = path != ""
+	}
+	if !explicit {
+		path = findConfigFile()
+	}
+	if path != "" {
+		if err := cfg.applyFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		// Earlier names take precedence over later ones
+		for i := len(s.env) - 1; i >= 0; i-- {
+			if v := getenv(s.env[i]); v != "" {
+				if err := cfg.set(s, v, Source{Kind: SourceEnv, Name: s.env[i]}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := settingByFlag(f.Name)
+		if !ok || flagErr != nil {
+			return
+		}
+		flagErr = cfg.set(s, *flagValues[f.Name], Source{Kind: SourceFlag, Name: "--" + f.Name})
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	// The default keys file follows the data directory wherever it is set
+	if cfg.sources["auth.keys_file"].Kind == SourceDefault {
+		cfg.KeysFile = filepath.Join(cfg.DataDir, keysFileName)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func findConfigFile() string {
+	for _, name := range DefaultConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func (c *Config) applyFile(path string) error {
+	values, err := parseFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Apply in key order so errors are reported deterministically
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := settingByKey(key)
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := c.set(s, values[key], Source{Kind: SourceFile, Name: path}); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	c.File = path
+	return nil
+}
+
+func (c *Config) set(s setting, value string, source Source) error {
+	if err := s.set(c, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("invalid value %q for %s from %s: %w", value, s.key, source, err)
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+// Validate checks that the settings are usable together
+func (c *Config) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("server.host must not be empty")
+	}
+	if c.DataDir == "" {
+		return fmt.Errorf("postgres.data_dir must not be empty")
+	}
+	if !c.TCP && c.SocketPath == "" {
+		return fmt.Errorf("server.tcp is false but no server.socket is set, so the API would not listen anywhere")
+	}
+	if !c.TCP && (c.TLSCertFile != "" || c.TLSSelfSigned) {
+		return fmt.Errorf("TLS applies to the TCP listener, which server.tcp turns off")
+	}
+	if c.Port == c.PostgresPort {
+		return fmt.Errorf("server.port and postgres.port must differ (both are %d)", c.Port)
+	}
+	if c.ProxyEnabled && (c.ProxyPort == c.Port || c.ProxyPort == c.PostgresPort) {
+		return fmt.Errorf("proxy.port (%d) must differ from server.port and postgres.port", c.ProxyPort)
+	}
+	if c.MaxIdleConns > c.MaxOpenConns {
+		return fmt.Errorf("postgres.max_idle_conns (%d) must not exceed postgres.max_open_conns (%d)", c.MaxIdleConns, c.MaxOpenConns)
+	}
+	if c.MaxTransactions >= c.MaxOpenConns {
+		return fmt.Errorf("limits.max_transactions (%d) must be below postgres.max_open_conns (%d), so open transactions leave a connection for other queries", c.MaxTransactions, c.MaxOpenConns)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	if c.TLSCertFile != "" && c.TLSSelfSigned {
+		return fmt.Errorf("tls.self_signed cannot be used with tls.cert_file")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" && !c.TLSSelfSigned {
+		return fmt.Errorf("tls.client_ca_file needs TLS: set tls.cert_file or tls.self_signed")
+	}
+	for _, allowed := range c.SafetyAllow {
+		for _, denied := range c.SafetyDeny {
+			if allowed == denied {
+				return fmt.Errorf("safety.allow and safety.deny both list %s", allowed)
+			}
+		}
+	}
+	return nil
+}
+
+// Limits returns the configured query limits
+func (c *Config) Limits() query.Limits {
+	return query.Limits{
+		QueryTimeout:  c.QueryTimeout,
+		MaxResultRows: c.MaxResultRows,
+		MaxQuerySize:  c.MaxQuerySize,
+		MaxTxSessions: c.MaxTransactions,
+		ImportTimeout: c.ImportTimeout,
+	}
+}
+
+// Policy returns the configured safety policy: the profile, with the
+// allowed and denied statement classes and forbidden functions applied
+func (c *Config) Policy() query.Policy {
+	// The profile name was checked when it was set
+	policy, _ := query.PolicyProfile(c.SafetyProfile)
+	policy = policy.Allow(c.SafetyAllow...).Deny(c.SafetyDeny...)
+	policy.ForbiddenFunctions = c.ForbiddenFunctions
+	policy.Role = c.PostgresRole
+	return policy
+}
+
+// TLSOptions returns the configured HTTPS settings. The self-signed
+// certificate is kept in the data directory.
+func (c *Config) TLSOptions() server.TLSOptions {
+	opts := server.TLSOptions{
+		CertFile:     c.TLSCertFile,
+		KeyFile:      c.TLSKeyFile,
+		ClientCAFile: c.TLSClientCAFile,
+	}
+	if c.TLSSelfSigned {
+		opts.SelfSignedDir = filepath.Join(c.DataDir, selfSignedDir)
+	}
+	return opts
+}
+
+// ConnectionOptions returns the configured database pool settings. The
+// server-side statement timeout follows the query timeout so PostgreSQL
+// doesn't cancel queries the executor still allows.
+func (c *Config) ConnectionOptions() postgres.ConnectionOptions {
+	return postgres.ConnectionOptions{
+		MaxOpenConns:     c.MaxOpenConns,
+		MaxIdleConns:     c.MaxIdleConns,
+		StatementTimeout: c.QueryTimeout,
+	}
+}
+
+// Print writes every setting with its effective value and source
+func (c *Config) Print(w io.Writer) error {
+	width := 0
+	for _, s := range settings {
+		if len(s.key) > width {
+			width = len(s.key)
+		}
+	}
+
+	if c.File != "" {
+		if _, err := fmt.Fprintf(w, "# Config file: %s\n", c.File); err != nil {
+			return err
+		}
+	}
+	for _, s := range settings {
+		if _, err := fmt.Fprintf(w, "%-*s = %-12s # %s\n", width, s.key, s.get(c), c.Source(s.key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setting describes one configurable value and the names it is read from
+type setting struct {
+	// key is the name used in config files, as section.name
+	key   string
+	env   []string
+	flag  string
+	usage string
+	// boolean settings may be given as a flag without a value
+	boolean bool
+	get     func(*Config) string
+	set     func(*Config, string) error
+}
+
+var settings = []setting{
+	{
+		key:   "server.host",
+		env:   []string{"VIBESQL_HOST", "VIBE_BIND_HOST"},
+		flag:  "host",
+		usage: "HTTP bind address (use 0.0.0.0 for LAN access)",
+		get:   func(c *Config) string { return c.Host },
+		set:   func(c *Config, v string) error { return setString(&c.Host, v) },
+	},
+	{
+		key:   "server.port",
+		env:   []string{"VIBESQL_PORT"},
+		flag:  "port",
+		usage: "HTTP port",
+		get:   func(c *Config) string { return strconv.Itoa(c.Port) },
+		set:   func(c *Config, v string) error { return setPort(&c.Port, v) },
+	},
+	{
+		key:   "server.max_connections",
+		env:   []string{"VIBESQL_MAX_CONNECTIONS"},
+		flag:  "max-connections",
+		usage: "Maximum concurrent HTTP connections",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxConnections) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.MaxConnections, v) },
+	},
+	{
+		key:   "server.socket",
+		env:   []string{"VIBESQL_SOCKET"},
+		flag:  "socket",
+		usage: "Also serve the API on this Unix domain socket path",
+		get:   func(c *Config) string { return c.SocketPath },
+		set:   func(c *Config, v string) error { return setString(&c.SocketPath, v) },
+	},
+	{
+		key:   "server.socket_mode",
+		env:   []string{"VIBESQL_SOCKET_MODE"},
+		flag:  "socket-mode",
+		usage: "Unix socket file permissions, in octal",
+		get:   func(c *Config) string { return fmt.Sprintf("%04o", c.SocketMode) },
+		set:   func(c *Config, v string) error { return setFileMode(&c.SocketMode, v) },
+	},
+	{
+		key:     "server.tcp",
+		env:     []string{"VIBESQL_TCP"},
+		flag:    "tcp",
+		usage:   "Serve the API on the TCP host and port (set false to use only server.socket)",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.TCP) },
+		set:     func(c *Config, v string) error { return setBool(&c.TCP, v) },
+	},
+	{
+		key:   "postgres.data_dir",
+		env:   []string{"VIBESQL_DATA"},
+		flag:  "data",
+		usage: "PostgreSQL data directory",
+		get:   func(c *Config) string { return c.DataDir },
+		set:   func(c *Config, v string) error { return setString(&c.DataDir, v) },
+	},
+	{
+		key:   "postgres.port",
+		env:   []string{"VIBESQL_PG_PORT"},
+		flag:  "pg-port",
+		usage: "Embedded PostgreSQL port",
+		get:   func(c *Config) string { return strconv.Itoa(c.PostgresPort) },
+		set:   func(c *Config, v string) error { return setPort(&c.PostgresPort, v) },
+	},
+	{
+		key:   "postgres.max_open_conns",
+		env:   []string{"VIBESQL_MAX_OPEN_CONNS"},
+		flag:  "max-open-conns",
+		usage: "Maximum open database connections in the pool",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxOpenConns) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.MaxOpenConns, v) },
+	},
+	{
+		key:   "postgres.max_idle_conns",
+		env:   []string{"VIBESQL_MAX_IDLE_CONNS"},
+		flag:  "max-idle-conns",
+		usage: "Maximum idle database connections in the pool",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxIdleConns) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.MaxIdleConns, v) },
+	},
+	{
+		key:   "postgres.role",
+		env:   []string{"VIBESQL_PG_ROLE"},
+		flag:  "pg-role",
+		usage: "PostgreSQL role queries run as (SET ROLE); API keys may name their own",
+		get:   func(c *Config) string { return c.PostgresRole },
+		set:   func(c *Config, v string) error { return setString(&c.PostgresRole, v) },
+	},
+	{
+		key:     "proxy.enabled",
+		env:     []string{"VIBESQL_PROXY"},
+		flag:    "proxy",
+		usage:   "Serve the PostgreSQL protocol on proxy.port for native clients, who log in with an API key",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.ProxyEnabled) },
+		set:     func(c *Config, v string) error { return setBool(&c.ProxyEnabled, v) },
+	},
+	{
+		key:   "proxy.port",
+		env:   []string{"VIBESQL_PROXY_PORT"},
+		flag:  "proxy-port",
+		usage: "PostgreSQL protocol proxy port, on server.host",
+		get:   func(c *Config) string { return strconv.Itoa(c.ProxyPort) },
+		set:   func(c *Config, v string) error { return setPort(&c.ProxyPort, v) },
+	},
+	{
+		key:   "proxy.max_connections",
+		env:   []string{"VIBESQL_PROXY_MAX_CONNECTIONS"},
+		flag:  "proxy-max-connections",
+		usage: "Maximum concurrent PostgreSQL protocol connections",
+		get:   func(c *Config) string { return strconv.Itoa(c.ProxyMaxConnections) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.ProxyMaxConnections, v) },
+	},
+	{
+		key:   "limits.query_timeout",
+		env:   []string{"VIBESQL_QUERY_TIMEOUT"},
+		flag:  "query-timeout",
+		usage: "Query timeout (e.g. 5s, 1m; plain numbers are seconds)",
+		get:   func(c *Config) string { return c.QueryTimeout.String() },
+		set:   func(c *Config, v string) error { return setDuration(&c.QueryTimeout, v) },
+	},
+	{
+		key:   "limits.import_timeout",
+		env:   []string{"VIBESQL_IMPORT_TIMEOUT"},
+		flag:  "import-timeout",
+		usage: "Time allowed for a bulk import, upload included (e.g. 5m)",
+		get:   func(c *Config) string { return c.ImportTimeout.String() },
+		set:   func(c *Config, v string) error { return setDuration(&c.ImportTimeout, v) },
+	},
+	{
+		key:   "limits.max_rows",
+		env:   []string{"VIBESQL_MAX_ROWS"},
+		flag:  "max-rows",
+		usage: "Maximum rows returned by a query",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxResultRows) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.MaxResultRows, v) },
+	},
+	{
+		key:   "limits.max_query_size",
+		env:   []string{"VIBESQL_MAX_QUERY_SIZE"},
+		flag:  "max-query-size",
+		usage: "Maximum SQL length in bytes (KB and MB suffixes allowed)",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxQuerySize) },
+		set:   func(c *Config, v string) error { return setSize(&c.MaxQuerySize, v) },
+	},
+	{
+		key:   "limits.max_transactions",
+		env:   []string{"VIBESQL_MAX_TRANSACTIONS"},
+		flag:  "max-transactions",
+		usage: "Maximum open interactive transactions and cursors (below postgres.max_open_conns)",
+		get:   func(c *Config) string { return strconv.Itoa(c.MaxTransactions) },
+		set:   func(c *Config, v string) error { return setPositiveInt(&c.MaxTransactions, v) },
+	},
+	{
+		key:   "safety.profile",
+		env:   []string{"VIBESQL_SAFETY_PROFILE"},
+		flag:  "safety-profile",
+		usage: "Safety profile: default, readonly, no-ddl or permissive",
+		get:   func(c *Config) string { return c.SafetyProfile },
+		set:   func(c *Config, v string) error { return setProfile(&c.SafetyProfile, v) },
+	},
+	{
+		key:   "safety.allow",
+		env:   []string{"VIBESQL_SAFETY_ALLOW"},
+		flag:  "safety-allow",
+		usage: "Statement classes to allow on top of the profile (comma-separated)",
+		get:   func(c *Config) string { return joinClasses(c.SafetyAllow) },
+		set:   func(c *Config, v string) error { return setClasses(&c.SafetyAllow, v) },
+	},
+	{
+		key:   "safety.deny",
+		env:   []string{"VIBESQL_SAFETY_DENY"},
+		flag:  "safety-deny",
+		usage: "Statement classes to reject on top of the profile (comma-separated)",
+		get:   func(c *Config) string { return joinClasses(c.SafetyDeny) },
+		set:   func(c *Config, v string) error { return setClasses(&c.SafetyDeny, v) },
+	},
+	{
+		key:   "safety.forbidden_functions",
+		env:   []string{"VIBESQL_FORBIDDEN_FUNCTIONS"},
+		flag:  "forbidden-functions",
+		usage: "Functions queries may not call, replacing the default list (comma-separated)",
+		get: func(c *Config) string {
+			if c.ForbiddenFunctions == nil {
+				return strings.Join(query.DefaultForbiddenFunctions, ",")
+			}
+			return strings.Join(c.ForbiddenFunctions, ",")
+		},
+		set: func(c *Config, v string) error { return setList(&c.ForbiddenFunctions, v) },
+	},
+	{
+		key:   "auth.keys_file",
+		env:   []string{"VIBESQL_KEYS_FILE"},
+		flag:  "keys-file",
+		usage: "API keys file, by default in the data directory; once it exists every request needs a key (manage with 'vibe keys')",
+		get:   func(c *Config) string { return c.KeysFile },
+		set:   func(c *Config, v string) error { return setString(&c.KeysFile, v) },
+	},
+	{
+		key:     "auth.allow_unauthenticated",
+		env:     []string{"VIBESQL_ALLOW_UNAUTHENTICATED"},
+		flag:    "allow-unauthenticated",
+		usage:   "Serve a non-loopback host without API keys or request signing, giving anyone who can reach it full database access",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.AllowUnauthenticated) },
+		set:     func(c *Config, v string) error { return setBool(&c.AllowUnauthenticated, v) },
+	},
+	{
+		key:   "auth.hmac_secret",
+		env:   []string{"VIBESQL_HMAC_SECRET"},
+		flag:  "hmac-secret",
+		usage: "Shared secret that every request must be signed with (HMAC-SHA256)",
+		get: func(c *Config) string {
+			// Never print the secret itself
+			if c.HMACSecret == "" {
+				return ""
+			}
+			return "(set)"
+		},
+		set: func(c *Config, v string) error { return setString(&c.HMACSecret, v) },
+	},
+	{
+		key:   "auth.hmac_window",
+		env:   []string{"VIBESQL_HMAC_WINDOW"},
+		flag:  "hmac-window",
+		usage: "How far a signed request's timestamp may be from the server's clock",
+		get:   func(c *Config) string { return c.HMACWindow.String() },
+		set:   func(c *Config, v string) error { return setDuration(&c.HMACWindow, v) },
+	},
+	{
+		key:   "tls.cert_file",
+		env:   []string{"VIBESQL_TLS_CERT"},
+		flag:  "tls-cert",
+		usage: "PEM certificate chain for HTTPS, reloaded when it changes",
+		get:   func(c *Config) string { return c.TLSCertFile },
+		set:   func(c *Config, v string) error { return setString(&c.TLSCertFile, v) },
+	},
+	{
+		key:   "tls.key_file",
+		env:   []string{"VIBESQL_TLS_KEY"},
+		flag:  "tls-key",
+		usage: "PEM private key for tls.cert_file",
+		get:   func(c *Config) string { return c.TLSKeyFile },
+		set:   func(c *Config, v string) error { return setString(&c.TLSKeyFile, v) },
+	},
+	{
+		key:     "tls.self_signed",
+		env:     []string{"VIBESQL_TLS_SELF_SIGNED"},
+		flag:    "tls-self-signed",
+		usage:   "Serve HTTPS with a self-signed certificate generated in the data directory",
+		boolean: true,
+		get:     func(c *Config) string { return strconv.FormatBool(c.TLSSelfSigned) },
+		set:     func(c *Config, v string) error { return setBool(&c.TLSSelfSigned, v) },
+	},
+	{
+		key:   "tls.client_ca_file",
+		env:   []string{"VIBESQL_TLS_CLIENT_CA"},
+		flag:  "tls-client-ca",
+		usage: "PEM CA certificates; clients must present a certificate signed by one (mutual TLS)",
+		get:   func(c *Config) string { return c.TLSClientCAFile },
+		set:   func(c *Config, v string) error { return setString(&c.TLSClientCAFile, v) },
+	},
+}
+
+func settingByKey(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func settingByFlag(name string) (setting, bool) {
+	for _, s := range settings {
+		if s.flag == name {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func setString(dst *string, v string) error {
+	if v == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	*dst = v
+	return nil
+}
+
+func setPositiveInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("must be an integer")
+	}
+	if n <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("must be true or false")
+	}
+	*dst = b
+	return nil
+}
+
+func setFileMode(dst *os.FileMode, v string) error {
+	mode, err := strconv.ParseUint(v, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return fmt.Errorf("must be octal permissions such as 0600 or 0660")
+	}
+	*dst = os.FileMode(mode)
+	return nil
+}
+
+func setPort(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("must be a port number between 1 and 65535")
+	}
+	*dst = n
+	return nil
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			return fmt.Errorf("must be a duration such as 5s or 1m")
+		}
+		d = time.Duration(seconds) * time.Second
+	}
+	if d <= 0 {
+		return fmt.Errorf("must be positive")
+	}
+	*dst = d
+	return nil
+}
+
+func setSize(dst *int, v string) error {
+	multiplier := 1
+	upper := strings.ToUpper(v)
+	switch {
+	case strings.HasSuffix(upper, "KB"):
+		multiplier, v = 1024, v[:len(v)-2]
+	case strings.HasSuffix(upper, "MB"):
+		multiplier, v = 1024*1024, v[:len(v)-2]
+	}
+	var n int
+	if err := setPositiveInt(&n, strings.TrimSpace(v)); err != nil {
+		return fmt.Errorf("must be a size in bytes such as 10240 or 10KB")
+	}
+	*dst = n * multiplier
+	return nil
+}
+
+func setProfile(dst *string, v string) error {
+	if _, err := query.PolicyProfile(v); err != nil || v == "" {
+		return fmt.Errorf("must be one of %s", strings.Join(query.ProfileNames(), ", "))
+	}
+	*dst = v
+	return nil
+}
+
+// setList parses a comma-separated list, optionally written as an inline
+// TOML or YAML array such as ["a", "b"]. An empty value is an empty list.
+func setList(dst *[]string, v string) error {
+	if strings.HasPrefix(v, "[") {
+		if !strings.HasSuffix(v, "]") {
+			return fmt.Errorf("malformed list")
+		}
+		v = v[1 : len(v)-1]
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		item = unquote(strings.TrimSpace(item))
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	*dst = list
+	return nil
+}
+
+func setClasses(dst *[]query.StatementClass, v string) error {
+	var names []string
+	if err := setList(&names, v); err != nil {
+		return err
+	}
+	classes, err := query.ParseStatementClasses(names)
+	if err != nil {
+		return err
+	}
+	*dst = classes
+	return nil
+}
+
+func joinClasses(classes []query.StatementClass) string {
+	names := make([]string, len(classes))
+	for i, class := range classes {
+		names[i] = string(class)
+	}
+	return strings.Join(names, ",")
+}
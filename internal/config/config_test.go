@@ -0,0 +1,485 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibesql/vibe/internal/pgproxy"
+)
+
+func envFunc(env map[string]string) func(string) string {
+	return func(key string) string {
+		return env[key]
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Host != "127.0.0.1" || cfg.Port != 5173 || cfg.PostgresPort != 5433 {
+		t.Errorf("Unexpected defaults: host=%s port=%d pgPort=%d", cfg.Host, cfg.Port, cfg.PostgresPort)
+	}
+	if cfg.DataDir != "./vibe-data" {
+		t.Errorf("Expected default data dir ./vibe-data, got %s", cfg.DataDir)
+	}
+	if cfg.QueryTimeout != 5*time.Second || cfg.MaxResultRows != 1000 || cfg.MaxQuerySize != 10240 {
+		t.Errorf("Unexpected default limits: %v %d %d", cfg.QueryTimeout, cfg.MaxResultRows, cfg.MaxQuerySize)
+	}
+	if got := cfg.Source("server.port"); got.Kind != SourceDefault {
+		t.Errorf("Expected default source, got %s", got)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "vibe.toml", `
+[server]
+port = 6000
+host = "0.0.0.0"
+
+[postgres]
+data_dir = "/var/lib/vibe"
+`)
+	env := map[string]string{
+		"VIBESQL_PORT": "7000",
+		"VIBESQL_DATA": "/data/env",
+	}
+
+	cfg, err := load([]string{"--config", path, "--port", "8000"}, envFunc(env), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	testCases := []struct {
+		key      string
+		got      interface{}
+		expected interface{}
+		source   Source
+	}{
+		{"server.port", cfg.Port, 8000, Source{SourceFlag, "--port"}},
+		{"postgres.data_dir", cfg.DataDir, "/data/env", Source{SourceEnv, "VIBESQL_DATA"}},
+		{"server.host", cfg.Host, "0.0.0.0", Source{SourceFile, path}},
+		{"postgres.port", cfg.PostgresPort, 5433, Source{Kind: SourceDefault}},
+	}
+
+	for _, tc := range testCases {
+		if tc.got != tc.expected {
+			t.Errorf("%s = %v, expected %v", tc.key, tc.got, tc.expected)
+		}
+		if got := cfg.Source(tc.key); got != tc.source {
+			t.Errorf("%s source = %s, expected %s", tc.key, got, tc.source)
+		}
+	}
+}
+
+func TestLoad_EnvAliases(t *testing.T) {
+	cfg, err := load(nil, envFunc(map[string]string{"VIBE_BIND_HOST": "0.0.0.0"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Host != "0.0.0.0" {
+		t.Errorf("Expected VIBE_BIND_HOST to set host, got %s", cfg.Host)
+	}
+
+	env := map[string]string{"VIBE_BIND_HOST": "0.0.0.0", "VIBESQL_HOST": "10.0.0.5"}
+	cfg, err = load(nil, envFunc(env), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Host != "10.0.0.5" {
+		t.Errorf("Expected VIBESQL_HOST to take precedence, got %s", cfg.Host)
+	}
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "vibe.yaml", "limits:\n  max_rows: 50000\n")
+
+	cfg, err := load(nil, envFunc(map[string]string{EnvConfigFile: path}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.MaxResultRows != 50000 {
+		t.Errorf("Expected max rows 50000, got %d", cfg.MaxResultRows)
+	}
+	if cfg.File != path {
+		t.Errorf("Expected file %s, got %s", path, cfg.File)
+	}
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeConfigFile(t, "vibe.yaml", `---
+# VibeSQL settings
+server:
+  port: 6001   # HTTP
+  max_connections: 8
+postgres:
+  data_dir: '/srv/vibe # data'
+  max_open_conns: 20
+  max_idle_conns: 10
+limits:
+  query_timeout: 60s
+  max_query_size: 64KB
+`)
+
+	cfg, err := load([]string{"--config", path}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Port != 6001 || cfg.MaxConnections != 8 {
+		t.Errorf("Unexpected server settings: port=%d maxConnections=%d", cfg.Port, cfg.MaxConnections)
+	}
+	if cfg.DataDir != "/srv/vibe # data" {
+		t.Errorf("Expected quoted '#' to be kept, got %q", cfg.DataDir)
+	}
+	if cfg.MaxOpenConns != 20 || cfg.MaxIdleConns != 10 {
+		t.Errorf("Unexpected pool settings: %d/%d", cfg.MaxOpenConns, cfg.MaxIdleConns)
+	}
+	if cfg.QueryTimeout != 60*time.Second {
+		t.Errorf("Expected 60s timeout, got %v", cfg.QueryTimeout)
+	}
+	if cfg.MaxQuerySize != 64*1024 {
+		t.Errorf("Expected 65536 byte query size, got %d", cfg.MaxQuerySize)
+	}
+}
+
+func TestLoad_SafetyPolicy(t *testing.T) {
+	path := writeConfigFile(t, "vibe.toml", `
+[safety]
+profile = "no-ddl"
+allow = ["maintenance", "ddl"]
+forbidden_functions = "pg_sleep, pg_read_file"
+`)
+
+	cfg, err := load([]string{"--config", path, "--safety-deny", "truncate"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	policy := cfg.Policy()
+	if policy.Name() != "no-ddl" {
+		t.Errorf("Expected no-ddl profile, got %s", policy.Name())
+	}
+	if err := policy.Check("VACUUM users"); err != nil {
+		t.Errorf("Expected safety.allow to allow VACUUM, got %v", err)
+	}
+	if err := policy.Check("CREATE TABLE t (id int)"); err != nil {
+		t.Errorf("Expected safety.allow to override the profile, got %v", err)
+	}
+	if err := policy.Check("TRUNCATE users"); err == nil {
+		t.Error("Expected TRUNCATE to be denied")
+	}
+	if err := policy.Check("SELECT pg_sleep(1)"); err == nil {
+		t.Error("Expected pg_sleep to be forbidden")
+	}
+	if err := policy.Check("SELECT lo_import('/etc/passwd')"); err != nil {
+		t.Errorf("Expected the forbidden function list to replace the defaults, got %v", err)
+	}
+
+	cfg, err = load(nil, envFunc(map[string]string{"VIBESQL_SAFETY_PROFILE": "readonly"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if policy := cfg.Policy(); !policy.ReadOnly || policy.Check("SELECT lo_import('/etc/passwd')") == nil {
+		t.Errorf("Expected a read-only policy with the default forbidden functions, got %+v", policy)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{"invalid port flag", "", "", []string{"--port", "70000"}, nil, "server.port"},
+		{"invalid env", "", "", nil, map[string]string{"VIBESQL_MAX_ROWS": "-5"}, "VIBESQL_MAX_ROWS"},
+		{"invalid timeout", "", "", []string{"--query-timeout", "soon"}, nil, "limits.query_timeout"},
+		{"unknown key", "vibe.toml", "[server]\nprot = 1\n", nil, nil, `unknown setting "server.prot"`},
+		{"duplicate key", "vibe.toml", "[server]\nport = 1\nport = 2\n", nil, nil, "duplicate setting"},
+		{"malformed toml", "vibe.toml", "[server\n", nil, nil, "line 1"},
+		{"yaml bad indentation", "vibe.yaml", "  port: 1\n", nil, nil, "unexpected indentation"},
+		{"unsupported extension", "vibe.json", "{}", nil, nil, "unsupported config file type"},
+		{"same ports", "", "", []string{"--port", "5433"}, nil, "must differ"},
+		{"idle exceeds open", "", "", []string{"--max-open-conns", "2", "--max-idle-conns", "3"}, nil, "must not exceed"},
+		{"missing explicit file", "", "", []string{"--config", "/nonexistent/vibe.toml"}, nil, "failed to open"},
+		{"positional argument", "", "", []string{"extra"}, nil, "unexpected argument"},
+		{"unknown profile", "", "", []string{"--safety-profile", "yolo"}, nil, "must be one of default, readonly, no-ddl, permissive"},
+		{"unknown class", "", "", nil, map[string]string{"VIBESQL_SAFETY_DENY": "ddl,drop"}, `unknown statement class "drop"`},
+		{"allow and deny overlap", "", "", []string{"--safety-allow", "maintenance", "--safety-deny", "maintenance"}, nil, "both list maintenance"},
+		{"malformed list", "vibe.toml", "[safety]\ndeny = [\"ddl\"\n", nil, nil, "malformed list"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := tc.args
+			if tc.file != "" {
+				args = append([]string{"--config", writeConfigFile(t, tc.file, tc.content)}, args...)
+			}
+
+			_, err := load(args, envFunc(tc.env), io.Discard)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	cfg, err := load([]string{"--max-rows", "500"}, envFunc(map[string]string{"VIBESQL_PORT": "7000"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"server.port",
+		"7000",
+		"env (VIBESQL_PORT)",
+		"limits.max_rows",
+		"flag (--max-rows)",
+		"postgres.data_dir",
+		"./vibe-data",
+		"# default",
+	}
+	for _, s := range expected {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected %q in output, got:\n%s", s, output)
+		}
+	}
+
+	if lines := strings.Count(output, "\n"); lines != len(settings) {
+		t.Errorf("Expected %d lines, got %d", len(settings), lines)
+	}
+}
+
+func TestLoad_KeysFile(t *testing.T) {
+	cfg, err := load(nil, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.KeysFile != filepath.Join(defaultDataDir, "vibe-keys.json") {
+		t.Errorf("Expected default keys file in the data directory, got %s", cfg.KeysFile)
+	}
+
+	cfg, err = load(nil, envFunc(map[string]string{"VIBESQL_DATA": "/var/lib/vibe"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.KeysFile != filepath.Join("/var/lib/vibe", "vibe-keys.json") {
+		t.Errorf("Expected default keys file to follow the data directory, got %s", cfg.KeysFile)
+	}
+	if cfg.Source("auth.keys_file").Kind != SourceDefault {
+		t.Errorf("Expected the keys file to come from the default, got %s", cfg.Source("auth.keys_file"))
+	}
+
+	path := writeConfigFile(t, "vibe.toml", "[auth]\nkeys_file = \"/etc/vibe/keys.json\"\n")
+	cfg, err = load([]string{"--config", path}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.KeysFile != "/etc/vibe/keys.json" {
+		t.Errorf("Expected keys file from config file, got %s", cfg.KeysFile)
+	}
+}
+
+func TestLoad_AllowUnauthenticated(t *testing.T) {
+	cfg, err := load([]string{"--allow-unauthenticated"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !cfg.AllowUnauthenticated {
+		t.Error("Expected the flag to allow unauthenticated access")
+	}
+	if Default().AllowUnauthenticated {
+		t.Error("Expected unauthenticated access to be off by default")
+	}
+}
+
+func TestLoadWithFlags(t *testing.T) {
+	var scope string
+	define := func(fs *flag.FlagSet) {
+		fs.StringVar(&scope, "scope", "read-only", "")
+	}
+
+	cfg, err := loadWithFlags([]string{"--scope", "admin", "--keys-file", "keys.json"}, envFunc(nil), io.Discard, define)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if scope != "admin" || cfg.KeysFile != "keys.json" {
+		t.Errorf("Expected both flags to be parsed, got scope=%s keysFile=%s", scope, cfg.KeysFile)
+	}
+}
+
+func TestLoad_HMAC(t *testing.T) {
+	env := map[string]string{"VIBESQL_HMAC_SECRET": "s3cret", "VIBESQL_HMAC_WINDOW": "90s"}
+	cfg, err := load(nil, envFunc(env), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.HMACSecret != "s3cret" || cfg.HMACWindow != 90*time.Second {
+		t.Errorf("Expected HMAC settings from the environment, got secret=%q window=%v", cfg.HMACSecret, cfg.HMACWindow)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("Expected the HMAC secret to be hidden, got:\n%s", buf.String())
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	cfg, err := load([]string{"--tls-self-signed", "--data", "/var/lib/vibe"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	opts := cfg.TLSOptions()
+	if !cfg.TLSSelfSigned || opts.SelfSignedDir != filepath.Join("/var/lib/vibe", "vibe-tls") {
+		t.Errorf("Expected a self-signed certificate in the data directory, got %+v", opts)
+	}
+
+	cfg, err = load(nil, envFunc(map[string]string{"VIBESQL_TLS_SELF_SIGNED": "false"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.TLSOptions().Enabled() {
+		t.Error("Expected TLS to be off")
+	}
+
+	invalid := [][]string{
+		{"--tls-cert", "cert.pem"},
+		{"--tls-cert", "cert.pem", "--tls-key", "key.pem", "--tls-self-signed"},
+		{"--tls-client-ca", "ca.pem"},
+		{"--tls-self-signed=maybe"},
+	}
+	for _, args := range invalid {
+		if _, err := load(args, envFunc(nil), io.Discard); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestLoad_Socket(t *testing.T) {
+	cfg, err := load([]string{"--socket", "/run/vibe.sock", "--socket-mode", "660", "--tcp=false"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.SocketPath != "/run/vibe.sock" || cfg.SocketMode != 0660 || cfg.TCP {
+		t.Errorf("Expected socket-only settings, got socket=%s mode=%o tcp=%v", cfg.SocketPath, cfg.SocketMode, cfg.TCP)
+	}
+
+	invalid := [][]string{
+		{"--tcp=false"},
+		{"--socket", "/run/vibe.sock", "--socket-mode", "rw"},
+		{"--socket", "/run/vibe.sock", "--socket-mode", "1777"},
+		{"--socket", "/run/vibe.sock", "--tcp=false", "--tls-self-signed"},
+	}
+	for _, args := range invalid {
+		if _, err := load(args, envFunc(nil), io.Discard); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestLoad_MaxTransactions(t *testing.T) {
+	cfg, err := load([]string{"--max-transactions", "3"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Limits().MaxTxSessions != 3 {
+		t.Errorf("Expected 3 transactions, got %d", cfg.Limits().MaxTxSessions)
+	}
+
+	// Open transactions must leave a pool connection for other queries
+	for _, args := range [][]string{
+		{"--max-transactions", "5"},
+		{"--max-open-conns", "2"},
+	} {
+		if _, err := load(args, envFunc(nil), io.Discard); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestLoad_ImportTimeout(t *testing.T) {
+	cfg, err := load(nil, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Limits().ImportTimeout != 5*time.Minute {
+		t.Errorf("Expected a 5m default, got %v", cfg.Limits().ImportTimeout)
+	}
+
+	cfg, err = load([]string{"--import-timeout", "30m"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Limits().ImportTimeout != 30*time.Minute || cfg.Limits().QueryTimeout != 5*time.Second {
+		t.Errorf("Expected only the import timeout to change, got %+v", cfg.Limits())
+	}
+}
+
+func TestLoad_PostgresRole(t *testing.T) {
+	cfg, err := load([]string{"--pg-role", "reporting"}, envFunc(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.PostgresRole != "reporting" || cfg.Policy().Role != "reporting" {
+		t.Errorf("Expected queries to run as reporting, got %q", cfg.Policy().Role)
+	}
+
+	cfg, err = load(nil, envFunc(map[string]string{"VIBESQL_PG_ROLE": "analyst"}), io.Discard)
+	if err != nil || cfg.PostgresRole != "analyst" {
+		t.Errorf("Expected the role from the environment, got %q, %v", cfg.PostgresRole, err)
+	}
+}
+
+func TestLoad_Proxy(t *testing.T) {
+	cfg, err := load(nil, envFunc(map[string]string{"VIBESQL_PROXY": "true"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !cfg.ProxyEnabled || cfg.ProxyPort != pgproxy.DefaultPort || cfg.ProxyMaxConnections != pgproxy.DefaultMaxConnections {
+		t.Errorf("Expected the proxy on its default port, got enabled=%v port=%d max=%d", cfg.ProxyEnabled, cfg.ProxyPort, cfg.ProxyMaxConnections)
+	}
+
+	invalid := [][]string{
+		{"--proxy", "--proxy-port", "5433"},
+		{"--proxy", "--proxy-port", "5173"},
+		{"--proxy-max-connections", "0"},
+	}
+	for _, args := range invalid {
+		if _, err := load(args, envFunc(nil), io.Discard); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseFile reads a config file into section.name keys. Only the subset of
+// TOML and YAML needed for flat settings grouped into sections is
+// supported: [section] headers or "section:" blocks, key/value pairs,
+// quoted or bare scalar values, single-line lists and # comments. Lists are
+// kept as written and split by the setting that reads them.
+func parseFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	var parse func(lines []string) (map[string]string, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		parse = parseTOML
+	case ".yaml", ".yml":
+		parse = parseYAML
+	default:
+		return nil, fmt.Errorf("unsupported config file type %q (use .toml or .yaml)", filepath.Ext(path))
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values, err := parse(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+func parseTOML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+
+	for i, raw := range lines {
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section header", i+1)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		if err := addValue(values, section, key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return values, nil
+}
+
+func parseYAML(lines []string) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+
+	for i, raw := range lines {
+		if strings.TrimSpace(raw) == "---" {
+			continue
+		}
+		line := strings.TrimRight(stripComment(raw), " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.Contains(line, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+
+		indented := line[0] == ' '
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", i+1)
+		}
+
+		if !indented {
+			if strings.TrimSpace(value) == "" {
+				section = strings.TrimSpace(key)
+				continue
+			}
+			section = ""
+		} else if section == "" {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+
+		if err := addValue(values, section, key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return values, nil
+}
+
+func addValue(values map[string]string, section, key, value string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("missing key")
+	}
+	if section != "" {
+		key = section + "." + key
+	}
+	if _, exists := values[key]; exists {
+		return fmt.Errorf("duplicate setting %q", key)
+	}
+	values[key] = unquote(strings.TrimSpace(value))
+	return nil
+}
+
+// stripComment removes a # comment that isn't inside a quoted string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// csvWriter writes a header row of column names, then one record per row.
+// NULL is written as an empty field.
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteColumns(columns []query.Column) error {
+	c.record = make([]string, len(columns))
+	for i, col := range columns {
+		c.record[i] = col.Name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	for i, value := range values {
+		c.record[i], _ = text(value)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
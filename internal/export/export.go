@@ -0,0 +1,95 @@
+// Package export encodes streamed query results as files: CSV, NDJSON,
+// Apache Parquet or SQL INSERT statements.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+	FormatSQL     = "sql"
+)
+
+// Formats lists every export format
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet, FormatSQL}
+
+// ContentTypes maps each export format to its media type
+var ContentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+	FormatSQL:     "application/sql; charset=utf-8",
+}
+
+// DefaultTable is the table named in SQL exports when none is given
+const DefaultTable = "export"
+
+// Writer encodes a query result as it is streamed. Close must be called
+// after the last row to complete the file; it does not close the
+// underlying writer.
+type Writer interface {
+	query.RowWriter
+	Close() error
+}
+
+// Options configures an export writer
+type Options struct {
+	// Table is the table SQL exports insert into, possibly
+	// schema-qualified. Empty means DefaultTable.
+	Table string
+}
+
+// NewWriter returns a writer encoding rows in the given format to w
+func NewWriter(format string, w io.Writer, opts Options) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	case FormatSQL:
+		table := opts.Table
+		if table == "" {
+			table = DefaultTable
+		}
+		return newSQLWriter(w, table), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// text returns the text form of a streamed value and whether it is
+// non-NULL. JSON values, arrays and numbers keep their JSON text.
+func text(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.RawMessage:
+		return string(v), true
+	case json.Number:
+		return v.String(), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value), true
+	}
+	return string(b), true
+}
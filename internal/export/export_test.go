@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+var testColumns = []query.Column{
+	{Name: "id", Type: "int8"},
+	{Name: "name", Type: "text"},
+	{Name: "score", Type: "numeric"},
+	{Name: "tags", Type: "_text"},
+	{Name: "meta", Type: "jsonb"},
+}
+
+var testRows = [][]interface{}{
+	{int64(1), "Ann", json.Number("9.50"), []interface{}{"a", "b c"}, json.RawMessage(`{"k":1}`)},
+	{int64(2), `O'Brien, "Bob"`, nil, []interface{}{`x"y`, nil}, nil},
+}
+
+func writeAll(t *testing.T, format string, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, opts)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) error = %v", format, err)
+	}
+	if err := w.WriteColumns(testColumns); err != nil {
+		t.Fatalf("WriteColumns() error = %v", err)
+	}
+	for _, row := range testRows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	if _, err := NewWriter("xlsx", &bytes.Buffer{}, Options{}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	want := "id,name,score,tags,meta\n" +
+		`1,Ann,9.50,"[""a"",""b c""]","{""k"":1}"` + "\n" +
+		`2,"O'Brien, ""Bob""",,"[""x\""y"",null]",` + "\n"
+	if got := writeAll(t, FormatCSV, Options{}); got != want {
+		t.Errorf("CSV export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	want := `{"id":1,"name":"Ann","score":9.50,"tags":["a","b c"],"meta":{"k":1}}` + "\n" +
+		`{"id":2,"name":"O'Brien, \"Bob\"","score":null,"tags":["x\"y",null],"meta":null}` + "\n"
+	if got := writeAll(t, FormatNDJSON, Options{}); got != want {
+		t.Errorf("NDJSON export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSQLWriter(t *testing.T) {
+	want := `INSERT INTO "export" ("id", "name", "score", "tags", "meta") VALUES (1, 'Ann', 9.50, '{"a","b c"}', '{"k":1}');` + "\n" +
+		`INSERT INTO "export" ("id", "name", "score", "tags", "meta") VALUES (2, 'O''Brien, "Bob"', NULL, ` + ` E'{"x\\"y",NULL}', NULL);` + "\n"
+	if got := writeAll(t, FormatSQL, Options{}); got != want {
+		t.Errorf("SQL export =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typeName string
+		want     string
+	}{
+		{nil, "int4", "NULL"},
+		{true, "bool", "TRUE"},
+		{1.5, "float8", "1.5"},
+		{"NaN", "float8", "'NaN'"},
+		{"aGk=", "bytea", "decode('aGk=', 'base64')"},
+		{[]interface{}{"aGk="}, "_bytea", ` E'{"\\\\x6869"}'`},
+		{[]interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3), nil}}, "_int4", `'{{"1","2"},{"3",NULL}}'`},
+		{"2024-01-02", "date", "'2024-01-02'"},
+	}
+
+	for _, tt := range tests {
+		if got := sqlLiteral(tt.value, tt.typeName); got != tt.want {
+			t.Errorf("sqlLiteral(%v, %q) = %s, want %s", tt.value, tt.typeName, got, tt.want)
+		}
+	}
+}
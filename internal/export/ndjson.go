@@ -0,0 +1,54 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// ndjsonWriter writes one JSON object per row, with keys in column order
+type ndjsonWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w)}
+}
+
+func (n *ndjsonWriter) WriteColumns(columns []query.Column) error {
+	n.keys = make([][]byte, len(columns))
+	for i, col := range columns {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return err
+		}
+		n.keys[i] = key
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(values []interface{}) error {
+	n.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		n.w.Write(n.keys[i])
+		n.w.WriteByte(':')
+
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.w.Write(b)
+	}
+	n.w.WriteByte('}')
+	return n.w.WriteByte('\n')
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
@@ -0,0 +1,460 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// parquetRowGroupSize is how many bytes of values are buffered before they
+// are written out as a row group
+const parquetRowGroupSize = 32 << 20
+
+// Parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+)
+
+// Parquet converted types, the legacy form of logical type annotations
+const (
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedDate            = 6
+	convertedTimestampMicros = 10
+	convertedInt16           = 16
+	convertedJSON            = 19
+)
+
+// Parquet logical types, as LogicalType union field ids
+const (
+	logicalNone      = 0
+	logicalString    = 1
+	logicalDate      = 6
+	logicalTimestamp = 8
+	logicalJSON      = 12
+)
+
+// Parquet encodings
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// parquetColumn buffers the values of one column for the current row group
+type parquetColumn struct {
+	name      string
+	typeName  string
+	physical  int32
+	converted int32
+	logical   int16
+	// utc marks timestamps that are adjusted to UTC (timestamptz)
+	utc bool
+
+	// defs holds one bit per row, set for non-NULL values
+	defs []byte
+	// values holds the non-NULL values in PLAIN encoding; booleans are
+	// bit-packed into it as they arrive
+	values  bytes.Buffer
+	nonNull int
+	bits    byte
+}
+
+// parquetChunk records where a column chunk was written
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// parquetRowGroup records a written row group
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	size   int64
+}
+
+// parquetWriter writes an uncompressed Parquet file with one optional,
+// PLAIN-encoded column per result column. Rows are buffered until about
+// parquetRowGroupSize bytes of values have accumulated, then written as a
+// row group of one data page per column; the schema and the location of
+// each row group go in the footer written by Close.
+//
+// Integers, floats, booleans, dates and timestamps keep their types;
+// bytea is stored as binary and every other type, numeric included, as
+// its text. Arrays are stored as JSON text.
+type parquetWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	columns []*parquetColumn
+
+	rows      int
+	rowGroups []parquetRowGroup
+	// rowGroupSize is parquetRowGroupSize, smaller in tests
+	rowGroupSize int
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w:            bufio.NewWriter(w),
+		rowGroupSize: parquetRowGroupSize,
+	}
+}
+
+func (p *parquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *parquetWriter) WriteColumns(columns []query.Column) error {
+	p.columns = make([]*parquetColumn, len(columns))
+	for i, col := range columns {
+		p.columns[i] = newParquetColumn(col)
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+func newParquetColumn(col query.Column) *parquetColumn {
+	c := &parquetColumn{
+		name:      col.Name,
+		typeName:  col.Type,
+		physical:  parquetByteArray,
+		converted: convertedUTF8,
+		logical:   logicalString,
+	}
+
+	switch col.Type {
+	case "bool":
+		c.physical, c.converted, c.logical = parquetBoolean, convertedNone, logicalNone
+	case "int2":
+		c.physical, c.converted, c.logical = parquetInt32, convertedInt16, logicalNone
+	case "int4":
+		c.physical, c.converted, c.logical = parquetInt32, convertedNone, logicalNone
+	case "int8":
+		c.physical, c.converted, c.logical = parquetInt64, convertedNone, logicalNone
+	case "float4", "float8":
+		c.physical, c.converted, c.logical = parquetDouble, convertedNone, logicalNone
+	case "date":
+		c.physical, c.converted, c.logical = parquetInt32, convertedDate, logicalDate
+	case "timestamptz":
+		c.physical, c.converted, c.logical = parquetInt64, convertedTimestampMicros, logicalTimestamp
+		c.utc = true
+	case "timestamp":
+		// TIMESTAMP_MICROS implies UTC, so local timestamps only get the
+		// logical type
+		c.physical, c.converted, c.logical = parquetInt64, convertedNone, logicalTimestamp
+	case "bytea":
+		c.converted, c.logical = convertedNone, logicalNone
+	case "json", "jsonb":
+		c.converted, c.logical = convertedJSON, logicalJSON
+	}
+	return c
+}
+
+func (p *parquetWriter) WriteRow(values []interface{}) error {
+	buffered := 0
+	for i, col := range p.columns {
+		if err := col.add(p.rows, values[i]); err != nil {
+			return err
+		}
+		buffered += col.values.Len()
+	}
+	p.rows++
+
+	if buffered >= p.rowGroupSize {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// add appends the value of row to the column
+func (c *parquetColumn) add(row int, value interface{}) error {
+	if row%8 == 0 {
+		c.defs = append(c.defs, 0)
+	}
+	if value == nil {
+		return nil
+	}
+	c.defs[row/8] |= 1 << (row % 8)
+
+	if err := c.encode(value); err != nil {
+		return fmt.Errorf("cannot export %v as %s in column %q: %w", value, c.typeName, c.name, err)
+	}
+	c.nonNull++
+	return nil
+}
+
+// encode appends a non-NULL value in PLAIN encoding
+func (c *parquetColumn) encode(value interface{}) error {
+	switch c.physical {
+	case parquetBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("not a boolean")
+		}
+		if b {
+			c.bits |= 1 << (c.nonNull % 8)
+		}
+		if c.nonNull%8 == 7 {
+			c.values.WriteByte(c.bits)
+			c.bits = 0
+		}
+		return nil
+
+	case parquetInt32:
+		var v int64
+		if c.logical == logicalDate {
+			t, err := parseTime(value, "2006-01-02")
+			if err != nil {
+				return err
+			}
+			v = int64(math.Floor(float64(t.Unix()) / 86400))
+		} else if n, ok := value.(int64); ok {
+			v = n
+		} else {
+			return fmt.Errorf("not an integer")
+		}
+		c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(int32(v))))
+		return nil
+
+	case parquetInt64:
+		var v int64
+		if c.logical == logicalTimestamp {
+			t, err := parseTime(value, time.RFC3339Nano)
+			if err != nil {
+				return err
+			}
+			v = t.UnixMicro()
+		} else if n, ok := value.(int64); ok {
+			v = n
+		} else {
+			return fmt.Errorf("not an integer")
+		}
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		return nil
+
+	case parquetDouble:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		case string:
+			// NaN and infinities are decoded as strings for JSON
+			switch v {
+			case "NaN":
+				f = math.NaN()
+			case "Infinity":
+				f = math.Inf(1)
+			case "-Infinity":
+				f = math.Inf(-1)
+			default:
+				return fmt.Errorf("not a number")
+			}
+		default:
+			return fmt.Errorf("not a number")
+		}
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)))
+		return nil
+	}
+
+	s, _ := text(value)
+	if c.typeName == "bytea" {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		s = string(b)
+	}
+	c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	c.values.WriteString(s)
+	return nil
+}
+
+// parseTime parses a date or timestamp decoded as a string. PostgreSQL's
+// infinite dates become the extremes of the column's range.
+func parseTime(value interface{}, layout string) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("not a date or timestamp")
+	}
+	switch s {
+	case "infinity":
+		return time.Date(9999, 12, 31, 23, 59, 59, 999999000, time.UTC), nil
+	case "-infinity":
+		return time.Date(-4713, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse(layout, s)
+}
+
+// flushRowGroup writes the buffered rows as a row group
+func (p *parquetWriter) flushRowGroup() error {
+	group := parquetRowGroup{rows: int64(p.rows)}
+
+	for _, col := range p.columns {
+		chunk, err := p.writeChunk(col)
+		if err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+	}
+
+	p.rowGroups = append(p.rowGroups, group)
+	p.rows = 0
+	return nil
+}
+
+// writeChunk writes a column's buffered values as a single data page and
+// resets the column
+func (p *parquetWriter) writeChunk(col *parquetColumn) (parquetChunk, error) {
+	if col.physical == parquetBoolean && col.nonNull%8 != 0 {
+		col.values.WriteByte(col.bits)
+	}
+
+	// Definition levels are a single bit-packed run, after their length
+	levels := binary.AppendUvarint(nil, uint64(len(col.defs))<<1|1)
+	levels = append(levels, col.defs...)
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	page = append(page, col.values.Bytes()...)
+
+	header := &thriftWriter{}
+	header.begin()
+	header.i32(1, 0) // DATA_PAGE
+	header.i32(2, int32(len(page)))
+	header.i32(3, int32(len(page)))
+	header.structField(5)
+	header.i32(1, int32(p.rows))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.end()
+	header.end()
+
+	chunk := parquetChunk{
+		offset: p.offset,
+		size:   int64(header.buf.Len() + len(page)),
+		values: int64(p.rows),
+	}
+	if err := p.write(header.buf.Bytes()); err != nil {
+		return chunk, err
+	}
+	if err := p.write(page); err != nil {
+		return chunk, err
+	}
+
+	col.defs = col.defs[:0]
+	col.values.Reset()
+	col.nonNull = 0
+	col.bits = 0
+	return chunk, nil
+}
+
+// Close writes the remaining rows and the file footer
+func (p *parquetWriter) Close() error {
+	if p.rows > 0 {
+		if err := p.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := p.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// footer encodes the FileMetaData
+func (p *parquetWriter) footer() []byte {
+	var totalRows int64
+	for _, group := range p.rowGroups {
+		totalRows += group.rows
+	}
+
+	t := &thriftWriter{}
+	t.begin()
+	t.i32(1, 1) // version
+
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.begin()
+	t.string(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for _, col := range p.columns {
+		t.begin()
+		t.i32(1, col.physical)
+		t.i32(3, 1) // OPTIONAL
+		t.string(4, col.name)
+		if col.converted != convertedNone {
+			t.i32(6, col.converted)
+		}
+		if col.logical != logicalNone {
+			t.structField(10)
+			t.structField(col.logical)
+			if col.logical == logicalTimestamp {
+				t.bool(1, col.utc)
+				t.structField(2)
+				t.structField(2) // MICROS
+				t.end()
+				t.end()
+			}
+			t.end()
+			t.end()
+		}
+		t.end()
+	}
+
+	t.i64(3, totalRows)
+
+	t.list(4, thriftStruct, len(p.rowGroups))
+	for _, group := range p.rowGroups {
+		t.begin()
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := p.columns[i]
+			t.begin()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, col.physical)
+			t.list(2, thriftI32, 2)
+			t.varint(encodingPlain)
+			t.varint(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.binary(col.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.rows)
+		t.end()
+	}
+
+	t.string(6, "vibe")
+	t.end()
+	return t.buf.Bytes()
+}
@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+func TestThriftWriter(t *testing.T) {
+	w := &thriftWriter{}
+	w.begin()
+	w.i32(1, 1)
+	w.string(4, "id")
+	w.structField(10)
+	w.bool(1, true)
+	w.end()
+	w.i64(30, -2)
+	w.list(31, thriftI32, 2)
+	w.varint(0)
+	w.varint(3)
+	w.end()
+
+	want := []byte{
+		0x15, 0x02, // field 1, i32 1
+		0x38, 0x02, 'i', 'd', // field 4 (+3), binary "id"
+		0x6c,       // field 10 (+6), struct
+		0x11, 0x00, // field 1, true; stop
+		0x06, 0x3c, 0x03, // field 30 (+20): long form, i64 -2
+		0x19, 0x25, 0x00, 0x06, // field 31, list of two i32
+		0x00, // stop
+	}
+	if got := w.buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("thriftWriter encoded % x, want % x", got, want)
+	}
+}
+
+func writeParquet(t *testing.T, rowGroupSize int, columns []query.Column, rows [][]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newParquetWriter(&buf)
+	w.rowGroupSize = rowGroupSize
+	if err := w.WriteColumns(columns); err != nil {
+		t.Fatalf("WriteColumns() error = %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(w.rowGroups) == 0 && len(rows) > 0 {
+		t.Fatal("Expected at least one row group")
+	}
+	return buf.Bytes()
+}
+
+func TestParquetWriter_Layout(t *testing.T) {
+	columns := []query.Column{{Name: "id", Type: "int4"}, {Name: "ok", Type: "bool"}, {Name: "name", Type: "text"}}
+	var rows [][]interface{}
+	for i := 0; i < 20; i++ {
+		rows = append(rows, []interface{}{int64(i), i%2 == 0, nil})
+	}
+
+	data := writeParquet(t, parquetRowGroupSize, columns, rows)
+
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatalf("Expected the file to start and end with %s", parquetMagic)
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	if footer[len(footer)-1] != 0 {
+		t.Error("Expected the footer to end with a struct stop")
+	}
+	for _, name := range []string{"schema", "id", "ok", "name", "vibe"} {
+		if !bytes.Contains(footer, []byte(name)) {
+			t.Errorf("Expected the footer to name %q", name)
+		}
+	}
+
+	// The first page starts with its header, right after the magic
+	if data[4] != 0x15 || data[5] != 0x00 {
+		t.Errorf("Expected a DATA_PAGE header at offset 4, got % x", data[4:6])
+	}
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	columns := []query.Column{{Name: "id", Type: "int8"}}
+
+	var buf bytes.Buffer
+	w := newParquetWriter(&buf)
+	w.rowGroupSize = 80
+	w.WriteColumns(columns)
+	for i := 0; i < 25; i++ {
+		if err := w.WriteRow([]interface{}{int64(i)}); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// 10 eight-byte values fill a row group
+	if len(w.rowGroups) != 3 {
+		t.Fatalf("Expected 3 row groups, got %d", len(w.rowGroups))
+	}
+	wantRows := []int64{10, 10, 5}
+	for i, group := range w.rowGroups {
+		if group.rows != wantRows[i] {
+			t.Errorf("Row group %d has %d rows, want %d", i, group.rows, wantRows[i])
+		}
+		if i > 0 && group.chunks[0].offset != w.rowGroups[i-1].chunks[0].offset+w.rowGroups[i-1].size {
+			t.Errorf("Row group %d does not follow the previous one", i)
+		}
+	}
+}
+
+func TestParquetWriter_Empty(t *testing.T) {
+	data := writeParquet(t, parquetRowGroupSize, []query.Column{{Name: "id", Type: "int4"}}, nil)
+	if !bytes.HasPrefix(data, []byte(parquetMagic)) || !bytes.HasSuffix(data, []byte(parquetMagic)) {
+		t.Fatalf("Expected an empty but valid file, got % x", data)
+	}
+}
+
+func TestParquetColumn_Encode(t *testing.T) {
+	tests := []struct {
+		typeName string
+		value    interface{}
+		want     []byte
+	}{
+		{"int4", int64(-1), []byte{0xff, 0xff, 0xff, 0xff}},
+		{"int8", int64(1), []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+		{"float8", "-Infinity", []byte{0, 0, 0, 0, 0, 0, 0xf0, 0xff}},
+		{"date", "1970-01-02", []byte{1, 0, 0, 0}},
+		{"date", "1969-12-31", []byte{0xff, 0xff, 0xff, 0xff}},
+		{"timestamptz", "1970-01-01T01:00:01.5+01:00", binary.LittleEndian.AppendUint64(nil, 1500000)},
+		{"text", "hé", []byte{3, 0, 0, 0, 'h', 0xc3, 0xa9}},
+		{"bytea", "aGk=", []byte{2, 0, 0, 0, 'h', 'i'}},
+		{"_int4", []interface{}{int64(1), nil}, []byte{8, 0, 0, 0, '[', '1', ',', 'n', 'u', 'l', 'l', ']'}},
+	}
+
+	for _, tt := range tests {
+		col := newParquetColumn(query.Column{Name: "c", Type: tt.typeName})
+		if err := col.add(0, tt.value); err != nil {
+			t.Errorf("add(%v) as %s error = %v", tt.value, tt.typeName, err)
+			continue
+		}
+		if got := col.values.Bytes(); !bytes.Equal(got, tt.want) {
+			t.Errorf("add(%v) as %s encoded % x, want % x", tt.value, tt.typeName, got, tt.want)
+		}
+	}
+
+	col := newParquetColumn(query.Column{Name: "c", Type: "int4"})
+	if err := col.add(0, "not a number"); err == nil {
+		t.Error("Expected an error encoding a string as int4")
+	}
+}
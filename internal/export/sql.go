@@ -0,0 +1,121 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// sqlWriter writes one INSERT statement per row. Values are written as
+// untyped literals, so they take the types of the target table's columns.
+type sqlWriter struct {
+	w       *bufio.Writer
+	table   string
+	columns []query.Column
+	prefix  string
+}
+
+func newSQLWriter(w io.Writer, table string) *sqlWriter {
+	return &sqlWriter{w: bufio.NewWriter(w), table: table}
+}
+
+func (s *sqlWriter) WriteColumns(columns []query.Column) error {
+	s.columns = columns
+
+	parts := strings.Split(s.table, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = pq.QuoteIdentifier(col.Name)
+	}
+	s.prefix = "INSERT INTO " + strings.Join(parts, ".") + " (" + strings.Join(names, ", ") + ") VALUES ("
+	return nil
+}
+
+func (s *sqlWriter) WriteRow(values []interface{}) error {
+	s.w.WriteString(s.prefix)
+	for i, value := range values {
+		if i > 0 {
+			s.w.WriteString(", ")
+		}
+		s.w.WriteString(sqlLiteral(value, s.columns[i].Type))
+	}
+	_, err := s.w.WriteString(");\n")
+	return err
+}
+
+func (s *sqlWriter) Close() error {
+	return s.w.Flush()
+}
+
+// sqlLiteral returns the SQL literal for a value of the given type
+func sqlLiteral(value interface{}, typeName string) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case json.Number:
+		return v.String()
+	case []interface{}:
+		return pq.QuoteLiteral(arrayLiteral(v, strings.TrimPrefix(typeName, "_")))
+	case string:
+		if typeName == "bytea" {
+			return "decode(" + pq.QuoteLiteral(v) + ", 'base64')"
+		}
+	}
+
+	s, _ := text(value)
+	return pq.QuoteLiteral(s)
+}
+
+// arrayElementEscaper escapes a quoted array element
+var arrayElementEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// arrayLiteral returns the PostgreSQL array text for decoded array
+// elements, such as {1,NULL,"a b"}
+func arrayLiteral(elems []interface{}, elemType string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		switch v := elem.(type) {
+		case nil:
+			b.WriteString("NULL")
+		case []interface{}:
+			b.WriteString(arrayLiteral(v, elemType))
+		default:
+			s, _ := text(v)
+			if elemType == "bytea" {
+				if raw, err := base64.StdEncoding.DecodeString(s); err == nil {
+					s = `\x` + hex.EncodeToString(raw)
+				}
+			}
+			b.WriteByte('"')
+			b.WriteString(arrayElementEscaper.Replace(s))
+			b.WriteByte('"')
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift compact protocol type codes
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftStruct    = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol, which is
+// how Parquet serializes its page headers and file metadata. Fields must be
+// written in increasing id order within each struct.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last is the id of the last field written in the current struct, and
+	// parents holds it for each enclosing struct
+	last    int16
+	parents []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+// varint writes a zigzag-encoded integer
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftBoolTrue)
+	} else {
+		t.field(id, thriftBoolFalse)
+	}
+}
+
+func (t *thriftWriter) string(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.uvarint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list writes the header of a list field of n elements of type elem. The
+// elements follow: i32s with varint, strings with binary and structs with
+// begin and end.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(n))
+	}
+}
+
+// structField starts a struct-valued field, which is closed by end
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// begin starts a struct: a list element or the top-level struct
+func (t *thriftWriter) begin() {
+	t.parents = append(t.parents, t.last)
+	t.last = 0
+}
+
+// end closes the current struct
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.parents[len(t.parents)-1]
+	t.parents = t.parents[:len(t.parents)-1]
+}
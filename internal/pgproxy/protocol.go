@@ -0,0 +1,256 @@
+package pgproxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// Startup packet codes, sent in place of a protocol version
+const (
+	protocolVersion3 = 196608
+	cancelRequest    = 80877102
+	sslRequest       = 80877103
+	gssEncRequest    = 80877104
+)
+
+// Authentication request codes, the first field of an 'R' message
+const (
+	authOK                = 0
+	authCleartextPassword = 3
+	authMD5Password       = 5
+	authSASL              = 10
+	authSASLContinue      = 11
+	authSASLFinal         = 12
+)
+
+const (
+	// maxStartupSize caps startup packets, as PostgreSQL does
+	maxStartupSize = 10000
+	// maxMessageSize caps the messages the proxy reads in full: queries,
+	// Parse and authentication messages. Other messages are streamed.
+	maxMessageSize = 64 << 20
+)
+
+// SQLSTATE codes sent to clients
+const (
+	sqlStateProtocolViolation    = "08P01"
+	sqlStateInvalidPassword      = "28P01"
+	sqlStateInvalidAuthorization = "28000"
+	sqlStateTooManyConnections   = "53300"
+	sqlStateFeatureNotSupported  = "0A000"
+	sqlStateInsufficientPriv     = "42501"
+	sqlStateSyntaxError          = "42601"
+	sqlStateConnectionFailure    = "08006"
+)
+
+// startupMessage is a client's first packet after any SSL negotiation
+type startupMessage struct {
+	code int
+	// params are the startup parameters of a protocol 3.0 startup message
+	params map[string]string
+	// cancel holds the process ID and secret key of a cancel request
+	cancel []byte
+}
+
+// readStartup reads a startup packet
+func readStartup(r io.Reader) (*startupMessage, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint32(header[:4]))
+	if length < 8 || length > maxStartupSize {
+		return nil, fmt.Errorf("invalid startup packet length %d", length)
+	}
+	body := make([]byte, length-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	msg := &startupMessage{code: int(binary.BigEndian.Uint32(header[4:]))}
+	switch msg.code {
+	case cancelRequest:
+		msg.cancel = body
+	case protocolVersion3:
+		msg.params = make(map[string]string)
+		for len(body) > 1 {
+			name, rest, ok := cutString(body)
+			if !ok {
+				return nil, fmt.Errorf("malformed startup packet")
+			}
+			value, rest, ok := cutString(rest)
+			if !ok {
+				return nil, fmt.Errorf("malformed startup packet")
+			}
+			msg.params[name] = value
+			body = rest
+		}
+	}
+	return msg, nil
+}
+
+// startupPacket encodes a protocol 3.0 startup message
+func startupPacket(params map[string]string, order []string) []byte {
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, uint32(protocolVersion3))
+	for _, name := range order {
+		if value, ok := params[name]; ok {
+			body.WriteString(name)
+			body.WriteByte(0)
+			body.WriteString(value)
+			body.WriteByte(0)
+		}
+	}
+	body.WriteByte(0)
+
+	packet := make([]byte, 4, 4+body.Len())
+	binary.BigEndian.PutUint32(packet, uint32(4+body.Len()))
+	return append(packet, body.Bytes()...)
+}
+
+// readHeader reads a message's type and the length of its body
+func readHeader(r io.Reader) (byte, int, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	length := int(binary.BigEndian.Uint32(header[1:]))
+	if length < 4 {
+		return 0, 0, fmt.Errorf("invalid message length %d", length)
+	}
+	return header[0], length - 4, nil
+}
+
+// readBody reads a message body of n bytes
+func readBody(r io.Reader, n int) ([]byte, error) {
+	if n > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes is too large", n)
+	}
+	body := make([]byte, n)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+// readMessage reads a whole message
+func readMessage(r io.Reader) (byte, []byte, error) {
+	typ, n, err := readHeader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	body, err := readBody(r, n)
+	return typ, body, err
+}
+
+// writeMessage writes a message
+func writeMessage(w io.Writer, typ byte, body []byte) error {
+	header := [5]byte{typ}
+	binary.BigEndian.PutUint32(header[1:], uint32(4+len(body)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// copyMessage copies a message body of n bytes from r to w, after the
+// header, without holding it in memory
+func copyMessage(w io.Writer, r io.Reader, typ byte, n int) error {
+	header := [5]byte{typ}
+	binary.BigEndian.PutUint32(header[1:], uint32(4+n))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, r, int64(n))
+	return err
+}
+
+// flushIfIdle flushes w once r has no more buffered input, so a burst of
+// messages is written together
+func flushIfIdle(w *bufio.Writer, r *bufio.Reader) error {
+	if r.Buffered() == 0 {
+		return w.Flush()
+	}
+	return nil
+}
+
+// authMessage encodes an authentication request with optional data
+func authMessage(code int, data []byte) []byte {
+	body := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(body, uint32(code))
+	return append(body, data...)
+}
+
+// errorFields encodes an ErrorResponse body
+func errorFields(severity, code, message, detail string) []byte {
+	var body bytes.Buffer
+	for _, field := range []struct {
+		typ   byte
+		value string
+	}{{'S', severity}, {'V', severity}, {'C', code}, {'M', message}, {'D', detail}} {
+		if field.value == "" {
+			continue
+		}
+		body.WriteByte(field.typ)
+		body.WriteString(field.value)
+		body.WriteByte(0)
+	}
+	body.WriteByte(0)
+	return body.Bytes()
+}
+
+// policyError encodes a policy rejection as an ErrorResponse body
+func policyError(err error) []byte {
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		return errorFields("ERROR", sqlStateInsufficientPriv, err.Error(), "")
+	}
+	code := sqlStateInsufficientPriv
+	if vibeErr.Code == postgres.ErrorCodeInvalidSQL {
+		code = sqlStateSyntaxError
+	}
+	return errorFields("ERROR", code, vibeErr.Message, vibeErr.Detail)
+}
+
+// errorMessage returns the message field of an ErrorResponse body
+func errorMessage(body []byte) string {
+	for len(body) > 1 {
+		typ := body[0]
+		value, rest, ok := cutString(body[1:])
+		if !ok {
+			break
+		}
+		if typ == 'M' {
+			return value
+		}
+		body = rest
+	}
+	return "unknown error"
+}
+
+// md5Password is the response to an MD5 password request
+func md5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// cutString splits a null-terminated string off the front of b
+func cutString(b []byte) (string, []byte, bool) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(b[:i]), b[i+1:], true
+}
+
+// cString encodes a null-terminated string
+func cString(s string) []byte {
+	return append([]byte(s), 0)
+}
@@ -0,0 +1,793 @@
+// Package pgproxy serves the PostgreSQL frontend/backend protocol in front
+// of the embedded PostgreSQL instance, so clients such as psql, pgx and
+// ORMs can connect to vibe directly. Clients log in with an API key as
+// their password; every query they send is checked against the key's
+// safety policy before it reaches PostgreSQL.
+package pgproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+	"github.com/vibesql/vibe/internal/scram"
+	"github.com/vibesql/vibe/internal/server"
+)
+
+const (
+	// DefaultPort is the proxy's default port, next to the embedded
+	// PostgreSQL's
+	DefaultPort = 5434
+	// DefaultMaxConnections caps concurrent client connections
+	DefaultMaxConnections = 10
+	// DefaultDatabase is used when a client doesn't name a database
+	DefaultDatabase = "postgres"
+
+	// startupTimeout bounds the time a client may take to log in
+	startupTimeout = 30 * time.Second
+)
+
+// forwardedParams are the startup parameters passed on to PostgreSQL.
+// Others, such as options, could override the proxy's session settings.
+var forwardedParams = []string{
+	"database",
+	"application_name",
+	"client_encoding",
+	"DateStyle",
+	"TimeZone",
+	"IntervalStyle",
+	"extra_float_digits",
+	"search_path",
+}
+
+// Options configures the proxy
+type Options struct {
+	// Addr is the host:port to listen on
+	Addr string
+	// Upstream is the host:port of the PostgreSQL instance
+	Upstream string
+	// User and Password are the role the proxy connects to PostgreSQL as.
+	// It must not be the superuser, whose sessions no policy could contain.
+	User     string
+	Password string
+	// Auth looks up the API keys clients log in with and their policies
+	Auth *server.Authenticator
+	// MaxConnections caps concurrent client connections. Zero uses
+	// DefaultMaxConnections.
+	MaxConnections int
+	// TLS, when set, is offered to clients that ask for SSL. Without it,
+	// cleartext passwords are only accepted on a loopback listener.
+	TLS *tls.Config
+}
+
+// Proxy accepts PostgreSQL protocol clients and relays their sessions to
+// the upstream instance
+type Proxy struct {
+	opts      Options
+	listener  net.Listener
+	semaphore chan struct{}
+	// loopback is set when the listener only accepts local connections
+	loopback bool
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// New returns a proxy with the given options
+func New(opts Options) *Proxy {
+	if opts.MaxConnections == 0 {
+		opts.MaxConnections = DefaultMaxConnections
+	}
+	return &Proxy{
+		opts:      opts,
+		semaphore: make(chan struct{}, opts.MaxConnections),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// Start listens on the proxy's address and serves clients in the
+// background
+func (p *Proxy) Start() error {
+	if p.opts.User == postgres.SuperuserRole {
+		return fmt.Errorf("the proxy must not connect to PostgreSQL as the superuser %s; use %s", postgres.SuperuserRole, postgres.AppRole)
+	}
+
+	listener, err := net.Listen("tcp", p.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind to %s: %w", p.opts.Addr, err)
+	}
+	p.listener = listener
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		p.loopback = addr.IP.IsLoopback()
+	}
+
+	log.Printf("[INFO] PostgreSQL proxy listening on %s (max connections: %d, TLS: %v)", listener.Addr(), p.opts.MaxConnections, p.opts.TLS != nil)
+	if p.opts.TLS == nil && !p.loopback {
+		log.Printf("[WARN] The PostgreSQL proxy has no TLS: sessions on %s are unencrypted, and keys without a SCRAM verifier cannot log in", listener.Addr())
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.acceptLoop()
+	}()
+	return nil
+}
+
+// Addr returns the address the proxy listens on
+func (p *Proxy) Addr() string {
+	if p.listener != nil {
+		return p.listener.Addr().String()
+	}
+	return p.opts.Addr
+}
+
+// Stop closes the listener and every client session
+func (p *Proxy) Stop() error {
+	if p.listener == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	err := p.listener.Close()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	log.Printf("[INFO] PostgreSQL proxy stopped")
+	return err
+}
+
+func (p *Proxy) acceptLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("[ERROR] PostgreSQL proxy accept error: %v", err)
+			}
+			return
+		}
+		if !p.track(conn) {
+			conn.Close()
+			return
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer p.untrack(conn)
+			p.serve(conn)
+		}()
+	}
+}
+
+// track records an open connection so Stop can close it
+func (p *Proxy) track(conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.conns[conn] = struct{}{}
+	return true
+}
+
+func (p *Proxy) untrack(conn net.Conn) {
+	conn.Close()
+	p.mu.Lock()
+	delete(p.conns, conn)
+	p.mu.Unlock()
+}
+
+// serve runs one client connection: startup, authentication, then the
+// relayed session
+func (p *Proxy) serve(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(startupTimeout))
+
+	// The slot is taken before the client sends anything, so clients that
+	// never log in still count against the cap
+	select {
+	case p.semaphore <- struct{}{}:
+		defer func() { <-p.semaphore }()
+	default:
+		p.fatal(bufio.NewWriter(conn), sqlStateTooManyConnections, "sorry, too many clients already")
+		return
+	}
+
+	conn, client, startup, err := p.negotiate(conn)
+	if err != nil || startup == nil {
+		if err != nil && !errors.Is(err, io.EOF) {
+			log.Printf("[ERROR] PostgreSQL client %s failed to start a session: %v", conn.RemoteAddr(), err)
+		}
+		return
+	}
+	out := bufio.NewWriter(conn)
+	_, encrypted := conn.(*tls.Conn)
+
+	if _, ok := startup.params["replication"]; ok {
+		p.fatal(out, sqlStateFeatureNotSupported, "replication connections are not supported")
+		return
+	}
+	user := startup.params["user"]
+	if user == "" {
+		p.fatal(out, sqlStateInvalidAuthorization, "no PostgreSQL user name specified in startup packet")
+		return
+	}
+
+	key, err := p.authenticate(client, out, user, encrypted || p.loopback)
+	if err != nil {
+		log.Printf("[ERROR] PostgreSQL client %s failed to log in as %q: %v", conn.RemoteAddr(), user, err)
+		return
+	}
+	policy := p.opts.Auth.Policy(key)
+
+	upstream, err := p.connectUpstream(out, startup.params, policy)
+	if err != nil {
+		log.Printf("[ERROR] PostgreSQL proxy failed to connect upstream for key %s: %v", key.ID, err)
+		return
+	}
+	defer upstream.Close()
+
+	conn.SetDeadline(time.Time{})
+	log.Printf("[INFO] PostgreSQL client %s connected with key %s (%s policy)", conn.RemoteAddr(), key.ID, policy.Name())
+
+	s := &session{
+		key:      key,
+		policy:   policy,
+		client:   client,
+		out:      out,
+		upstream: bufio.NewReader(upstream),
+		in:       bufio.NewWriter(upstream),
+	}
+	s.run(conn, upstream)
+	log.Printf("[INFO] PostgreSQL client %s disconnected", conn.RemoteAddr())
+}
+
+// negotiate reads startup packets, forwarding cancel requests, until the
+// client's startup message. SSL is accepted when the proxy has TLS, and
+// the rest of the session runs over the returned TLS connection; GSS
+// encryption is always declined. The message is nil when the connection
+// ends without one.
+func (p *Proxy) negotiate(conn net.Conn) (net.Conn, *bufio.Reader, *startupMessage, error) {
+	client := bufio.NewReader(conn)
+	for {
+		startup, err := readStartup(client)
+		if err != nil {
+			return conn, nil, nil, err
+		}
+
+		switch startup.code {
+		case sslRequest:
+			if _, encrypted := conn.(*tls.Conn); p.opts.TLS == nil || encrypted {
+				if _, err := conn.Write([]byte{'N'}); err != nil {
+					return conn, nil, nil, err
+				}
+				continue
+			}
+			// Bytes sent before the handshake would be taken as if they
+			// came over TLS
+			if client.Buffered() > 0 {
+				return conn, nil, nil, fmt.Errorf("received unencrypted data after SSL request")
+			}
+			if _, err := conn.Write([]byte{'S'}); err != nil {
+				return conn, nil, nil, err
+			}
+			tlsConn := tls.Server(conn, p.opts.TLS)
+			if err := tlsConn.Handshake(); err != nil {
+				return conn, nil, nil, fmt.Errorf("TLS handshake failed: %w", err)
+			}
+			conn, client = tlsConn, bufio.NewReader(tlsConn)
+		case gssEncRequest:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return conn, nil, nil, err
+			}
+		case cancelRequest:
+			p.forwardCancel(startup.cancel)
+			return conn, nil, nil, nil
+		case protocolVersion3:
+			return conn, client, startup, nil
+		default:
+			out := bufio.NewWriter(conn)
+			p.fatal(out, sqlStateFeatureNotSupported, fmt.Sprintf("unsupported frontend protocol %d.%d", startup.code>>16, startup.code&0xffff))
+			return conn, nil, nil, nil
+		}
+	}
+}
+
+// forwardCancel passes a cancel request on to PostgreSQL. Clients know
+// the upstream process ID and secret key, which the proxy relays at
+// startup, so the request needs no translation.
+func (p *Proxy) forwardCancel(cancel []byte) {
+	conn, err := net.DialTimeout("tcp", p.opts.Upstream, startupTimeout)
+	if err != nil {
+		log.Printf("[ERROR] PostgreSQL proxy failed to forward cancel request: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	packet := make([]byte, 8, 8+len(cancel))
+	binary.BigEndian.PutUint32(packet, uint32(8+len(cancel)))
+	binary.BigEndian.PutUint32(packet[4:], cancelRequest)
+	conn.Write(append(packet, cancel...))
+}
+
+// authenticate checks the client's password, which must be one of the API
+// keys, and returns the key. SCRAM-SHA-256 is used for keys with a
+// verifier; older keys fall back to a cleartext password, which is only
+// asked for over a secure connection: TLS or a loopback listener.
+func (p *Proxy) authenticate(client *bufio.Reader, out *bufio.Writer, user string, secure bool) (*server.APIKey, error) {
+	key, err := p.opts.Auth.Lookup(user)
+	if err != nil {
+		p.fatal(out, sqlStateInvalidAuthorization, "failed to load API keys")
+		return nil, err
+	}
+	if key == nil {
+		if enabled, _ := p.opts.Auth.Enabled(); !enabled {
+			p.fatal(out, sqlStateInvalidAuthorization, "no API keys exist: create one with 'vibe keys create' and log in with it as the password")
+			return nil, fmt.Errorf("no keys file")
+		}
+		p.fatal(out, sqlStateInvalidPassword, fmt.Sprintf("password authentication failed for user %q", user))
+		return nil, fmt.Errorf("no active key with that ID or name")
+	}
+
+	if key.SCRAM == "" && !secure {
+		p.fatal(out, sqlStateInvalidAuthorization, fmt.Sprintf("key %q has no SCRAM verifier, so its password would be sent in cleartext: connect with sslmode=require, or create a new key", user))
+		return nil, fmt.Errorf("refused cleartext password without TLS")
+	}
+
+	if key.SCRAM != "" {
+		err = p.authenticateSCRAM(client, out, key)
+	} else {
+		err = p.authenticateCleartext(client, out, key)
+	}
+	if err != nil {
+		p.fatal(out, sqlStateInvalidPassword, fmt.Sprintf("password authentication failed for user %q", user))
+		return nil, err
+	}
+
+	if err := writeMessage(out, 'R', authMessage(authOK, nil)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (p *Proxy) authenticateSCRAM(client *bufio.Reader, out *bufio.Writer, key *server.APIKey) error {
+	verifier, err := scram.ParseVerifier(key.SCRAM)
+	if err != nil {
+		return err
+	}
+	exchange := scram.NewServer(verifier)
+
+	if err := writeMessage(out, 'R', authMessage(authSASL, append(cString(scram.Mechanism), 0))); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	body, err := readPassword(client)
+	if err != nil {
+		return err
+	}
+	mechanism, rest, ok := cutString(body)
+	if !ok || mechanism != scram.Mechanism || len(rest) < 4 {
+		return fmt.Errorf("unsupported SASL mechanism %q", mechanism)
+	}
+	serverFirst, err := exchange.First(rest[4:])
+	if err != nil {
+		return err
+	}
+	if err := writeMessage(out, 'R', authMessage(authSASLContinue, serverFirst)); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	clientFinal, err := readPassword(client)
+	if err != nil {
+		return err
+	}
+	serverFinal, err := exchange.Final(clientFinal)
+	if err != nil {
+		return err
+	}
+	return writeMessage(out, 'R', authMessage(authSASLFinal, serverFinal))
+}
+
+func (p *Proxy) authenticateCleartext(client *bufio.Reader, out *bufio.Writer, key *server.APIKey) error {
+	if err := writeMessage(out, 'R', authMessage(authCleartextPassword, nil)); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	body, err := readPassword(client)
+	if err != nil {
+		return err
+	}
+	password, _, _ := cutString(body)
+	ok, err := p.opts.Auth.Verify(key, password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return scram.ErrAuthFailed
+	}
+	return nil
+}
+
+// readPassword reads a password, SASLInitialResponse or SASLResponse
+// message, all of type 'p'
+func readPassword(client *bufio.Reader) ([]byte, error) {
+	typ, body, err := readMessage(client)
+	if err != nil {
+		return nil, err
+	}
+	if typ != 'p' {
+		return nil, fmt.Errorf("expected a password message, got %q", typ)
+	}
+	return body, nil
+}
+
+// connectUpstream opens the session's PostgreSQL connection and relays
+// its startup messages to the client, up to the first ReadyForQuery. Under
+// a read-only policy, transactions default to READ ONLY. The session runs
+// as the policy's role, which is also what RESET ROLE returns to.
+func (p *Proxy) connectUpstream(out *bufio.Writer, clientParams map[string]string, policy query.Policy) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", p.opts.Upstream, startupTimeout)
+	if err != nil {
+		p.fatal(out, sqlStateConnectionFailure, "could not connect to the database")
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(startupTimeout))
+
+	params := map[string]string{"user": p.opts.User, "database": DefaultDatabase}
+	for _, name := range forwardedParams {
+		if value, ok := clientParams[name]; ok {
+			params[name] = value
+		}
+	}
+	order := append([]string{"user"}, forwardedParams...)
+	if policy.ReadOnly {
+		params["default_transaction_read_only"] = "on"
+		order = append(order, "default_transaction_read_only")
+	}
+	if policy.Role != "" {
+		params["role"] = policy.Role
+		order = append(order, "role")
+	}
+
+	if err := p.startUpstream(conn, out, params, order); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// startUpstream sends the startup message, answers PostgreSQL's
+// authentication request and relays the rest of the startup to the client
+func (p *Proxy) startUpstream(conn net.Conn, out *bufio.Writer, params map[string]string, order []string) error {
+	if _, err := conn.Write(startupPacket(params, order)); err != nil {
+		p.fatal(out, sqlStateConnectionFailure, "could not connect to the database")
+		return err
+	}
+
+	upstream := bufio.NewReader(conn)
+	var exchange *scram.Client
+	for {
+		typ, body, err := readMessage(upstream)
+		if err != nil {
+			p.fatal(out, sqlStateConnectionFailure, "lost the connection to the database")
+			return err
+		}
+
+		switch typ {
+		case 'R':
+			if len(body) < 4 {
+				return fmt.Errorf("malformed authentication request")
+			}
+			code := int(binary.BigEndian.Uint32(body))
+			response, err := p.answerUpstream(code, body[4:], &exchange)
+			if err != nil {
+				p.fatal(out, sqlStateConnectionFailure, "could not authenticate to the database")
+				return err
+			}
+			if response != nil {
+				if err := writeMessage(conn, 'p', response); err != nil {
+					return err
+				}
+			}
+
+		case 'E':
+			// The client gets PostgreSQL's own error, such as a missing
+			// database
+			writeMessage(out, 'E', body)
+			out.Flush()
+			return fmt.Errorf("upstream startup failed: %s", errorMessage(body))
+
+		case 'Z':
+			if err := writeMessage(out, typ, body); err != nil {
+				return err
+			}
+			return out.Flush()
+
+		default:
+			// ParameterStatus, BackendKeyData and notices
+			if err := writeMessage(out, typ, body); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// answerUpstream returns the response to an authentication request from
+// PostgreSQL, or nil when none is due
+func (p *Proxy) answerUpstream(code int, data []byte, exchange **scram.Client) ([]byte, error) {
+	switch code {
+	case authOK:
+		return nil, nil
+	case authCleartextPassword:
+		return cString(p.opts.Password), nil
+	case authMD5Password:
+		return cString(md5Password(p.opts.User, p.opts.Password, data)), nil
+	case authSASL:
+		*exchange = scram.NewClient(p.opts.Password)
+		first, err := (*exchange).First()
+		if err != nil {
+			return nil, err
+		}
+		response := cString(scram.Mechanism)
+		response = binary.BigEndian.AppendUint32(response, uint32(len(first)))
+		return append(response, first...), nil
+	case authSASLContinue:
+		if *exchange == nil {
+			return nil, fmt.Errorf("unexpected SASL continue")
+		}
+		return (*exchange).Final(data)
+	case authSASLFinal:
+		if *exchange == nil {
+			return nil, fmt.Errorf("unexpected SASL final")
+		}
+		return nil, (*exchange).Verify(data)
+	}
+	return nil, fmt.Errorf("unsupported authentication request %d", code)
+}
+
+// fatal sends a FATAL error to the client
+func (p *Proxy) fatal(out *bufio.Writer, code, message string) {
+	writeMessage(out, 'E', errorFields("FATAL", code, message, ""))
+	out.Flush()
+}
+
+// session relays one authenticated client's messages to PostgreSQL and
+// back, checking every query against the key's policy
+type session struct {
+	key    *server.APIKey
+	policy query.Policy
+
+	client   *bufio.Reader
+	out      *bufio.Writer
+	upstream *bufio.Reader
+	in       *bufio.Writer
+
+	// sent counts the messages forwarded upstream that end in a
+	// ReadyForQuery: Query, FunctionCall and Sync
+	sent int
+
+	mu sync.Mutex
+	// rejections are errors for the client, each sent just before the
+	// ReadyForQuery with the given number, so it arrives in order after
+	// the results of earlier queries
+	rejections []rejection
+}
+
+type rejection struct {
+	readyNumber int
+	body        []byte
+}
+
+// run relays messages until either side closes the connection
+func (s *session) run(conn, upstream net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := s.relayResponses(); err != nil && !isClosed(err) {
+			log.Printf("[ERROR] PostgreSQL proxy: %v", err)
+		}
+		// Unblock the client loop
+		conn.Close()
+	}()
+
+	if err := s.relayRequests(); err != nil && !isClosed(err) {
+		log.Printf("[ERROR] PostgreSQL proxy: %v", err)
+	}
+	upstream.Close()
+	<-done
+}
+
+// relayRequests forwards client messages upstream. Queries the policy
+// rejects are replaced by a Sync, whose ReadyForQuery carries the error
+// back to the client. After a rejected Parse, the rest of the extended
+// query is dropped up to its Sync, as PostgreSQL does after an error.
+func (s *session) relayRequests() error {
+	var discarding []byte
+	for {
+		typ, n, err := readHeader(s.client)
+		if err != nil {
+			return err
+		}
+
+		switch typ {
+		case 'Q':
+			body, err := readBody(s.client, n)
+			if err != nil {
+				return err
+			}
+			sql, _, _ := cutString(body)
+			if err := s.check(sql); err != nil {
+				s.reject(err)
+				err = writeMessage(s.in, 'S', nil)
+			} else {
+				err = writeMessage(s.in, 'Q', body)
+			}
+			if err != nil {
+				return err
+			}
+			s.sent++
+
+		case 'P':
+			body, err := readBody(s.client, n)
+			if err != nil {
+				return err
+			}
+			if discarding != nil {
+				break
+			}
+			_, rest, _ := cutString(body)
+			sql, _, _ := cutString(rest)
+			if err := s.check(sql); err != nil {
+				discarding = policyError(err)
+				break
+			}
+			if err := writeMessage(s.in, 'P', body); err != nil {
+				return err
+			}
+
+		case 'F':
+			// Function calls would bypass the SQL policy
+			if _, err := s.client.Discard(n); err != nil {
+				return err
+			}
+			s.rejectBody(errorFields("ERROR", sqlStateInsufficientPriv, "Function calls are not allowed", "Use a SQL query instead"))
+			if err := writeMessage(s.in, 'S', nil); err != nil {
+				return err
+			}
+			s.sent++
+
+		case 'S':
+			if _, err := s.client.Discard(n); err != nil {
+				return err
+			}
+			if discarding != nil {
+				s.rejectBody(discarding)
+				discarding = nil
+			}
+			if err := writeMessage(s.in, 'S', nil); err != nil {
+				return err
+			}
+			s.sent++
+
+		case 'X':
+			writeMessage(s.in, 'X', nil)
+			s.in.Flush()
+			return nil
+
+		default:
+			if discarding != nil {
+				if _, err := s.client.Discard(n); err != nil {
+					return err
+				}
+				break
+			}
+			if err := copyMessage(s.in, s.client, typ, n); err != nil {
+				return err
+			}
+		}
+
+		if err := flushIfIdle(s.in, s.client); err != nil {
+			return err
+		}
+	}
+}
+
+// check logs a query and enforces the session's policy on it
+func (s *session) check(sql string) error {
+	if err := s.policy.CheckClient(sql); err != nil {
+		log.Printf("[ERROR] PostgreSQL query rejected for key %s (%s policy): %v", s.key.ID, s.policy.Name(), err)
+		return err
+	}
+	log.Printf("[INFO] PostgreSQL query for key %s: %.100s", s.key.ID, sql)
+	return nil
+}
+
+// reject queues a policy error for the ReadyForQuery of the next message
+// forwarded upstream
+func (s *session) reject(err error) {
+	s.rejectBody(policyError(err))
+}
+
+func (s *session) rejectBody(body []byte) {
+	s.mu.Lock()
+	s.rejections = append(s.rejections, rejection{readyNumber: s.sent + 1, body: body})
+	s.mu.Unlock()
+}
+
+// relayResponses forwards PostgreSQL's messages to the client, inserting
+// queued rejections before their ReadyForQuery
+func (s *session) relayResponses() error {
+	received := 0
+	for {
+		typ, n, err := readHeader(s.upstream)
+		if err != nil {
+			return err
+		}
+
+		if typ == 'Z' {
+			body, err := readBody(s.upstream, n)
+			if err != nil {
+				return err
+			}
+			received++
+			for _, body := range s.takeRejections(received) {
+				if err := writeMessage(s.out, 'E', body); err != nil {
+					return err
+				}
+			}
+			if err := writeMessage(s.out, 'Z', body); err != nil {
+				return err
+			}
+		} else if err := copyMessage(s.out, s.upstream, typ, n); err != nil {
+			return err
+		}
+
+		if err := flushIfIdle(s.out, s.upstream); err != nil {
+			return err
+		}
+	}
+}
+
+// takeRejections removes and returns the rejections due before the
+// ReadyForQuery with the given number
+func (s *session) takeRejections(readyNumber int) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due [][]byte
+	for len(s.rejections) > 0 && s.rejections[0].readyNumber <= readyNumber {
+		due = append(due, s.rejections[0].body)
+		s.rejections = s.rejections[1:]
+	}
+	return due
+}
+
+// isClosed reports whether err just means a connection was closed
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,410 @@
+package pgproxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+	"github.com/vibesql/vibe/internal/scram"
+	"github.com/vibesql/vibe/internal/server"
+)
+
+const upstreamPassword = "upstream-secret"
+
+// fakeUpstream is a minimal PostgreSQL server: it requires SCRAM for the
+// proxy's role and answers every simple query with one row holding the
+// query text
+type fakeUpstream struct {
+	listener net.Listener
+	params   chan map[string]string
+	queries  chan string
+}
+
+func startUpstream(t *testing.T) *fakeUpstream {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &fakeUpstream{
+		listener: listener,
+		params:   make(chan map[string]string, 10),
+		queries:  make(chan string, 100),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	verifier, err := scram.NewVerifier(upstreamPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go u.serve(conn, verifier)
+		}
+	}()
+	return u
+}
+
+func (u *fakeUpstream) serve(conn net.Conn, verifier *scram.Verifier) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	startup, err := readStartup(r)
+	if err != nil || startup.code != protocolVersion3 {
+		return
+	}
+	u.params <- startup.params
+
+	exchange := scram.NewServer(verifier)
+	writeMessage(conn, 'R', authMessage(authSASL, append(cString(scram.Mechanism), 0)))
+	_, body, err := readMessage(r)
+	if err != nil {
+		return
+	}
+	_, rest, _ := cutString(body)
+	serverFirst, err := exchange.First(rest[4:])
+	if err != nil {
+		return
+	}
+	writeMessage(conn, 'R', authMessage(authSASLContinue, serverFirst))
+	_, body, err = readMessage(r)
+	if err != nil {
+		return
+	}
+	serverFinal, err := exchange.Final(body)
+	if err != nil {
+		writeMessage(conn, 'E', errorFields("FATAL", sqlStateInvalidPassword, "password authentication failed", ""))
+		return
+	}
+	writeMessage(conn, 'R', authMessage(authSASLFinal, serverFinal))
+	writeMessage(conn, 'R', authMessage(authOK, nil))
+	writeMessage(conn, 'S', append(cString("server_version"), cString("16.0")...))
+	writeMessage(conn, 'K', make([]byte, 8))
+	writeMessage(conn, 'Z', []byte{'I'})
+
+	for {
+		typ, body, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case 'Q':
+			sql, _, _ := cutString(body)
+			u.queries <- sql
+			row := binary.BigEndian.AppendUint16(nil, 1)
+			row = binary.BigEndian.AppendUint32(row, uint32(len(sql)))
+			row = append(row, sql...)
+			writeMessage(conn, 'T', rowDescription("query"))
+			writeMessage(conn, 'D', row)
+			writeMessage(conn, 'C', cString("SELECT 1"))
+			writeMessage(conn, 'Z', []byte{'I'})
+		case 'S':
+			writeMessage(conn, 'Z', []byte{'I'})
+		case 'X':
+			return
+		}
+	}
+}
+
+// rowDescription describes one text column
+func rowDescription(name string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, 1)
+	body = append(body, cString(name)...)
+	body = binary.BigEndian.AppendUint32(body, 0)  // table OID
+	body = binary.BigEndian.AppendUint16(body, 0)  // column number
+	body = binary.BigEndian.AppendUint32(body, 25) // text
+	body = binary.BigEndian.AppendUint16(body, 0xffff)
+	body = binary.BigEndian.AppendUint32(body, 0xffffffff)
+	return binary.BigEndian.AppendUint16(body, 0)
+}
+
+// startProxy starts a proxy in front of upstream with the given keys
+func startProxy(t *testing.T, upstream *fakeUpstream, store *server.KeyStore, policy query.Policy) *Proxy {
+	t.Helper()
+	return startTLSProxy(t, upstream, store, policy, nil)
+}
+
+// startTLSProxy starts a proxy that offers TLS with the given configuration
+func startTLSProxy(t *testing.T, upstream *fakeUpstream, store *server.KeyStore, policy query.Policy, tlsConfig *tls.Config) *Proxy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if store != nil {
+		if err := store.Save(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	proxy := New(Options{
+		Addr:     "127.0.0.1:0",
+		Upstream: upstream.listener.Addr().String(),
+		User:     postgres.AppRole,
+		Password: upstreamPassword,
+		Auth:     server.NewAuthenticator(path, policy),
+		TLS:      tlsConfig,
+	})
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { proxy.Stop() })
+	return proxy
+}
+
+func open(t *testing.T, proxy *Proxy, user, password string) *sql.DB {
+	t.Helper()
+	return openWithSSL(t, proxy, user, password, "disable")
+}
+
+func openWithSSL(t *testing.T, proxy *Proxy, user, password, sslmode string) *sql.DB {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(proxy.Addr())
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=postgres sslmode=%s", host, port, user, password, sslmode)
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func queryText(db *sql.DB, sql string) (string, error) {
+	var text string
+	err := db.QueryRow(sql).Scan(&text)
+	return text, err
+}
+
+func sqlState(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+func TestProxy_Query(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	key, secret, _ := store.Create("app", server.ScopeReadWrite)
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+
+	for _, user := range []string{key.ID, "app"} {
+		db := open(t, proxy, user, secret)
+		text, err := queryText(db, "SELECT 1")
+		if err != nil {
+			t.Fatalf("Query as %q error = %v", user, err)
+		}
+		if text != "SELECT 1" {
+			t.Errorf("Query as %q = %q, want the upstream's answer", user, text)
+		}
+	}
+
+	params := <-upstream.params
+	if params["user"] != postgres.AppRole || params["database"] != "postgres" {
+		t.Errorf("Expected the proxy's role upstream, got %v", params)
+	}
+	if _, ok := params["default_transaction_read_only"]; ok {
+		t.Error("Expected read-write sessions for a read-write key")
+	}
+}
+
+func TestProxy_Authentication(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	key, secret, _ := store.Create("app", server.ScopeReadWrite)
+	revoked, revokedSecret, _ := store.Create("old", server.ScopeReadWrite)
+	store.Revoke(revoked.ID)
+
+	// Keys created before SCRAM verifiers were stored use a cleartext
+	// password
+	legacy, legacySecret, _ := store.Create("legacy", server.ScopeReadWrite)
+	legacy.SCRAM = ""
+
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		code     string
+	}{
+		{"wrong password", key.ID, "vibe_nope", sqlStateInvalidPassword},
+		{"another key's secret", key.ID, legacySecret, sqlStateInvalidPassword},
+		{"unknown user", "nobody", secret, sqlStateInvalidPassword},
+		{"revoked key", revoked.ID, revokedSecret, sqlStateInvalidPassword},
+		{"cleartext", legacy.ID, legacySecret, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := queryText(open(t, proxy, tt.user, tt.password), "SELECT 1")
+			if got := sqlState(err); got != tt.code {
+				t.Errorf("Expected SQLSTATE %q, got %q (%v)", tt.code, got, err)
+			}
+		})
+	}
+}
+
+func TestProxy_NoKeysFile(t *testing.T) {
+	upstream := startUpstream(t)
+	proxy := startProxy(t, upstream, nil, query.DefaultPolicy())
+
+	_, err := queryText(open(t, proxy, "app", "secret"), "SELECT 1")
+	if sqlState(err) != sqlStateInvalidAuthorization || !strings.Contains(err.Error(), "vibe keys create") {
+		t.Errorf("Expected a hint to create a key, got %v", err)
+	}
+}
+
+func TestProxy_Policy(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	_, secret, _ := store.Create("bi", server.ScopeReadOnly)
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+	db := open(t, proxy, "bi", secret)
+
+	tests := []struct {
+		sql  string
+		code string
+	}{
+		{"SELECT * FROM users", ""},
+		{"BEGIN", ""},
+		{"DELETE FROM users WHERE id = 1", sqlStateInsufficientPriv},
+		{"BEGIN READ WRITE", sqlStateInsufficientPriv},
+		{"SELECT 'unterminated", sqlStateSyntaxError},
+		{"SELECT 2", ""},
+	}
+	for _, tt := range tests {
+		_, err := queryText(db, tt.sql)
+		if got := sqlState(err); got != tt.code {
+			t.Errorf("%s: expected SQLSTATE %q, got %q (%v)", tt.sql, tt.code, got, err)
+		}
+	}
+
+	params := <-upstream.params
+	if params["default_transaction_read_only"] != "on" {
+		t.Errorf("Expected read-only sessions for a read-only key, got %v", params)
+	}
+
+	// Rejected queries never reach PostgreSQL
+	close(upstream.queries)
+	var forwarded []string
+	for sql := range upstream.queries {
+		forwarded = append(forwarded, sql)
+	}
+	if want := []string{"SELECT * FROM users", "BEGIN", "SELECT 2"}; strings.Join(forwarded, ";") != strings.Join(want, ";") {
+		t.Errorf("Forwarded %q, want %q", forwarded, want)
+	}
+}
+
+func TestProxy_Role(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	key, secret, _ := store.Create("bi", server.ScopeReadWrite)
+	key.Role = "analyst"
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+	db := open(t, proxy, "bi", secret)
+
+	if _, err := queryText(db, "SELECT 1"); err != nil {
+		t.Fatalf("Query error = %v", err)
+	}
+	if params := <-upstream.params; params["role"] != "analyst" || params["user"] != postgres.AppRole {
+		t.Errorf("Expected the session to run as the key's role, got %v", params)
+	}
+	for _, sql := range []string{"RESET ROLE", "SET ROLE postgres", "SELECT set_config('role', 'postgres', false)"} {
+		if _, err := queryText(db, sql); sqlState(err) != sqlStateInsufficientPriv {
+			t.Errorf("%s: expected SQLSTATE %s, got %v", sql, sqlStateInsufficientPriv, err)
+		}
+	}
+}
+
+func TestProxy_MaxConnections(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	_, secret, _ := store.Create("app", server.ScopeReadWrite)
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+	proxy.semaphore = make(chan struct{}, 1)
+
+	first := open(t, proxy, "app", secret)
+	if _, err := queryText(first, "SELECT 1"); err != nil {
+		t.Fatalf("First connection error = %v", err)
+	}
+
+	_, err := queryText(open(t, proxy, "app", secret), "SELECT 1")
+	if sqlState(err) != sqlStateTooManyConnections {
+		t.Errorf("Expected SQLSTATE %s, got %v", sqlStateTooManyConnections, err)
+	}
+}
+
+func TestProxy_RefusesSuperuser(t *testing.T) {
+	proxy := New(Options{Addr: "127.0.0.1:0", User: postgres.SuperuserRole})
+	if err := proxy.Start(); err == nil {
+		proxy.Stop()
+		t.Fatal("Expected the proxy to refuse to connect upstream as the superuser")
+	}
+}
+
+func TestProxy_TLS(t *testing.T) {
+	certFile, keyFile, err := server.EnsureSelfSignedCertificate(t.TempDir(), []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	legacy, secret, _ := store.Create("legacy", server.ScopeReadWrite)
+	legacy.SCRAM = ""
+
+	plain := startProxy(t, upstream, store, query.DefaultPolicy())
+	encrypted := startTLSProxy(t, upstream, store, query.DefaultPolicy(), &tls.Config{Certificates: []tls.Certificate{cert}})
+	// Treat both as reachable from other machines
+	plain.loopback, encrypted.loopback = false, false
+
+	if _, err := queryText(openWithSSL(t, plain, "legacy", secret, "require"), "SELECT 1"); err == nil {
+		t.Error("Expected sslmode=require to fail without TLS")
+	}
+	if _, err := queryText(open(t, plain, "legacy", secret), "SELECT 1"); sqlState(err) != sqlStateInvalidAuthorization {
+		t.Errorf("Expected a cleartext password to be refused without TLS, got %v", err)
+	}
+	if _, err := queryText(openWithSSL(t, encrypted, "legacy", secret, "require"), "SELECT 1"); err != nil {
+		t.Errorf("Expected a cleartext password over TLS to be accepted, got %v", err)
+	}
+}
+
+func TestProxy_MaxConnections_BeforeLogin(t *testing.T) {
+	upstream := startUpstream(t)
+	store := &server.KeyStore{}
+	_, secret, _ := store.Create("app", server.ScopeReadWrite)
+	proxy := startProxy(t, upstream, store, query.DefaultPolicy())
+	proxy.semaphore = make(chan struct{}, 1)
+
+	// A client that never sends its startup packet still holds a slot
+	idle, err := net.Dial("tcp", proxy.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = queryText(open(t, proxy, "app", secret), "SELECT 1")
+	if sqlState(err) != sqlStateTooManyConnections {
+		t.Errorf("Expected SQLSTATE %s while a client has not logged in, got %v", sqlStateTooManyConnections, err)
+	}
+}
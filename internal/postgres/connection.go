@@ -1,8 +1,10 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -13,8 +15,44 @@ const (
 	maxIdleConnections = 2
 	connMaxLifetime    = 1 * time.Hour
 	connMaxIdleTime    = 10 * time.Minute
+
+	defaultStatementTimeout = 5 * time.Second
 )
 
+// ConnectionOptions configures a connection pool. Zero values use the
+// defaults.
+type ConnectionOptions struct {
+	MaxOpenConns int
+	MaxIdleConns int
+	// StatementTimeout is the server-side statement_timeout set on every
+	// connection. It should match the query timeout so PostgreSQL doesn't
+	// cancel queries the executor still allows.
+	StatementTimeout time.Duration
+}
+
+// DefaultConnectionOptions returns the default pool settings
+func DefaultConnectionOptions() ConnectionOptions {
+	return ConnectionOptions{
+		MaxOpenConns:     maxOpenConnections,
+		MaxIdleConns:     maxIdleConnections,
+		StatementTimeout: defaultStatementTimeout,
+	}
+}
+
+func (o ConnectionOptions) withDefaults() ConnectionOptions {
+	defaults := DefaultConnectionOptions()
+	if o.MaxOpenConns <= 0 {
+		o.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if o.StatementTimeout <= 0 {
+		o.StatementTimeout = defaults.StatementTimeout
+	}
+	return o
+}
+
 // Connection represents a PostgreSQL database connection pool
 type Connection struct {
 	db *sql.DB
@@ -22,7 +60,14 @@ type Connection struct {
 
 // NewConnection creates a new connection pool to the PostgreSQL database
 func NewConnection(host string, port int, user string, password string, dbname string) (*Connection, error) {
-	connStr := buildConnectionString(host, port, user, password, dbname)
+	return NewConnectionWithOptions(host, port, user, password, dbname, DefaultConnectionOptions())
+}
+
+// NewConnectionWithOptions creates a new connection pool with the given pool
+// size and statement timeout
+func NewConnectionWithOptions(host string, port int, user string, password string, dbname string, opts ConnectionOptions) (*Connection, error) {
+	opts = opts.withDefaults()
+	connStr := buildConnectionString(host, port, user, password, dbname, opts.StatementTimeout)
 	
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -30,8 +75,8 @@ func NewConnection(host string, port int, user string, password string, dbname s
 	}
 	
 	// Configure connection pool
-	db.SetMaxOpenConns(maxOpenConnections)
-	db.SetMaxIdleConns(maxIdleConnections)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
 	db.SetConnMaxLifetime(connMaxLifetime)
 	db.SetConnMaxIdleTime(connMaxIdleTime)
 	
@@ -44,23 +89,26 @@ func NewConnection(host string, port int, user string, password string, dbname s
 	return &Connection{db: db}, nil
 }
 
-// NewConnectionSimple creates a connection with simplified parameters for localhost
-func NewConnectionSimple(port int) (*Connection, error) {
-	return NewConnection("127.0.0.1", port, "postgres", "", "postgres")
-}
-
 // buildConnectionString constructs a PostgreSQL connection string
-func buildConnectionString(host string, port int, user string, password string, dbname string) string {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=disable statement_timeout=5000",
-		host, port, user, dbname)
+func buildConnectionString(host string, port int, user string, password string, dbname string, statementTimeout time.Duration) string {
+	connStr := fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=disable statement_timeout=%d",
+		host, port, user, dbname, statementTimeout.Milliseconds())
 	
 	if password != "" {
-		connStr += fmt.Sprintf(" password=%s", password)
+		connStr += " password=" + quoteConnValue(password)
 	}
 	
 	return connStr
 }
 
+// quoteConnValue quotes a connection string value the way lib/pq parses
+// it: in single quotes, with backslashes and quotes escaped by a backslash
+func quoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
 // DB returns the underlying database connection pool
 func (c *Connection) DB() *sql.DB {
 	return c.db
@@ -81,3 +129,29 @@ func (c *Connection) Ping() error {
 	}
 	return c.db.Ping()
 }
+
+// PingContext verifies the connection is alive, giving up when ctx is done
+func (c *Connection) PingContext(ctx context.Context) error {
+	if c.db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	return c.db.PingContext(ctx)
+}
+
+// ServerVersion returns the PostgreSQL server version (e.g. "16.1")
+func (c *Connection) ServerVersion() (string, error) {
+	return c.ServerVersionContext(context.Background())
+}
+
+// ServerVersionContext returns the PostgreSQL server version, giving up
+// when ctx is done
+func (c *Connection) ServerVersionContext(ctx context.Context) (string, error) {
+	if c.db == nil {
+		return "", fmt.Errorf("database connection is nil")
+	}
+	var v string
+	if err := c.db.QueryRowContext(ctx, "SHOW server_version").Scan(&v); err != nil {
+		return "", fmt.Errorf("failed to query server version: %w", err)
+	}
+	return v, nil
+}
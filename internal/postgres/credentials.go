@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lib/pq"
+
+	"github.com/vibesql/vibe/internal/scram"
+)
+
+const (
+	// SuperuserRole is the role initdb creates. vibe only uses it to set up
+	// AppRole.
+	SuperuserRole = "postgres"
+	// AppRole is the non-superuser role HTTP queries and PostgreSQL protocol
+	// sessions run as
+	AppRole = "vibe_app"
+	// CredentialsFile holds the generated role passwords, inside the data
+	// directory
+	CredentialsFile = "vibe-credentials.json"
+
+	passwordBytes = 32
+)
+
+// Credentials are the passwords of the embedded instance's roles,
+// generated when the data directory is initialized
+type Credentials struct {
+	SuperuserPassword string `json:"superuserPassword"`
+	AppPassword       string `json:"appPassword"`
+}
+
+// newCredentials generates random passwords
+func newCredentials() (*Credentials, error) {
+	superuser, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	app, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{SuperuserPassword: superuser, AppPassword: app}, nil
+}
+
+// loadCredentials reads the credentials file in dataDir. It returns nil
+// if the file doesn't exist, as in data directories initialized before
+// vibe used passwords.
+func loadCredentials(dataDir string) (*Credentials, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, CredentialsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", CredentialsFile, err)
+	}
+	if creds.SuperuserPassword == "" || creds.AppPassword == "" {
+		return nil, fmt.Errorf("invalid credentials file %s: missing passwords", CredentialsFile)
+	}
+	return &creds, nil
+}
+
+// save writes the credentials file in dataDir, readable only by the
+// server's user. It never replaces an existing file, whose passwords may
+// already be in use.
+func (c *Credentials) save(dataDir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dataDir, CredentialsFile), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create credentials file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return f.Close()
+}
+
+// writePasswordFile writes password to a temporary file for initdb's
+// --pwfile and returns its path
+func writePasswordFile(password string) (string, error) {
+	f, err := os.CreateTemp("", "vibe-pwfile-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(password + "\n"); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// passwordLiteral returns a SQL literal of password's SCRAM verifier.
+// PostgreSQL stores a verifier as given, so the password itself never
+// reaches the server or its statement log.
+func passwordLiteral(password string) (string, error) {
+	verifier, err := scram.NewVerifier(password)
+	if err != nil {
+		return "", err
+	}
+	return pq.QuoteLiteral(verifier.String()), nil
+}
+
+// createAppRole creates AppRole with password. It may create tables and
+// schemas but is not a superuser, so it cannot change server settings,
+// read server files or bypass row-level security. Tables the superuser
+// created before the role existed are handed over to it.
+func createAppRole(db *sql.DB, password string) error {
+	literal, err := passwordLiteral(password)
+	if err != nil {
+		return err
+	}
+	role := pq.QuoteIdentifier(AppRole)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		fmt.Sprintf("CREATE ROLE %s LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOREPLICATION NOBYPASSRLS PASSWORD %s", role, literal),
+		fmt.Sprintf("GRANT CREATE, TEMPORARY ON DATABASE postgres TO %s", role),
+		fmt.Sprintf("GRANT ALL ON SCHEMA public TO %s", role),
+		// Sequences owned by a column follow their table
+		fmt.Sprintf(`DO $$
+DECLARE
+	r record;
+BEGIN
+	FOR r IN
+		SELECT c.oid::regclass AS relation
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+			AND c.relowner = %s::regrole
+			AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('a', 'i')
+			)
+	LOOP
+		EXECUTE format('ALTER TABLE %%s OWNER TO %%I', r.relation, %s);
+	END LOOP;
+END
+$$`, pq.QuoteLiteral(SuperuserRole), pq.QuoteLiteral(AppRole)),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create role %s: %w", AppRole, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// CheckRoles checks the roles queries are configured to run as: each must
+// exist, must not be a superuser, and the connection's role must be a
+// member of it so SET ROLE succeeds.
+func CheckRoles(db *sql.DB, roles []string) error {
+	for _, role := range roles {
+		var superuser, member bool
+		err := db.QueryRow("SELECT rolsuper, pg_has_role(current_user, oid, 'MEMBER') FROM pg_roles WHERE rolname = $1", role).Scan(&superuser, &member)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("role %s does not exist: create it as %s and run GRANT %s TO %s", role, SuperuserRole, pq.QuoteIdentifier(role), AppRole)
+		case err != nil:
+			return fmt.Errorf("failed to check role %s: %w", role, err)
+		case superuser:
+			return fmt.Errorf("role %s is a superuser: queries must run as an ordinary role", role)
+		case !member:
+			return fmt.Errorf("%s is not a member of role %s: run GRANT %s TO %s as %s", AppRole, role, pq.QuoteIdentifier(role), AppRole, SuperuserRole)
+		}
+	}
+	return nil
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, passwordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
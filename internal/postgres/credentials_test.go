@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentials_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	if creds, err := loadCredentials(dir); creds != nil || err != nil {
+		t.Fatalf("loadCredentials() = %v, %v, want nil without a file", creds, err)
+	}
+
+	creds, err := newCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.SuperuserPassword == creds.AppPassword || len(creds.AppPassword) != 2*passwordBytes {
+		t.Errorf("Expected two distinct random passwords, got %+v", creds)
+	}
+	if err := creds.save(dir); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, CredentialsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := loadCredentials(dir)
+	if err != nil {
+		t.Fatalf("loadCredentials() error = %v", err)
+	}
+	if *loaded != *creds {
+		t.Errorf("loadCredentials() = %+v, want %+v", loaded, creds)
+	}
+
+	// Passwords in use are never replaced
+	other, _ := newCredentials()
+	if err := other.save(dir); err == nil {
+		t.Error("Expected save() to refuse to replace the credentials file")
+	}
+}
+
+func TestLoadCredentials_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, CredentialsFile), []byte(`{"superuserPassword": "x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCredentials(dir); err == nil {
+		t.Error("Expected an error for a file without the app password")
+	}
+}
+
+func TestManager_HBATrusts(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir, 5433)
+
+	legacy := "# TYPE  DATABASE  USER  ADDRESS  METHOD\n# local all all trust\nhost all all 127.0.0.1/32 trust\n"
+	if err := os.WriteFile(filepath.Join(dir, "pg_hba.conf"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if trusting, err := m.hbaTrusts(); err != nil || !trusting {
+		t.Errorf("hbaTrusts() = %v, %v, want true", trusting, err)
+	}
+
+	// Without the credentials file a password-protected instance can't be
+	// set up
+	if err := m.writeHBA(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.setupRoles(); err == nil || !strings.Contains(err.Error(), CredentialsFile) {
+		t.Errorf("setupRoles() error = %v, want the missing credentials file", err)
+	}
+}
+
+func TestPasswordLiteral(t *testing.T) {
+	literal, err := passwordLiteral("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(literal, "'SCRAM-SHA-256$4096:") || strings.Contains(literal, "s3cret") {
+		t.Errorf("passwordLiteral() = %s, want a quoted SCRAM verifier", literal)
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=5432 user=postgres dbname=postgres sslmode=disable")
+	if err != nil {
+		t.Skipf("Skipping test: cannot connect to test database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skipf("Skipping test: database not available: %v", err)
+	}
+
+	if _, err := db.Exec(`DO $$ BEGIN CREATE ROLE vibe_test_checked NOLOGIN; EXCEPTION WHEN duplicate_object THEN NULL; END $$`); err != nil {
+		t.Skipf("Skipping test: cannot create a role: %v", err)
+	}
+
+	if err := CheckRoles(db, []string{"vibe_test_checked"}); err != nil {
+		t.Errorf("CheckRoles() error = %v", err)
+	}
+	for _, role := range []string{"vibe_test_missing", SuperuserRole} {
+		if err := CheckRoles(db, []string{"vibe_test_checked", role}); err == nil || !strings.Contains(err.Error(), role) {
+			t.Errorf("CheckRoles(%s) = %v, want an error naming the role", role, err)
+		}
+	}
+}
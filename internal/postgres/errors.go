@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -20,6 +21,10 @@ const (
 	ErrorCodeInternalError       = "INTERNAL_ERROR"
 	ErrorCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
 	ErrorCodeDatabaseUnavailable = "DATABASE_UNAVAILABLE"
+	ErrorCodeTransactionNotFound = "TRANSACTION_NOT_FOUND"
+	ErrorCodeCursorNotFound      = "CURSOR_NOT_FOUND"
+	ErrorCodeStatementNotAllowed = "STATEMENT_NOT_ALLOWED"
+	ErrorCodeUnauthorized        = "UNAUTHORIZED"
 )
 
 // HTTP status codes for VibeSQL errors
@@ -34,6 +39,10 @@ const (
 	HTTPStatusInternalError       = 500
 	HTTPStatusServiceUnavailable  = 503
 	HTTPStatusDatabaseUnavailable = 503
+	HTTPStatusTransactionNotFound = 404
+	HTTPStatusCursorNotFound      = 404
+	HTTPStatusStatementNotAllowed = 403
+	HTTPStatusUnauthorized        = 401
 )
 
 // VibeError represents a VibeSQL error
@@ -68,6 +77,8 @@ var sqlStateToVibeCode = map[string]string{
 	"42P02": ErrorCodeInvalidSQL, // undefined_parameter
 	"42883": ErrorCodeInvalidSQL, // undefined_function
 	"42804": ErrorCodeInvalidSQL, // datatype_mismatch
+	"42P18": ErrorCodeInvalidSQL, // indeterminate_datatype
+	"22P02": ErrorCodeInvalidSQL, // invalid_text_representation
 	
 	// Query cancellation → QUERY_TIMEOUT
 	"57014": ErrorCodeQueryTimeout, // query_canceled
@@ -89,10 +100,20 @@ var sqlStateToVibeCode = map[string]string{
 	// Document size errors → DOCUMENT_TOO_LARGE
 	"54000": ErrorCodeDocumentTooLarge, // program_limit_exceeded
 	"54001": ErrorCodeDocumentTooLarge, // statement_too_complex
+
+	// Writes in a READ ONLY transaction → STATEMENT_NOT_ALLOWED
+	"25006": ErrorCodeStatementNotAllowed, // read_only_sql_transaction
 }
 
 // TranslateError translates a PostgreSQL error to a VibeSQL error
 func TranslateError(err error) *VibeError {
+	return TranslateErrorWithTimeout(err, 0)
+}
+
+// TranslateErrorWithTimeout translates a PostgreSQL error to a VibeSQL error,
+// reporting timeouts against the given query timeout. A zero timeout
+// leaves the limit out of the message.
+func TranslateErrorWithTimeout(err error, timeout time.Duration) *VibeError {
 	if err == nil {
 		return nil
 	}
@@ -108,7 +129,7 @@ func TranslateError(err error) *VibeError {
 		return NewVibeError(
 			ErrorCodeQueryTimeout,
 			"Query execution timeout",
-			"Query exceeded the maximum execution time of 5 seconds",
+			timeoutDetail(timeout),
 		)
 	}
 	
@@ -123,7 +144,12 @@ func TranslateError(err error) *VibeError {
 	// Check if it's a PostgreSQL error
 	var pqErr *pq.Error
 	if errors.As(err, &pqErr) {
-		return translatePQError(pqErr)
+		vibeErr := translatePQError(pqErr)
+		// statement_timeout cancels the query server-side
+		if vibeErr.Code == ErrorCodeQueryTimeout && timeout > 0 {
+			vibeErr.Detail = timeoutDetail(timeout) + " | " + vibeErr.Detail
+		}
+		return vibeErr
 	}
 	
 	// Unknown error type → INTERNAL_ERROR
@@ -134,6 +160,32 @@ func TranslateError(err error) *VibeError {
 	)
 }
 
+func timeoutDetail(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "Query exceeded the maximum execution time"
+	}
+	return fmt.Sprintf("Query exceeded the maximum execution time of %s", FormatDuration(timeout))
+}
+
+// FormatDuration formats a limit for messages, e.g. "5 seconds",
+// "2 minutes", or "1.5s" when it isn't a whole number of seconds
+func FormatDuration(d time.Duration) string {
+	if d%time.Second != 0 {
+		return d.String()
+	}
+	seconds := int64(d / time.Second)
+	switch {
+	case seconds == 1:
+		return "1 second"
+	case seconds == 60:
+		return "1 minute"
+	case seconds%60 == 0:
+		return fmt.Sprintf("%d minutes", seconds/60)
+	default:
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+}
+
 // translatePQError translates a pq.Error to a VibeError
 func translatePQError(pqErr *pq.Error) *VibeError {
 	sqlState := string(pqErr.Code)
@@ -163,6 +215,8 @@ func buildErrorMessage(vibeCode string, pqErr *pq.Error) string {
 		return "Database is unavailable"
 	case ErrorCodeDocumentTooLarge:
 		return "Document too large"
+	case ErrorCodeStatementNotAllowed:
+		return "Statement not allowed"
 	default:
 		// Use PostgreSQL's message if available
 		if pqErr.Message != "" {
@@ -214,6 +268,14 @@ func GetHTTPStatusCode(errorCode string) int {
 		return HTTPStatusServiceUnavailable
 	case ErrorCodeDatabaseUnavailable:
 		return HTTPStatusDatabaseUnavailable
+	case ErrorCodeTransactionNotFound:
+		return HTTPStatusTransactionNotFound
+	case ErrorCodeCursorNotFound:
+		return HTTPStatusCursorNotFound
+	case ErrorCodeStatementNotAllowed:
+		return HTTPStatusStatementNotAllowed
+	case ErrorCodeUnauthorized:
+		return HTTPStatusUnauthorized
 	default:
 		return HTTPStatusInternalError
 	}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -176,6 +178,46 @@ func TestTranslateError_ContextDeadlineExceeded(t *testing.T) {
 	}
 }
 
+func TestTranslateErrorWithTimeout_ReportsConfiguredTimeout(t *testing.T) {
+	testCases := []struct {
+		timeout  time.Duration
+		expected string
+	}{
+		{0, "Query exceeded the maximum execution time"},
+		{5 * time.Second, "Query exceeded the maximum execution time of 5 seconds"},
+		{time.Second, "Query exceeded the maximum execution time of 1 second"},
+		{90 * time.Second, "Query exceeded the maximum execution time of 90 seconds"},
+		{2 * time.Minute, "Query exceeded the maximum execution time of 2 minutes"},
+		{1500 * time.Millisecond, "Query exceeded the maximum execution time of 1.5s"},
+	}
+
+	for _, tc := range testCases {
+		result := TranslateErrorWithTimeout(context.DeadlineExceeded, tc.timeout)
+		if result.Detail != tc.expected {
+			t.Errorf("Timeout %v: expected detail %q, got %q", tc.timeout, tc.expected, result.Detail)
+		}
+	}
+}
+
+func TestTranslateErrorWithTimeout_StatementTimeout(t *testing.T) {
+	pqErr := &pq.Error{
+		Code:    "57014",
+		Message: "canceling statement due to statement timeout",
+	}
+
+	result := TranslateErrorWithTimeout(pqErr, time.Minute)
+
+	if result.Code != ErrorCodeQueryTimeout {
+		t.Errorf("Expected code %s, got %s", ErrorCodeQueryTimeout, result.Code)
+	}
+	if !strings.HasPrefix(result.Detail, "Query exceeded the maximum execution time of 1 minute") {
+		t.Errorf("Expected detail to report the timeout, got %q", result.Detail)
+	}
+	if !strings.Contains(result.Detail, "statement timeout") {
+		t.Errorf("Expected detail to keep the PostgreSQL message, got %q", result.Detail)
+	}
+}
+
 func TestTranslateError_ContextCanceled(t *testing.T) {
 	err := context.Canceled
 	
@@ -227,6 +269,10 @@ func TestGetHTTPStatusCode_AllErrorCodes(t *testing.T) {
 		{ErrorCodeInternalError, 500},
 		{ErrorCodeServiceUnavailable, 503},
 		{ErrorCodeDatabaseUnavailable, 503},
+		{ErrorCodeTransactionNotFound, 404},
+		{ErrorCodeCursorNotFound, 404},
+		{ErrorCodeStatementNotAllowed, 403},
+		{ErrorCodeUnauthorized, 401},
 		{"UNKNOWN_CODE", 500}, // Default to 500
 	}
 	
@@ -304,6 +350,8 @@ func TestSQLStateMapping_AllCodes(t *testing.T) {
 		{"42P02", ErrorCodeInvalidSQL},
 		{"42883", ErrorCodeInvalidSQL},
 		{"42804", ErrorCodeInvalidSQL},
+		{"42P18", ErrorCodeInvalidSQL},
+		{"22P02", ErrorCodeInvalidSQL},
 		
 		// Query cancellation
 		{"57014", ErrorCodeQueryTimeout},
@@ -325,6 +373,9 @@ func TestSQLStateMapping_AllCodes(t *testing.T) {
 		// Document size errors
 		{"54000", ErrorCodeDocumentTooLarge},
 		{"54001", ErrorCodeDocumentTooLarge},
+
+		// Read-only transactions
+		{"25006", ErrorCodeStatementNotAllowed},
 	}
 	
 	for _, tt := range tests {
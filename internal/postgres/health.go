@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthCheckTimeout bounds each database check, so a probe reports the
+// database as unavailable instead of waiting for a free pool connection
+const HealthCheckTimeout = 2 * time.Second
+
+// HealthProbe reports the state of the embedded PostgreSQL process and
+// the connection pool used to reach it
+type HealthProbe struct {
+	manager *Manager
+	conn    *Connection
+
+	mu      sync.Mutex
+	version string
+}
+
+// NewHealthProbe creates a probe for the given manager and connection
+func NewHealthProbe(manager *Manager, conn *Connection) *HealthProbe {
+	return &HealthProbe{
+		manager: manager,
+		conn:    conn,
+	}
+}
+
+// IsRunning reports whether the PostgreSQL process is running
+func (p *HealthProbe) IsRunning() bool {
+	return p.manager.IsRunning()
+}
+
+// Ping verifies the database accepts connections within
+// HealthCheckTimeout
+func (p *HealthProbe) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	return p.conn.PingContext(ctx)
+}
+
+// ServerVersion returns the PostgreSQL server version. The version is
+// cached after the first successful lookup since it can't change while
+// the process is running.
+func (p *HealthProbe) ServerVersion() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.version != "" {
+		return p.version, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HealthCheckTimeout)
+	defer cancel()
+	v, err := p.conn.ServerVersionContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Distribution builds append details, e.g. "16.1 (Debian 16.1-1)"
+	if idx := strings.IndexByte(v, ' '); idx >= 0 {
+		v = v[:idx]
+	}
+	p.version = v
+	return v, nil
+}
@@ -17,6 +17,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 //go:embed embed/*
@@ -51,6 +53,10 @@ type Manager struct {
 	winShareDir string
 	winLibDir   string
 
+	// credentials are the role passwords, loaded or generated with the
+	// data directory
+	credentials *Credentials
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	errCh  chan error
@@ -100,6 +106,11 @@ func (m *Manager) Start() error {
 		return fmt.Errorf("postgres failed to become ready: %w", err)
 	}
 
+	if err := m.setupRoles(); err != nil {
+		_ = m.stopPostgres()
+		return fmt.Errorf("failed to set up database roles: %w", err)
+	}
+
 	m.running = true
 
 	go m.monitorProcess()
@@ -434,6 +445,11 @@ func extractShareTarGz(data []byte, targetDir string) error {
 func (m *Manager) initializeDataDir() error {
 	pgVersionPath := filepath.Join(m.dataDir, "PG_VERSION")
 	if _, err := os.Stat(pgVersionPath); err == nil {
+		creds, err := loadCredentials(m.dataDir)
+		if err != nil {
+			return err
+		}
+		m.credentials = creds
 		return nil
 	}
 
@@ -441,12 +457,35 @@ func (m *Manager) initializeDataDir() error {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
+	// initdb needs an empty directory, but vibe keeps files of its own
+	// there too, such as API keys created before the first start
+	restore, err := stashFiles(m.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to prepare data directory: %w", err)
+	}
+	defer func() {
+		if err := restore(); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}()
+
+	creds, err := newCredentials()
+	if err != nil {
+		return fmt.Errorf("failed to generate passwords: %w", err)
+	}
+	pwfile, err := writePasswordFile(creds.SuperuserPassword)
+	if err != nil {
+		return fmt.Errorf("failed to write password file: %w", err)
+	}
+	defer os.Remove(pwfile)
+
 	initdbArgs := []string{
 		"-D", m.dataDir,
 		"--no-locale",
 		"--encoding=UTF8",
-		"--auth=trust",
-		"--username=postgres",
+		"--auth=scram-sha-256",
+		"--username=" + SuperuserRole,
+		"--pwfile=" + pwfile,
 		"--nosync",
 	}
 
@@ -471,6 +510,14 @@ func (m *Manager) initializeDataDir() error {
 		}
 	}
 
+	// Without the file, nothing could log in to the new instance, so start
+	// over next time
+	if err := creds.save(m.dataDir); err != nil {
+		_ = os.RemoveAll(m.dataDir)
+		return err
+	}
+	m.credentials = creds
+
 	if err := m.createConfigFiles(); err != nil {
 		return fmt.Errorf("failed to create config files: %w", err)
 	}
@@ -478,6 +525,124 @@ func (m *Manager) initializeDataDir() error {
 	return nil
 }
 
+// stashFiles moves the entries of dir to a directory beside it and
+// returns a function that moves them back
+func stashFiles(dir string) (func() error, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return func() error { return nil }, err
+	}
+
+	stash, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dir)), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make([]string, 0, len(entries))
+	restore := func() error {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to restore files from %s: %w", stash, err)
+		}
+		for _, name := range moved {
+			if err := os.Rename(filepath.Join(stash, name), filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("failed to restore files from %s: %w", stash, err)
+			}
+		}
+		return os.Remove(stash)
+	}
+
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(dir, entry.Name()), filepath.Join(stash, entry.Name())); err != nil {
+			if restoreErr := restore(); restoreErr != nil {
+				log.Printf("[ERROR] %v", restoreErr)
+			}
+			return nil, err
+		}
+		moved = append(moved, entry.Name())
+	}
+	return restore, nil
+}
+
+// setupRoles runs once PostgreSQL accepts connections. It creates AppRole
+// if it doesn't exist yet. A data directory from before vibe used
+// passwords still trusts local connections: it gets passwords, and
+// password authentication once AppRole exists.
+func (m *Manager) setupRoles() error {
+	trusting, err := m.hbaTrusts()
+	if err != nil {
+		return err
+	}
+
+	if m.credentials == nil {
+		if !trusting {
+			return fmt.Errorf("%s is missing from %s, so the role passwords are unknown", CredentialsFile, m.dataDir)
+		}
+		creds, err := newCredentials()
+		if err != nil {
+			return fmt.Errorf("failed to generate passwords: %w", err)
+		}
+		if err := creds.save(m.dataDir); err != nil {
+			return err
+		}
+		m.credentials = creds
+	}
+
+	conn, err := NewConnection("127.0.0.1", m.port, SuperuserRole, m.credentials.SuperuserPassword, "postgres")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	db := conn.DB()
+
+	if trusting {
+		literal, err := passwordLiteral(m.credentials.SuperuserPassword)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER ROLE %s PASSWORD %s", pq.QuoteIdentifier(SuperuserRole), literal)); err != nil {
+			return fmt.Errorf("failed to set the %s password: %w", SuperuserRole, err)
+		}
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", AppRole).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up role %s: %w", AppRole, err)
+	}
+	if !exists {
+		if err := createAppRole(db, m.credentials.AppPassword); err != nil {
+			return err
+		}
+		log.Printf("[INFO] Created database role %s", AppRole)
+	}
+
+	if trusting {
+		if err := m.writeHBA(); err != nil {
+			return fmt.Errorf("failed to write pg_hba.conf: %w", err)
+		}
+		if _, err := db.Exec("SELECT pg_reload_conf()"); err != nil {
+			return fmt.Errorf("failed to reload pg_hba.conf: %w", err)
+		}
+		log.Printf("[INFO] Switched PostgreSQL authentication from trust to scram-sha-256 (passwords in %s)", filepath.Join(m.dataDir, CredentialsFile))
+	}
+	return nil
+}
+
+// hbaTrusts reports whether pg_hba.conf lets connections in without a
+// password
+func (m *Manager) hbaTrusts() (bool, error) {
+	data, err := os.ReadFile(filepath.Join(m.dataDir, "pg_hba.conf"))
+	if err != nil {
+		return false, fmt.Errorf("failed to read pg_hba.conf: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "#") && fields[len(fields)-1] == "trust" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *Manager) buildEnv() []string {
 	env := os.Environ()
 	if m.shareDir != "" {
@@ -520,24 +685,30 @@ min_wal_size = 80MB
 log_destination = 'stderr'
 logging_collector = off
 log_statement = 'all'
+password_encryption = 'scram-sha-256'
 `, m.port, shmType)
 
 	if err := os.WriteFile(confPath, []byte(conf), 0600); err != nil {
 		return err
 	}
+	return m.writeHBA()
+}
 
+// writeHBA writes a pg_hba.conf that requires a password for every
+// connection
+func (m *Manager) writeHBA() error {
 	hbaPath := filepath.Join(m.dataDir, "pg_hba.conf")
 	var hba string
 	if runtime.GOOS == "windows" {
 		hba = `# TYPE  DATABASE        USER            ADDRESS                 METHOD
-host    all             all             127.0.0.1/32            trust
-host    all             all             ::1/128                 trust
+host    all             all             127.0.0.1/32            scram-sha-256
+host    all             all             ::1/128                 scram-sha-256
 `
 	} else {
 		hba = `# TYPE  DATABASE        USER            ADDRESS                 METHOD
-local   all             all                                     trust
-host    all             all             127.0.0.1/32            trust
-host    all             all             ::1/128                 trust
+local   all             all                                     scram-sha-256
+host    all             all             127.0.0.1/32            scram-sha-256
+host    all             all             ::1/128                 scram-sha-256
 `
 	}
 	return os.WriteFile(hbaPath, []byte(hba), 0600)
@@ -653,7 +824,7 @@ func (m *Manager) isReady() bool {
 		m.errCh <- err
 		return false
 	default:
-		conn, err := NewConnectionSimple(m.port)
+		conn, err := NewConnection("127.0.0.1", m.port, SuperuserRole, m.superuserPassword(), "postgres")
 		if err != nil {
 			return false
 		}
@@ -706,8 +877,29 @@ func (m *Manager) IsRunning() bool {
 	return m.running
 }
 
+// GetConnectionString returns the connection string for AppRole, without
+// its password (see GetAppCredentials)
 func (m *Manager) GetConnectionString() string {
-	return fmt.Sprintf("host=127.0.0.1 port=%d dbname=postgres user=postgres sslmode=disable", m.port)
+	return fmt.Sprintf("host=127.0.0.1 port=%d dbname=postgres user=%s sslmode=disable", m.port, AppRole)
+}
+
+// GetAppCredentials returns the role and password that clients of the
+// running instance connect as
+func (m *Manager) GetAppCredentials() (user, password string) {
+	if m.credentials == nil {
+		return AppRole, ""
+	}
+	return AppRole, m.credentials.AppPassword
+}
+
+// superuserPassword is empty until the credentials are known, which is
+// fine while a data directory from before vibe used passwords still
+// trusts local connections
+func (m *Manager) superuserPassword() string {
+	if m.credentials == nil {
+		return ""
+	}
+	return m.credentials.SuperuserPassword
 }
 
 func (m *Manager) GetDataDir() string {
@@ -715,11 +907,18 @@ func (m *Manager) GetDataDir() string {
 }
 
 func (m *Manager) CreateConnection() (*Connection, error) {
+	return m.CreateConnectionWithOptions(DefaultConnectionOptions())
+}
+
+// CreateConnectionWithOptions connects to the running instance with the
+// given pool settings
+func (m *Manager) CreateConnectionWithOptions(opts ConnectionOptions) (*Connection, error) {
 	if !m.running {
 		return nil, fmt.Errorf("postgres manager is not running")
 	}
 
-	return NewConnectionSimple(m.port)
+	user, password := m.GetAppCredentials()
+	return NewConnectionWithOptions("127.0.0.1", m.port, user, password, "postgres", opts)
 }
 
 func (m *Manager) GetPort() int {
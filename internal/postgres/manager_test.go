@@ -235,6 +235,12 @@ func TestManager_CreateConfigFiles(t *testing.T) {
 	if !strings.Contains(hbaStr, "127.0.0.1/32") {
 		t.Error("pg_hba.conf missing localhost entry")
 	}
+	if !strings.Contains(hbaStr, "scram-sha-256") {
+		t.Error("pg_hba.conf should require scram-sha-256")
+	}
+	if trusting, err := m.hbaTrusts(); err != nil || trusting {
+		t.Errorf("hbaTrusts() = %v, %v, want false", trusting, err)
+	}
 }
 
 func TestManager_GetConnectionString(t *testing.T) {
@@ -246,12 +252,12 @@ func TestManager_GetConnectionString(t *testing.T) {
 		{
 			name: "default port",
 			port: 5432,
-			want: "host=127.0.0.1 port=5432 dbname=postgres user=postgres sslmode=disable",
+			want: "host=127.0.0.1 port=5432 dbname=postgres user=vibe_app sslmode=disable",
 		},
 		{
 			name: "custom port",
 			port: 5433,
-			want: "host=127.0.0.1 port=5433 dbname=postgres user=postgres sslmode=disable",
+			want: "host=127.0.0.1 port=5433 dbname=postgres user=vibe_app sslmode=disable",
 		},
 	}
 	
@@ -448,3 +454,34 @@ func BenchmarkManager_IsRunning(b *testing.B) {
 }
 
 
+
+func TestStashFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(filepath.Join(dir, "vibe-tls"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vibe-keys.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	restore, err := stashFiles(dir)
+	if err != nil {
+		t.Fatalf("stashFiles() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected an empty directory while stashed, got %d entries", len(entries))
+	}
+
+	if err := restore(); err != nil {
+		t.Fatalf("restore() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "vibe-keys.json")); err != nil || string(data) != "{}" {
+		t.Errorf("Expected the keys file back, got %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "vibe-tls")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the directory back, got %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dir)); len(entries) != 1 {
+		t.Errorf("Expected the stash to be removed, got %d entries beside the data directory", len(entries))
+	}
+}
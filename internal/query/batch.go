@@ -0,0 +1,87 @@
+package query
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+const (
+	// MaxBatchStatements is the maximum number of statements accepted in
+	// a single batch request
+	MaxBatchStatements = 1000
+)
+
+// BatchOptions controls how ExecuteBatch runs its statements
+type BatchOptions struct {
+	// Parallel runs statements concurrently, at most one per connection
+	// of the pool, instead of one after the other
+	Parallel bool
+	// StopOnError skips every statement not yet started once one fails
+	StopOnError bool
+}
+
+// BatchResult is the outcome of one statement of a batch: either Result or
+// Err is set, or neither when the statement was skipped
+type BatchResult struct {
+	Result  *ExecutionResult
+	Err     *postgres.VibeError
+	Skipped bool
+}
+
+// ExecuteBatch runs independent statements, each as its own query outside
+// any transaction, and returns one BatchResult per statement in order.
+// A failing statement does not stop the others unless batch.StopOnError
+// is set.
+func (e *Executor) ExecuteBatch(statements []Statement, opts Options, batch BatchOptions) []BatchResult {
+	results := make([]BatchResult, len(statements))
+
+	workers := 1
+	if batch.Parallel {
+		workers = e.batchWorkers(len(statements))
+	}
+
+	var failed atomic.Bool
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if batch.StopOnError && failed.Load() {
+					results[i].Skipped = true
+					continue
+				}
+
+				result, err := e.ExecuteWithOptions(statements[i].SQL, statements[i].Params, opts)
+				if err != nil {
+					results[i].Err = postgres.TranslateError(err)
+					failed.Store(true)
+					continue
+				}
+				results[i].Result = result
+			}
+		}()
+	}
+
+	for i := range statements {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// batchWorkers returns how many statements of a batch of n may run at
+// once: one per connection the pool may open, or all of them when the
+// pool is unbounded
+func (e *Executor) batchWorkers(n int) int {
+	workers := e.db.Stats().MaxOpenConnections
+	if workers <= 0 || workers > n {
+		workers = n
+	}
+	return workers
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// unreachableDB returns a pool whose connections are always refused
+func unreachableDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=postgres dbname=postgres sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Failed to open pool: %v", err)
+	}
+	return db
+}
+
+func TestExecuteBatch_ReportsEachFailure(t *testing.T) {
+	db := unreachableDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	statements := []Statement{{SQL: "SELECT 1"}, {SQL: "SELECT 2"}, {SQL: "SELECT 3"}}
+	results := executor.ExecuteBatch(statements, Options{}, BatchOptions{})
+
+	if len(results) != len(statements) {
+		t.Fatalf("Expected %d results, got %d", len(statements), len(results))
+	}
+	for i, result := range results {
+		if result.Err == nil || result.Skipped {
+			t.Errorf("Expected statement %d to fail without being skipped, got %+v", i, result)
+		}
+	}
+}
+
+func TestExecuteBatch_StopOnError(t *testing.T) {
+	db := unreachableDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	statements := []Statement{{SQL: "SELECT 1"}, {SQL: "SELECT 2"}, {SQL: "SELECT 3"}}
+	results := executor.ExecuteBatch(statements, Options{}, BatchOptions{StopOnError: true})
+
+	if results[0].Err == nil {
+		t.Fatalf("Expected the first statement to fail, got %+v", results[0])
+	}
+	for i, result := range results[1:] {
+		if !result.Skipped || result.Err != nil || result.Result != nil {
+			t.Errorf("Expected statement %d to be skipped, got %+v", i+1, result)
+		}
+	}
+}
+
+func TestExecutor_BatchWorkers(t *testing.T) {
+	db := unreachableDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	if workers := executor.batchWorkers(50); workers != 50 {
+		t.Errorf("Expected an unbounded pool to run every statement at once, got %d workers", workers)
+	}
+
+	db.SetMaxOpenConns(4)
+	if workers := executor.batchWorkers(50); workers != 4 {
+		t.Errorf("Expected one worker per pooled connection, got %d", workers)
+	}
+	if workers := executor.batchWorkers(2); workers != 2 {
+		t.Errorf("Expected no more workers than statements, got %d", workers)
+	}
+}
+
+func TestExecuteBatch_Parallel(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(4)
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	statements := make([]Statement, 20)
+	for i := range statements {
+		statements[i] = Statement{SQL: "SELECT $1::int AS n", Params: []interface{}{i}}
+	}
+	statements[7] = Statement{SQL: "SELECT * FROM batch_missing_table"}
+
+	results := executor.ExecuteBatch(statements, Options{}, BatchOptions{Parallel: true})
+
+	for i, result := range results {
+		if i == 7 {
+			if result.Err == nil || result.Err.Code != postgres.ErrorCodeInvalidSQL {
+				t.Errorf("Expected statement 7 to fail with INVALID_SQL, got %+v", result.Err)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("Statement %d unexpected error = %v", i, result.Err)
+		}
+		if n := result.Result.Rows[0]["n"]; n != int64(i) {
+			t.Errorf("Expected statement %d to return %d, got %v", i, i, n)
+		}
+	}
+}
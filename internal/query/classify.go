@@ -0,0 +1,401 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// StatementClass groups SQL statements by what they do, for policy checks
+type StatementClass string
+
+const (
+	// ClassQuery is a read-only query: SELECT, VALUES, TABLE or WITH ... SELECT
+	ClassQuery  StatementClass = "query"
+	ClassInsert StatementClass = "insert"
+	ClassUpdate StatementClass = "update"
+	ClassDelete StatementClass = "delete"
+	ClassMerge  StatementClass = "merge"
+	// ClassDDL changes the schema: CREATE, ALTER, DROP, COMMENT ON
+	ClassDDL      StatementClass = "ddl"
+	ClassTruncate StatementClass = "truncate"
+	ClassExplain  StatementClass = "explain"
+	ClassShow     StatementClass = "show"
+	// ClassCopy is COPY ... TO STDOUT
+	ClassCopy StatementClass = "copy"
+	// ClassSession changes session state: SET, RESET, DISCARD
+	ClassSession StatementClass = "session"
+	// ClassTransaction controls transactions: BEGIN, COMMIT, ROLLBACK, SAVEPOINT
+	ClassTransaction StatementClass = "transaction"
+	// ClassPrivilege changes permissions: GRANT, REVOKE
+	ClassPrivilege StatementClass = "privilege"
+	// ClassMaintenance is VACUUM, ANALYZE, REINDEX, CLUSTER, CHECKPOINT or
+	// REFRESH MATERIALIZED VIEW
+	ClassMaintenance StatementClass = "maintenance"
+	// ClassProcedure runs server-side code: CALL, DO
+	ClassProcedure StatementClass = "procedure"
+	// ClassOther is any other statement PostgreSQL accepts, such as LOCK,
+	// LISTEN, PREPARE or cursor commands
+	ClassOther StatementClass = "other"
+)
+
+// StatementClasses lists every statement class
+var StatementClasses = []StatementClass{
+	ClassQuery, ClassInsert, ClassUpdate, ClassDelete, ClassMerge,
+	ClassDDL, ClassTruncate, ClassExplain, ClassShow, ClassCopy,
+	ClassSession, ClassTransaction, ClassPrivilege, ClassMaintenance,
+	ClassProcedure, ClassOther,
+}
+
+// commandClasses maps a statement's leading keyword to its class
+var commandClasses = map[string]StatementClass{
+	"SELECT": ClassQuery,
+	"VALUES": ClassQuery,
+	"TABLE":  ClassQuery,
+	"WITH":   ClassQuery,
+
+	"INSERT": ClassInsert,
+	"UPDATE": ClassUpdate,
+	"DELETE": ClassDelete,
+	"MERGE":  ClassMerge,
+
+	"CREATE":  ClassDDL,
+	"ALTER":   ClassDDL,
+	"DROP":    ClassDDL,
+	"COMMENT": ClassDDL,
+
+	"TRUNCATE": ClassTruncate,
+	"EXPLAIN":  ClassExplain,
+	"SHOW":     ClassShow,
+	"COPY":     ClassCopy,
+
+	"SET":     ClassSession,
+	"RESET":   ClassSession,
+	"DISCARD": ClassSession,
+
+	"BEGIN":     ClassTransaction,
+	"START":     ClassTransaction,
+	"COMMIT":    ClassTransaction,
+	"END":       ClassTransaction,
+	"ROLLBACK":  ClassTransaction,
+	"ABORT":     ClassTransaction,
+	"SAVEPOINT": ClassTransaction,
+	"RELEASE":   ClassTransaction,
+
+	"GRANT":  ClassPrivilege,
+	"REVOKE": ClassPrivilege,
+
+	"VACUUM":     ClassMaintenance,
+	"ANALYZE":    ClassMaintenance,
+	"ANALYSE":    ClassMaintenance,
+	"REINDEX":    ClassMaintenance,
+	"CLUSTER":    ClassMaintenance,
+	"CHECKPOINT": ClassMaintenance,
+	"REFRESH":    ClassMaintenance,
+
+	"CALL": ClassProcedure,
+	"DO":   ClassProcedure,
+
+	"LOCK":       ClassOther,
+	"LISTEN":     ClassOther,
+	"UNLISTEN":   ClassOther,
+	"NOTIFY":     ClassOther,
+	"PREPARE":    ClassOther,
+	"EXECUTE":    ClassOther,
+	"DEALLOCATE": ClassOther,
+	"DECLARE":    ClassOther,
+	"FETCH":      ClassOther,
+	"MOVE":       ClassOther,
+	"CLOSE":      ClassOther,
+	"LOAD":       ClassOther,
+	"SECURITY":   ClassOther,
+	"IMPORT":     ClassOther,
+	"REASSIGN":   ClassOther,
+}
+
+// ParsedStatement is one classified statement of a SQL string
+type ParsedStatement struct {
+	Class StatementClass
+	// Command is the leading keyword, upper-cased. For WITH queries it is
+	// the keyword of the main statement after the CTEs, e.g. "SELECT".
+	Command string
+	// SQL is the statement text without the separating semicolon
+	SQL string
+	// Explained is the statement an EXPLAIN applies to
+	Explained *ParsedStatement
+
+	tokens []token
+}
+
+// ParseStatements tokenizes sql, splits it into statements at top-level
+// semicolons and classifies each one. Empty statements are dropped.
+// Statements with an unrecognized leading keyword have an empty Class.
+func ParseStatements(sql string) ([]*ParsedStatement, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []*ParsedStatement
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].isPunct(";") {
+			continue
+		}
+		if i > start {
+			stmtTokens := tokens[start:i]
+			stmt := classify(stmtTokens)
+			stmt.SQL = sql[stmtTokens[0].pos:stmtTokens[len(stmtTokens)-1].end]
+			statements = append(statements, stmt)
+		}
+		start = i + 1
+	}
+	return statements, nil
+}
+
+// classify determines the class of one statement's tokens
+func classify(tokens []token) *ParsedStatement {
+	stmt := &ParsedStatement{tokens: tokens}
+
+	// A query may be wrapped in parentheses: (SELECT 1) UNION (SELECT 2)
+	i := 0
+	for i < len(tokens) && tokens[i].isPunct("(") {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].kind != tokenWord {
+		return stmt
+	}
+
+	command := strings.ToUpper(tokens[i].text)
+	class, ok := commandClasses[command]
+	if !ok {
+		return stmt
+	}
+	stmt.Command = command
+	stmt.Class = class
+
+	switch command {
+	case "WITH":
+		if main := mainStatementIndex(tokens, i+1); main >= 0 {
+			stmt.Command = strings.ToUpper(tokens[main].text)
+			stmt.Class = commandClasses[stmt.Command]
+		}
+	case "EXPLAIN":
+		if j := explainedStatementIndex(tokens, i+1); j < len(tokens) {
+			stmt.Explained = classify(tokens[j:])
+		}
+	case "COPY":
+		if !copiesToStdout(tokens) {
+			stmt.Class = ClassOther
+		}
+	}
+	return stmt
+}
+
+// mainStatementIndex finds the statement that follows a WITH clause's
+// common table expressions, whose bodies are parenthesized
+func mainStatementIndex(tokens []token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.isAny("SELECT", "VALUES", "TABLE", "INSERT", "UPDATE", "DELETE", "MERGE"):
+			return i
+		}
+	}
+	return -1
+}
+
+// explainedStatementIndex skips EXPLAIN's options, either a parenthesized
+// list or the legacy ANALYZE and VERBOSE keywords
+func explainedStatementIndex(tokens []token, i int) int {
+	if i < len(tokens) && tokens[i].isPunct("(") {
+		depth := 0
+		for ; i < len(tokens); i++ {
+			if tokens[i].isPunct("(") {
+				depth++
+			} else if tokens[i].isPunct(")") {
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return i
+	}
+	for i < len(tokens) && tokens[i].isAny("ANALYZE", "ANALYSE", "VERBOSE") {
+		i++
+	}
+	return i
+}
+
+// analyzes reports whether an EXPLAIN statement executes the explained
+// statement, via the legacy ANALYZE keyword or an ANALYZE option that is
+// not switched off. As in PostgreSQL, the last ANALYZE option wins.
+func (s *ParsedStatement) analyzes() bool {
+	if s.Class != ClassExplain || len(s.tokens) < 2 {
+		return false
+	}
+
+	tokens := s.tokens[1:]
+	if !tokens[0].isPunct("(") {
+		return tokens[0].isAny("ANALYZE", "ANALYSE") ||
+			(tokens[0].is("VERBOSE") && len(tokens) > 1 && tokens[1].isAny("ANALYZE", "ANALYSE"))
+	}
+
+	end := closingParen(tokens, 0)
+	analyze := false
+	for i := 1; i < end; i++ {
+		if tokens[i].isAny("ANALYZE", "ANALYSE") {
+			analyze = i+1 >= end || !isFalseOption(tokens[i+1])
+		}
+	}
+	return analyze
+}
+
+// isFalseOption reports whether a token switches a boolean option off
+func isFalseOption(t token) bool {
+	if t.kind == tokenNumber {
+		return t.text == "0"
+	}
+	return t.isAny("FALSE", "OFF") || (t.kind == tokenString && (t.text == "'false'" || t.text == "'off'"))
+}
+
+// copiesToStdout reports whether a COPY statement writes to STDOUT, the
+// only direction that doesn't touch the server's files or read client data
+func copiesToStdout(tokens []token) bool {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("FROM"):
+			return false
+		case depth == 0 && t.is("TO"):
+			return i+1 < len(tokens) && tokens[i+1].is("STDOUT")
+		}
+	}
+	return false
+}
+
+// defaultAllowedClasses are the statement classes a zero StatementPolicy
+// allows. Session, transaction and privilege statements would leak state
+// across pooled connections or bypass the API's own transaction handling,
+// and procedures and maintenance commands can run arbitrary or long work.
+var defaultAllowedClasses = map[StatementClass]bool{
+	ClassQuery:    true,
+	ClassInsert:   true,
+	ClassUpdate:   true,
+	ClassDelete:   true,
+	ClassMerge:    true,
+	ClassDDL:      true,
+	ClassTruncate: true,
+	ClassExplain:  true,
+	ClassShow:     true,
+	ClassCopy:     true,
+}
+
+// StatementPolicy decides which statement classes may be executed. The
+// zero value allows the default classes; Allow and Deny return modified
+// copies. Policies are comparable, so Limits stays comparable too.
+type StatementPolicy struct {
+	allowed uint32
+	denied  uint32
+}
+
+// classBit returns the policy bit of a statement class
+func classBit(class StatementClass) uint32 {
+	for i, c := range StatementClasses {
+		if c == class {
+			return 1 << uint(i)
+		}
+	}
+	return 0
+}
+
+// Allow returns a copy of the policy that also allows the given classes
+func (p StatementPolicy) Allow(classes ...StatementClass) StatementPolicy {
+	for _, class := range classes {
+		p.allowed |= classBit(class)
+		p.denied &^= classBit(class)
+	}
+	return p
+}
+
+// Deny returns a copy of the policy that rejects the given classes
+func (p StatementPolicy) Deny(classes ...StatementClass) StatementPolicy {
+	for _, class := range classes {
+		p.denied |= classBit(class)
+		p.allowed &^= classBit(class)
+	}
+	return p
+}
+
+// Allows reports whether statements of the class may be executed
+func (p StatementPolicy) Allows(class StatementClass) bool {
+	bit := classBit(class)
+	switch {
+	case bit == 0 || p.denied&bit != 0:
+		return false
+	case p.allowed&bit != 0:
+		return true
+	}
+	return defaultAllowedClasses[class]
+}
+
+// Check returns a STATEMENT_NOT_ALLOWED error for a statement the policy
+// does not allow. EXPLAIN is checked against the explained statement as well.
+func (p StatementPolicy) Check(stmt *ParsedStatement) error {
+	if !p.Allows(stmt.Class) {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeStatementNotAllowed,
+			"Statement not allowed",
+			fmt.Sprintf("%s statements (%s) are not allowed", stmt.Command, stmt.Class),
+		)
+	}
+
+	// Data-modifying statements can also hide in a WITH clause or in
+	// COPY (...) TO STDOUT
+	for _, nested := range nestedModifications(stmt.tokens) {
+		if !p.Allows(nested.Class) {
+			return postgres.NewVibeError(
+				postgres.ErrorCodeStatementNotAllowed,
+				"Statement not allowed",
+				fmt.Sprintf("%s statements (%s) are not allowed", nested.Command, nested.Class),
+			)
+		}
+	}
+
+	if stmt.Explained != nil {
+		return p.Check(stmt.Explained)
+	}
+	return nil
+}
+
+// nestedModifications finds the INSERT, UPDATE, DELETE and MERGE statements
+// nested in parentheses within a statement's tokens
+func nestedModifications(tokens []token) []*ParsedStatement {
+	var nested []*ParsedStatement
+	for i := 1; i+1 < len(tokens); i++ {
+		if !tokens[i-1].isPunct("(") {
+			continue
+		}
+
+		t, next := tokens[i], tokens[i+1]
+		switch {
+		case t.isAny("INSERT", "MERGE") && next.is("INTO"),
+			t.is("DELETE") && next.is("FROM"),
+			t.is("UPDATE") && (next.kind == tokenWord || next.kind == tokenIdent):
+			command := strings.ToUpper(t.text)
+			nested = append(nested, &ParsedStatement{Command: command, Class: commandClasses[command]})
+		}
+	}
+	return nested
+}
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func TestParseStatements_Classes(t *testing.T) {
+	tests := []struct {
+		sql     string
+		class   StatementClass
+		command string
+	}{
+		{"SELECT 1", ClassQuery, "SELECT"},
+		{"select * from users", ClassQuery, "SELECT"},
+		{"VALUES (1, 'a'), (2, 'b')", ClassQuery, "VALUES"},
+		{"TABLE users", ClassQuery, "TABLE"},
+		{"(SELECT 1) UNION (SELECT 2)", ClassQuery, "SELECT"},
+		{"-- leading comment\nSELECT 1", ClassQuery, "SELECT"},
+		{"/* block */ SELECT 1", ClassQuery, "SELECT"},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", ClassQuery, "SELECT"},
+		{"WITH RECURSIVE t(n) AS (VALUES (1) UNION ALL SELECT n+1 FROM t WHERE n < 5) SELECT n FROM t", ClassQuery, "SELECT"},
+		{"WITH t AS MATERIALIZED (SELECT 1) SELECT * FROM t", ClassQuery, "SELECT"},
+		{"WITH old AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM old)", ClassDelete, "DELETE"},
+		{"WITH v AS (SELECT 1) INSERT INTO t SELECT * FROM v", ClassInsert, "INSERT"},
+		{"INSERT INTO users (name) VALUES ('a')", ClassInsert, "INSERT"},
+		{"UPDATE users SET name = 'a' WHERE id = 1", ClassUpdate, "UPDATE"},
+		{"DELETE FROM users WHERE id = 1", ClassDelete, "DELETE"},
+		{"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DO NOTHING", ClassMerge, "MERGE"},
+		{"CREATE TABLE t (id int)", ClassDDL, "CREATE"},
+		{"CREATE INDEX CONCURRENTLY idx ON t (id)", ClassDDL, "CREATE"},
+		{"ALTER TABLE t ADD COLUMN c text", ClassDDL, "ALTER"},
+		{"DROP TABLE t", ClassDDL, "DROP"},
+		{"COMMENT ON TABLE t IS 'x'", ClassDDL, "COMMENT"},
+		{"TRUNCATE t", ClassTruncate, "TRUNCATE"},
+		{"EXPLAIN SELECT 1", ClassExplain, "EXPLAIN"},
+		{"SHOW server_version", ClassShow, "SHOW"},
+		{"COPY users TO STDOUT", ClassCopy, "COPY"},
+		{"COPY (SELECT 1) TO STDOUT WITH (FORMAT csv)", ClassCopy, "COPY"},
+		{"COPY users FROM STDIN", ClassOther, "COPY"},
+		{"COPY users TO '/tmp/users.csv'", ClassOther, "COPY"},
+		{"SET search_path = public", ClassSession, "SET"},
+		{"RESET ALL", ClassSession, "RESET"},
+		{"BEGIN", ClassTransaction, "BEGIN"},
+		{"COMMIT", ClassTransaction, "COMMIT"},
+		{"GRANT SELECT ON t TO bob", ClassPrivilege, "GRANT"},
+		{"VACUUM ANALYZE t", ClassMaintenance, "VACUUM"},
+		{"REFRESH MATERIALIZED VIEW v", ClassMaintenance, "REFRESH"},
+		{"DO $$ BEGIN DELETE FROM t; END $$", ClassProcedure, "DO"},
+		{"CALL my_proc()", ClassProcedure, "CALL"},
+		{"LOCK TABLE t", ClassOther, "LOCK"},
+		{"this is not sql", "", ""},
+		{"12345", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			statements, err := ParseStatements(tt.sql)
+			if err != nil {
+				t.Fatalf("ParseStatements() unexpected error = %v", err)
+			}
+			if len(statements) != 1 {
+				t.Fatalf("Expected 1 statement, got %d", len(statements))
+			}
+			if statements[0].Class != tt.class || statements[0].Command != tt.command {
+				t.Errorf("Expected %s (%s), got %s (%s)", tt.class, tt.command, statements[0].Class, statements[0].Command)
+			}
+		})
+	}
+}
+
+func TestParseStatements_Split(t *testing.T) {
+	statements, err := ParseStatements("SELECT ';'; ; DELETE FROM t WHERE id = 1;\n-- done\n")
+	if err != nil {
+		t.Fatalf("ParseStatements() unexpected error = %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(statements))
+	}
+	if statements[0].SQL != "SELECT ';'" {
+		t.Errorf("Unexpected first statement text %q", statements[0].SQL)
+	}
+	if statements[1].Class != ClassDelete || statements[1].SQL != "DELETE FROM t WHERE id = 1" {
+		t.Errorf("Unexpected second statement %s %q", statements[1].Class, statements[1].SQL)
+	}
+
+	statements, err = ParseStatements(" -- only a comment ;")
+	if err != nil {
+		t.Fatalf("ParseStatements() unexpected error = %v", err)
+	}
+	if len(statements) != 0 {
+		t.Errorf("Expected no statements, got %d", len(statements))
+	}
+}
+
+func TestParseStatements_Explain(t *testing.T) {
+	tests := []struct {
+		sql       string
+		explained StatementClass
+	}{
+		{"EXPLAIN SELECT 1", ClassQuery},
+		{"EXPLAIN ANALYZE VERBOSE DELETE FROM t WHERE id = 1", ClassDelete},
+		{"EXPLAIN (ANALYZE, FORMAT JSON) UPDATE t SET a = 1 WHERE id = 1", ClassUpdate},
+		{"EXPLAIN (FORMAT JSON) WITH x AS (SELECT 1) SELECT * FROM x", ClassQuery},
+	}
+
+	for _, tt := range tests {
+		statements, err := ParseStatements(tt.sql)
+		if err != nil {
+			t.Fatalf("ParseStatements(%q) unexpected error = %v", tt.sql, err)
+		}
+		explained := statements[0].Explained
+		if explained == nil {
+			t.Fatalf("%q: expected an explained statement", tt.sql)
+		}
+		if explained.Class != tt.explained {
+			t.Errorf("%q: expected explained class %s, got %s", tt.sql, tt.explained, explained.Class)
+		}
+	}
+}
+
+func TestStatementPolicy(t *testing.T) {
+	var policy StatementPolicy
+
+	for _, class := range []StatementClass{ClassQuery, ClassInsert, ClassDDL, ClassExplain, ClassShow, ClassCopy} {
+		if !policy.Allows(class) {
+			t.Errorf("Expected default policy to allow %s", class)
+		}
+	}
+	for _, class := range []StatementClass{ClassSession, ClassTransaction, ClassPrivilege, ClassProcedure, ClassOther, ""} {
+		if policy.Allows(class) {
+			t.Errorf("Expected default policy to deny %q", class)
+		}
+	}
+
+	custom := policy.Deny(ClassDDL, ClassTruncate).Allow(ClassMaintenance)
+	if custom.Allows(ClassDDL) || custom.Allows(ClassTruncate) || !custom.Allows(ClassMaintenance) {
+		t.Error("Expected Allow and Deny to override the defaults")
+	}
+	if !policy.Allows(ClassDDL) {
+		t.Error("Expected Deny to return a copy")
+	}
+	if !custom.Allow(ClassDDL).Allows(ClassDDL) {
+		t.Error("Expected Allow to undo Deny")
+	}
+	if custom == policy {
+		t.Error("Expected modified policy to differ from the zero policy")
+	}
+}
+
+func TestStatementPolicy_Check(t *testing.T) {
+	policy := StatementPolicy{}.Deny(ClassDelete)
+
+	tests := []struct {
+		sql        string
+		wantErr    bool
+		wantDetail string
+	}{
+		{"SELECT 1", false, ""},
+		{"DELETE FROM t WHERE id = 1", true, "DELETE statements (delete) are not allowed"},
+		{"EXPLAIN DELETE FROM t WHERE id = 1", true, "DELETE statements (delete) are not allowed"},
+		{"WITH d AS (DELETE FROM t WHERE id = 1 RETURNING id) SELECT * FROM d", true, "DELETE statements (delete) are not allowed"},
+		{"WITH i AS (INSERT INTO t VALUES (1) RETURNING id) SELECT * FROM i", false, ""},
+		{"BEGIN", true, "BEGIN statements (transaction) are not allowed"},
+		{"COPY t FROM STDIN", true, "COPY statements (other) are not allowed"},
+	}
+
+	for _, tt := range tests {
+		statements, err := ParseStatements(tt.sql)
+		if err != nil {
+			t.Fatalf("ParseStatements(%q) unexpected error = %v", tt.sql, err)
+		}
+		err = policy.Check(statements[0])
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: Check() error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		vibeErr := err.(*postgres.VibeError)
+		if vibeErr.Code != postgres.ErrorCodeStatementNotAllowed || vibeErr.Detail != tt.wantDetail {
+			t.Errorf("%q: unexpected error %s: %s", tt.sql, vibeErr.Code, vibeErr.Detail)
+		}
+	}
+}
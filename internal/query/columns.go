@@ -0,0 +1,40 @@
+package query
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq/oid"
+)
+
+// Column describes a result column
+type Column struct {
+	Name string
+	// Type is the PostgreSQL type name as in pg_type.typname
+	// (e.g. "int4", "jsonb", "_text"), empty for types the driver doesn't know
+	Type string
+	// TypeOID is the PostgreSQL type OID, zero when Type is unknown
+	TypeOID uint32
+}
+
+// typeOIDs maps upper-case type names reported by lib/pq back to their OIDs
+var typeOIDs = func() map[string]uint32 {
+	m := make(map[string]uint32, len(oid.TypeName))
+	for o, name := range oid.TypeName {
+		m[name] = uint32(o)
+	}
+	return m
+}()
+
+func describeColumns(columnTypes []*sql.ColumnType) []Column {
+	columns := make([]Column, len(columnTypes))
+	for i, ct := range columnTypes {
+		typeName := ct.DatabaseTypeName()
+		columns[i] = Column{
+			Name:    ct.Name(),
+			Type:    strings.ToLower(typeName),
+			TypeOID: typeOIDs[typeName],
+		}
+	}
+	return columns
+}
@@ -0,0 +1,23 @@
+package query
+
+import "testing"
+
+func TestTypeOIDs(t *testing.T) {
+	testCases := []struct {
+		typeName string
+		expected uint32
+	}{
+		{"INT4", 23},
+		{"TEXT", 25},
+		{"JSONB", 3802},
+		{"_INT4", 1007},
+		{"NUMERIC", 1700},
+		{"UNKNOWN_TYPE", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := typeOIDs[tc.typeName]; got != tc.expected {
+			t.Errorf("typeOIDs[%s] = %d, want %d", tc.typeName, got, tc.expected)
+		}
+	}
+}
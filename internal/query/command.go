@@ -0,0 +1,99 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// returnsRows reports whether a statement may return rows. Commands that
+// never do, such as INSERT without RETURNING or DDL, are run with Exec so
+// the number of rows they affected is known.
+func returnsRows(stmt *ParsedStatement) bool {
+	switch stmt.Class {
+	case ClassInsert, ClassUpdate, ClassDelete, ClassMerge:
+		return hasTopLevelReturning(stmt.tokens)
+	case ClassDDL, ClassTruncate, ClassSession, ClassTransaction, ClassPrivilege, ClassMaintenance:
+		return false
+	case ClassProcedure:
+		// DO never returns rows; CALL returns the procedure's OUT parameters
+		return stmt.Command != "DO"
+	}
+	return true
+}
+
+// hasTopLevelReturning reports whether tokens contain a RETURNING clause
+// outside any parentheses, i.e. one of the main statement rather than of a
+// data-modifying CTE
+func hasTopLevelReturning(tokens []token) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("RETURNING"):
+			return true
+		}
+	}
+	return false
+}
+
+// objectTypeWords lists the object types of CREATE, ALTER and DROP that
+// span two words, such as MATERIALIZED VIEW
+var objectTypeWords = map[string]int{
+	"ACCESS":       2,
+	"DEFAULT":      2,
+	"EVENT":        2,
+	"FOREIGN":      2,
+	"MATERIALIZED": 2,
+	"USER":         2,
+	"TEXT":         3,
+}
+
+// commandTag builds the command tag PostgreSQL reports for a statement
+// that affected or returned n rows, e.g. "UPDATE 3" or "INSERT 0 1".
+// Commands without a row count are tagged with their leading keywords,
+// e.g. "CREATE TABLE".
+func commandTag(stmt *ParsedStatement, n int64) string {
+	switch stmt.Command {
+	case "INSERT":
+		return fmt.Sprintf("INSERT 0 %d", n)
+	case "UPDATE", "DELETE", "MERGE", "COPY", "FETCH", "MOVE":
+		return fmt.Sprintf("%s %d", stmt.Command, n)
+	case "SELECT", "VALUES", "TABLE":
+		return fmt.Sprintf("SELECT %d", n)
+	case "TRUNCATE":
+		return "TRUNCATE TABLE"
+	case "CREATE", "ALTER", "DROP":
+		return stmt.Command + objectType(stmt.tokens[1:])
+	}
+	return stmt.Command
+}
+
+// objectType returns the object type following CREATE, ALTER or DROP,
+// with a leading space, skipping modifiers that PostgreSQL leaves out of
+// the command tag: CREATE OR REPLACE UNIQUE INDEX is tagged CREATE INDEX.
+func objectType(tokens []token) string {
+	i := 0
+	for i < len(tokens) && tokens[i].isAny("OR", "REPLACE", "UNIQUE", "TEMP", "TEMPORARY", "UNLOGGED", "GLOBAL", "LOCAL", "RECURSIVE", "TRUSTED", "PROCEDURAL", "CONSTRAINT") {
+		i++
+	}
+	if i >= len(tokens) || tokens[i].kind != tokenWord {
+		return ""
+	}
+
+	n := 1
+	if words, ok := objectTypeWords[strings.ToUpper(tokens[i].text)]; ok {
+		n = words
+	}
+
+	var words []string
+	for _, t := range tokens[i:] {
+		if len(words) == n || t.kind != tokenWord {
+			break
+		}
+		words = append(words, strings.ToUpper(t.text))
+	}
+	return " " + strings.Join(words, " ")
+}
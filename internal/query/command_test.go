@@ -0,0 +1,111 @@
+package query
+
+import (
+	"testing"
+)
+
+func parseOne(t *testing.T, sql string) *ParsedStatement {
+	t.Helper()
+	statements, err := ParseStatements(sql)
+	if err != nil || len(statements) != 1 {
+		t.Fatalf("ParseStatements(%q) = %v, %v", sql, statements, err)
+	}
+	return statements[0]
+}
+
+func TestReturnsRows(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT 1", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"VALUES (1)", true},
+		{"SHOW server_version", true},
+		{"EXPLAIN DELETE FROM users WHERE id = 1", true},
+		{"COPY users TO STDOUT", true},
+		{"CALL refresh_stats()", true},
+		{"INSERT INTO users (name) VALUES ('a')", false},
+		{"INSERT INTO users (name) VALUES ('a') RETURNING id", true},
+		{"UPDATE users SET name = 'a' WHERE id = 1", false},
+		{"UPDATE users SET name = 'a' WHERE id = 1 returning *", true},
+		{"DELETE FROM users WHERE id = 1", false},
+		{"WITH d AS (DELETE FROM users WHERE id = 1 RETURNING id) DELETE FROM orders WHERE user_id IN (SELECT id FROM d)", false},
+		{"WITH d AS (DELETE FROM users WHERE id = 1 RETURNING id) SELECT * FROM d", true},
+		{"UPDATE users SET name = 'returning' WHERE id = 1", false},
+		{"CREATE TABLE t (id int)", false},
+		{"TRUNCATE users", false},
+		{"VACUUM users", false},
+		{"DO $$ BEGIN PERFORM 1; END $$", false},
+	}
+
+	for _, tt := range tests {
+		if got := returnsRows(parseOne(t, tt.sql)); got != tt.want {
+			t.Errorf("returnsRows(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestCommandTag(t *testing.T) {
+	tests := []struct {
+		sql  string
+		n    int64
+		want string
+	}{
+		{"SELECT * FROM users", 2, "SELECT 2"},
+		{"VALUES (1), (2)", 2, "SELECT 2"},
+		{"INSERT INTO users (name) VALUES ('a')", 1, "INSERT 0 1"},
+		{"UPDATE users SET name = 'a' WHERE id > 1", 3, "UPDATE 3"},
+		{"DELETE FROM users WHERE id = 1", 0, "DELETE 0"},
+		{"WITH d AS (SELECT 1) UPDATE users SET name = 'a' WHERE id = 1", 1, "UPDATE 1"},
+		{"COPY users TO STDOUT", 5, "COPY 5"},
+		{"CREATE TABLE t (id int)", 0, "CREATE TABLE"},
+		{"CREATE TEMP TABLE t (id int)", 0, "CREATE TABLE"},
+		{"create unique index on t (id)", 0, "CREATE INDEX"},
+		{"CREATE OR REPLACE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE sql", 0, "CREATE FUNCTION"},
+		{"CREATE MATERIALIZED VIEW v AS SELECT 1", 0, "CREATE MATERIALIZED VIEW"},
+		{"DROP TEXT SEARCH CONFIGURATION c", 0, "DROP TEXT SEARCH CONFIGURATION"},
+		{"ALTER TABLE t ADD COLUMN name text", 0, "ALTER TABLE"},
+		{"TRUNCATE users", 0, "TRUNCATE TABLE"},
+		{"GRANT SELECT ON users TO bob", 0, "GRANT"},
+		{"SHOW server_version", 1, "SHOW"},
+	}
+
+	for _, tt := range tests {
+		if got := commandTag(parseOne(t, tt.sql), tt.n); got != tt.want {
+			t.Errorf("commandTag(%q, %d) = %q, want %q", tt.sql, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestExecutor_Execute_RowsAffected(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	tests := []struct {
+		sql      string
+		affected int64
+		tag      string
+	}{
+		{"CREATE TEMP TABLE rows_affected_check (id int)", 0, "CREATE TABLE"},
+		{"INSERT INTO rows_affected_check SELECT generate_series(1, 5)", 5, "INSERT 0 5"},
+		{"UPDATE rows_affected_check SET id = id + 10 WHERE id > 2", 3, "UPDATE 3"},
+		{"DELETE FROM rows_affected_check WHERE id > 10 RETURNING id", 3, "DELETE 3"},
+		{"SELECT * FROM rows_affected_check", 2, "SELECT 2"},
+	}
+
+	// Temporary tables live on one connection
+	db.SetMaxOpenConns(1)
+	for _, tt := range tests {
+		result, err := executor.Execute(tt.sql)
+		if err != nil {
+			t.Fatalf("Execute(%q) unexpected error = %v", tt.sql, err)
+		}
+		if result.RowsAffected != tt.affected || result.CommandTag != tt.tag {
+			t.Errorf("Execute(%q) = %d, %q, want %d, %q", tt.sql, result.RowsAffected, result.CommandTag, tt.affected, tt.tag)
+		}
+	}
+}
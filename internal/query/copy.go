@@ -0,0 +1,58 @@
+package query
+
+// copyAsQuery returns the query equivalent to a COPY ... TO STDOUT
+// statement, so its rows are returned like those of a SELECT. lib/pq cannot
+// read COPY output, and result encoding is up to the API anyway, so COPY
+// options such as FORMAT are ignored. Other SQL is returned unchanged.
+func copyAsQuery(sql string) string {
+	statements, err := ParseStatements(sql)
+	if err != nil || len(statements) != 1 || statements[0].Class != ClassCopy {
+		return sql
+	}
+	tokens := statements[0].tokens
+
+	// COPY ( query ) TO STDOUT
+	if len(tokens) > 1 && tokens[1].isPunct("(") {
+		if end := closingParen(tokens, 1); end > 2 {
+			return sql[tokens[2].pos:tokens[end-1].end]
+		}
+		return sql
+	}
+
+	// COPY table [ ( column, ... ) ] TO STDOUT
+	i := 1
+	for i < len(tokens) && !tokens[i].isPunct("(") && !tokens[i].is("TO") {
+		i++
+	}
+	if i == 1 || i >= len(tokens) {
+		return sql
+	}
+	table := sql[tokens[1].pos:tokens[i-1].end]
+
+	columns := "*"
+	if tokens[i].isPunct("(") {
+		end := closingParen(tokens, i)
+		if end <= i+1 {
+			return sql
+		}
+		columns = sql[tokens[i+1].pos:tokens[end-1].end]
+	}
+	return "SELECT " + columns + " FROM " + table
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, or -1 if it is unbalanced
+func closingParen(tokens []token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].isPunct("(") {
+			depth++
+		} else if tokens[i].isPunct(")") {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,25 @@
+package query
+
+import "testing"
+
+func TestCopyAsQuery(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"COPY users TO STDOUT", "SELECT * FROM users"},
+		{"copy public.users to stdout with (format csv, header)", "SELECT * FROM public.users"},
+		{`COPY "Users" (id, "Name") TO STDOUT`, `SELECT id, "Name" FROM "Users"`},
+		{"COPY (SELECT id FROM users WHERE active) TO STDOUT CSV", "SELECT id FROM users WHERE active"},
+		{"COPY ((SELECT 1) UNION (SELECT 2)) TO STDOUT;", "(SELECT 1) UNION (SELECT 2)"},
+		{"SELECT 1", "SELECT 1"},
+		{"COPY users FROM STDIN", "COPY users FROM STDIN"},
+		{"COPY users TO STDOUT; SELECT 1", "COPY users TO STDOUT; SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		if got := copyAsQuery(tt.sql); got != tt.expected {
+			t.Errorf("copyAsQuery(%q) = %q, expected %q", tt.sql, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// ExecutePage runs a query through a server-side cursor and returns its
+// first page of at most limit rows. When more rows may follow, the result's
+// NextCursor identifies the cursor for FetchPage. The cursor holds a pool
+// connection until it is read to the end or sits idle for TxIdleTimeout.
+func (e *Executor) ExecutePage(sql string, params []interface{}, opts Options, limit int) (*ExecutionResult, error) {
+	if err := e.limits.checkPageSize(limit); err != nil {
+		return nil, err
+	}
+	return e.sessions.openCursor(sql, params, opts, limit)
+}
+
+// FetchPage returns the next page of an open cursor. A zero limit reuses
+// the page size the cursor was opened with. Only a request with the Owner,
+// read-only mode and Role the cursor was opened with may fetch from it.
+func (e *Executor) FetchPage(cursor string, opts Options, limit int) (*ExecutionResult, error) {
+	if limit != 0 {
+		if err := e.limits.checkPageSize(limit); err != nil {
+			return nil, err
+		}
+	}
+	return e.sessions.fetch(cursor, opts, limit)
+}
+
+func (l Limits) checkPageSize(limit int) error {
+	if limit <= 0 || limit > l.MaxResultRows {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid page size",
+			fmt.Sprintf("'limit' must be between 1 and %d", l.MaxResultRows),
+		)
+	}
+	return nil
+}
+
+func (s *txSessions) openCursor(sql string, params []interface{}, opts Options, limit int) (*ExecutionResult, error) {
+	session, err := s.open(true, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer session.mu.Unlock()
+
+	session.pageSize = limit
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.limits.QueryTimeout)
+	defer cancel()
+
+	declare := fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursorName(session.id), trimStatement(copyAsQuery(sql)))
+	if _, err := session.tx.ExecContext(ctx, declare, params...); err != nil {
+		s.close(session, false)
+		return nil, s.limits.translateError(err)
+	}
+
+	return s.fetchPage(session, opts, limit)
+}
+
+func (s *txSessions) fetch(id string, opts Options, limit int) (*ExecutionResult, error) {
+	session, err := s.acquire(id, true, opts.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer session.mu.Unlock()
+
+	// The rows were read under the policy the cursor was opened with,
+	// which must be no looser than the one fetching them
+	if (opts.ReadOnly && !session.readOnly) || opts.Role != session.role {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeStatementNotAllowed,
+			"Statement not allowed",
+			"The cursor was opened under a different safety policy",
+		)
+	}
+
+	if limit == 0 {
+		limit = session.pageSize
+	}
+	return s.fetchPage(session, opts, limit)
+}
+
+// fetchPage reads the next page from a locked cursor session, closing the
+// cursor once it is exhausted
+func (s *txSessions) fetchPage(session *txSession, opts Options, limit int) (*ExecutionResult, error) {
+	session.timer.Stop()
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", limit, cursorName(session.id))
+	result, err := runQuery(session.tx, s.limits, fetch, nil, opts)
+	if err != nil {
+		s.close(session, false)
+		log.Printf("[WARN] Cursor %s closed after failed fetch", session.id)
+		return nil, err
+	}
+	// The FETCH is how pages are read, not the client's statement
+	result.RowsAffected = 0
+	result.CommandTag = ""
+
+	if result.RowCount < limit {
+		if err := s.close(session, true); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	result.NextCursor = session.id
+	session.lastUsed = time.Now()
+	session.timer.Reset(s.idleTimeout)
+	return result, nil
+}
+
+// cursorName derives a valid SQL identifier from a session ID
+func cursorName(id string) string {
+	return "vibe_cursor_" + id
+}
+
+// trimStatement removes trailing semicolons so the statement can be
+// embedded in DECLARE ... CURSOR FOR
+func trimStatement(sql string) string {
+	return strings.TrimRight(strings.TrimSpace(sql), "; \t\r\n")
+}
+
+func newCursorNotFoundError(id string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		postgres.ErrorCodeCursorNotFound,
+		"Cursor not found",
+		fmt.Sprintf("Cursor '%s' does not exist, has been read to the end, or was closed after being idle", id),
+	)
+}
@@ -0,0 +1,169 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func expectCursorNotFound(t *testing.T, err error) {
+	t.Helper()
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T (%v)", err, err)
+	}
+	if vibeErr.Code != postgres.ErrorCodeCursorNotFound {
+		t.Errorf("Expected CURSOR_NOT_FOUND, got %s", vibeErr.Code)
+	}
+}
+
+func TestExecutor_FetchPage_UnknownCursor(t *testing.T) {
+	executor := NewExecutor(nil)
+
+	_, err := executor.FetchPage("missing", Options{}, 0)
+	expectCursorNotFound(t, err)
+}
+
+func TestExecutor_SessionKindsAreSeparate(t *testing.T) {
+	executor := NewExecutor(nil)
+	executor.sessions.sessions["tx"] = &txSession{id: "tx"}
+	executor.sessions.sessions["cur"] = &txSession{id: "cur", cursor: true}
+
+	_, err := executor.FetchPage("tx", Options{}, 0)
+	expectCursorNotFound(t, err)
+
+	_, err = executor.ExecuteInSession("cur", "SELECT 1", nil, Options{})
+	expectTransactionNotFound(t, err)
+	expectTransactionNotFound(t, executor.CommitSession("cur", Options{}))
+}
+
+func TestExecutor_FetchPage_Policy(t *testing.T) {
+	executor := NewExecutor(nil)
+	executor.sessions.sessions["cur"] = &txSession{id: "cur", cursor: true, owner: "key1", role: "analyst"}
+
+	for _, opts := range []Options{
+		{Owner: "key1", ReadOnly: true, Role: "analyst"},
+		{Owner: "key1"},
+		{Owner: "key1", Role: "admin"},
+	} {
+		_, err := executor.FetchPage("cur", opts, 0)
+		if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeStatementNotAllowed {
+			t.Errorf("%+v: expected STATEMENT_NOT_ALLOWED, got %v", opts, err)
+		}
+	}
+}
+
+func TestExecutor_PageSizeLimits(t *testing.T) {
+	executor := NewExecutorWithLimits(nil, Limits{MaxResultRows: 100})
+
+	for _, limit := range []int{-1, 0, 101} {
+		_, err := executor.ExecutePage("SELECT 1", nil, Options{}, limit)
+		vibeErr, ok := err.(*postgres.VibeError)
+		if !ok {
+			t.Fatalf("limit %d: expected VibeError, got %T (%v)", limit, err, err)
+		}
+		if vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+			t.Errorf("limit %d: expected INVALID_SQL, got %s", limit, vibeErr.Code)
+		}
+	}
+
+	_, err := executor.FetchPage("missing", Options{}, 101)
+	if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+		t.Errorf("Expected INVALID_SQL for oversized fetch, got %v", err)
+	}
+}
+
+func TestTrimStatement(t *testing.T) {
+	testCases := map[string]string{
+		"SELECT 1":           "SELECT 1",
+		"  SELECT 1;  ":      "SELECT 1",
+		"SELECT 1;;\n":       "SELECT 1",
+		"SELECT ';' AS semi": "SELECT ';' AS semi",
+	}
+	for input, expected := range testCases {
+		if got := trimStatement(input); got != expected {
+			t.Errorf("trimStatement(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestExecutor_Pagination(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	page, err := executor.ExecutePage("SELECT n FROM generate_series(1, $1::int) AS n ORDER BY n;", []interface{}{int64(25)}, Options{}, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var seen []int64
+	pages := 0
+	for {
+		pages++
+		for _, row := range page.Values {
+			seen = append(seen, row[0].(int64))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page, err = executor.FetchPage(page.NextCursor, Options{}, 0)
+		if err != nil {
+			t.Fatalf("Expected no error fetching page %d, got: %v", pages+1, err)
+		}
+	}
+
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+	if len(seen) != 25 || seen[0] != 1 || seen[24] != 25 {
+		t.Errorf("Expected rows 1..25 in order, got %v", seen)
+	}
+	if len(executor.sessions.sessions) != 0 {
+		t.Errorf("Expected exhausted cursor to be closed, %d sessions open", len(executor.sessions.sessions))
+	}
+}
+
+func TestExecutor_Pagination_InvalidQuery(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	if _, err := executor.ExecutePage("SELECT * FROM missing_table_for_cursor", nil, Options{}, 10); err == nil {
+		t.Fatal("Expected error for invalid query")
+	}
+	if len(executor.sessions.sessions) != 0 {
+		t.Errorf("Expected failed cursor to be closed, %d sessions open", len(executor.sessions.sessions))
+	}
+}
+
+func TestExecutor_Truncate(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutorWithLimits(db, Limits{MaxResultRows: 5})
+
+	result, err := executor.ExecuteWithOptions("SELECT generate_series(1, 20) AS n", nil, Options{Truncate: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.RowCount != 5 || !result.Truncated {
+		t.Errorf("Expected 5 truncated rows, got %d (truncated=%v)", result.RowCount, result.Truncated)
+	}
+
+	result, err = executor.ExecuteWithOptions("SELECT generate_series(1, 5) AS n", nil, Options{Truncate: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Truncated {
+		t.Error("Expected result at exactly the limit not to be truncated")
+	}
+
+	if _, err := executor.ExecuteWithOptions("SELECT generate_series(1, 20) AS n", nil, Options{}); err == nil {
+		t.Error("Expected RESULT_TOO_LARGE without truncate")
+	}
+}
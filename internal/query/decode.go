@@ -0,0 +1,282 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// decodeValue converts a value scanned from lib/pq into its JSON
+// representation, using the column's PostgreSQL type name
+// (sql.ColumnType.DatabaseTypeName, e.g. "JSONB", "_INT4", "NUMERIC").
+func decodeValue(val interface{}, typeName string, opts Options) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return decodeBytes(v, typeName, opts)
+	case time.Time:
+		return formatTime(v, typeName), nil
+	case float64:
+		return decodeFloat(v), nil
+	default:
+		return v, nil
+	}
+}
+
+func decodeBytes(b []byte, typeName string, opts Options) (interface{}, error) {
+	if strings.HasPrefix(typeName, "_") {
+		return decodeArray(string(b), typeName[1:], opts)
+	}
+
+	switch typeName {
+	case "JSON", "JSONB":
+		if !json.Valid(b) {
+			return nil, fmt.Errorf("invalid %s value returned by database", strings.ToLower(typeName))
+		}
+		return json.RawMessage(b), nil
+	case "BYTEA":
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "NUMERIC":
+		return decodeNumeric(string(b), opts), nil
+	default:
+		return string(b), nil
+	}
+}
+
+// decodeNumeric returns numeric values as exact JSON numbers, or as strings
+// when requested. NaN and Infinity have no JSON number form and are always
+// returned as strings.
+func decodeNumeric(s string, opts Options) interface{} {
+	if opts.NumericAsString {
+		return s
+	}
+	switch s {
+	case "NaN", "Infinity", "-Infinity":
+		return s
+	}
+	return json.Number(s)
+}
+
+// decodeFloat keeps NaN and ±Infinity representable in JSON
+func decodeFloat(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	default:
+		return f
+	}
+}
+
+func formatTime(t time.Time, typeName string) string {
+	switch typeName {
+	case "DATE":
+		return t.Format("2006-01-02")
+	case "TIME":
+		return t.Format("15:04:05.999999")
+	case "TIMETZ":
+		return t.Format("15:04:05.999999Z07:00")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// decodeArray parses a PostgreSQL array literal such as {1,2,NULL} or
+// {{"a","b"},{"c","d"}} into nested JSON arrays, decoding each element
+// according to the array's element type.
+func decodeArray(s string, elemType string, opts Options) (interface{}, error) {
+	// Arrays with non-default bounds are prefixed with their dimensions,
+	// e.g. [0:1]={1,2}
+	if strings.HasPrefix(s, "[") {
+		if idx := strings.Index(s, "="); idx >= 0 {
+			s = s[idx+1:]
+		}
+	}
+
+	p := &arrayParser{input: s, elemType: elemType, opts: opts}
+	value, err := p.parseArray()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("unexpected trailing data in array literal %q", s)
+	}
+	return value, nil
+}
+
+type arrayParser struct {
+	input    string
+	pos      int
+	elemType string
+	opts     Options
+}
+
+func (p *arrayParser) parseArray() ([]interface{}, error) {
+	if p.pos >= len(p.input) || p.input[p.pos] != '{' {
+		return nil, fmt.Errorf("malformed array literal %q", p.input)
+	}
+	p.pos++
+
+	elems := []interface{}{}
+	if p.pos < len(p.input) && p.input[p.pos] == '}' {
+		p.pos++
+		return elems, nil
+	}
+
+	for {
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unterminated array literal %q", p.input)
+		}
+
+		var elem interface{}
+		var err error
+		switch p.input[p.pos] {
+		case '{':
+			elem, err = p.parseArray()
+		case '"':
+			elem, err = p.parseQuoted()
+		default:
+			elem, err = p.parseUnquoted()
+		}
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unterminated array literal %q", p.input)
+		}
+		switch p.input[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return elems, nil
+		default:
+			return nil, fmt.Errorf("malformed array literal %q", p.input)
+		}
+	}
+}
+
+func (p *arrayParser) parseQuoted() (interface{}, error) {
+	p.pos++ // opening quote
+
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch c {
+		case '\\':
+			p.pos++
+			if p.pos < len(p.input) {
+				sb.WriteByte(p.input[p.pos])
+				p.pos++
+			}
+		case '"':
+			p.pos++
+			return p.decodeElement(sb.String())
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, fmt.Errorf("unterminated quoted element in array literal %q", p.input)
+}
+
+func (p *arrayParser) parseUnquoted() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != ',' && p.input[p.pos] != '}' {
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.input[start:p.pos])
+	if strings.EqualFold(raw, "NULL") {
+		return nil, nil
+	}
+	return p.decodeElement(raw)
+}
+
+func (p *arrayParser) decodeElement(s string) (interface{}, error) {
+	switch p.elemType {
+	case "INT2", "INT4", "INT8":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer array element %q", s)
+		}
+		return n, nil
+	case "FLOAT4", "FLOAT8":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float array element %q", s)
+		}
+		return decodeFloat(f), nil
+	case "NUMERIC":
+		return decodeNumeric(s, p.opts), nil
+	case "BOOL":
+		return s == "t", nil
+	case "JSON", "JSONB":
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("invalid json array element %q", s)
+		}
+		return json.RawMessage(s), nil
+	case "BYTEA":
+		return decodeByteaElement(s), nil
+	case "TIMESTAMPTZ", "TIMESTAMP", "DATE", "TIMETZ":
+		return decodeTimeElement(s, p.elemType), nil
+	default:
+		return s, nil
+	}
+}
+
+// decodeByteaElement returns a bytea array element, in PostgreSQL's hex
+// output format, as base64 like scalar bytea values. Other formats are
+// returned as-is.
+func decodeByteaElement(s string) interface{} {
+	if !strings.HasPrefix(s, `\x`) {
+		return s
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return s
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// decodeTimeElement formats a date or time array element as scalar values
+// of its type are formatted. Values the driver cannot parse either, such
+// as infinity or BC dates, are returned as PostgreSQL printed them.
+func decodeTimeElement(s string, elemType string) interface{} {
+	var t time.Time
+	var err error
+	if elemType == "TIMETZ" {
+		t, err = parseTimeTZ(s)
+	} else {
+		t, err = pq.ParseTimestamp(nil, s)
+	}
+	if err != nil {
+		return s
+	}
+	return formatTime(t, elemType)
+}
+
+// parseTimeTZ parses a time with time zone, whose offset PostgreSQL prints
+// as hours and, when not whole, minutes and seconds
+func parseTimeTZ(s string) (time.Time, error) {
+	var err error
+	for _, layout := range []string{"15:04:05-07", "15:04:05-07:00", "15:04:05-07:00:00"} {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
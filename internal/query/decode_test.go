@@ -0,0 +1,104 @@
+package query
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func marshalValue(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal %v: %v", v, err)
+	}
+	return string(b)
+}
+
+func TestDecodeValue_Scalars(t *testing.T) {
+	ts := time.Date(2026, 2, 7, 13, 45, 30, 500000000, time.UTC)
+
+	testCases := []struct {
+		name     string
+		val      interface{}
+		typeName string
+		opts     Options
+		expected string
+	}{
+		{"null", nil, "TEXT", Options{}, `null`},
+		{"int", int64(42), "INT4", Options{}, `42`},
+		{"bool", true, "BOOL", Options{}, `true`},
+		{"text", "hello", "TEXT", Options{}, `"hello"`},
+		{"jsonb object", []byte(`{"name": "Alice"}`), "JSONB", Options{}, `{"name":"Alice"}`},
+		{"json array", []byte(`[1, 2, 3]`), "JSON", Options{}, `[1,2,3]`},
+		{"numeric", []byte("12345678901234567890.123456789"), "NUMERIC", Options{}, `12345678901234567890.123456789`},
+		{"numeric as string", []byte("3.14"), "NUMERIC", Options{NumericAsString: true}, `"3.14"`},
+		{"numeric NaN", []byte("NaN"), "NUMERIC", Options{}, `"NaN"`},
+		{"bytea", []byte{0xde, 0xad, 0xbe, 0xef}, "BYTEA", Options{}, `"3q2+7w=="`},
+		{"uuid", []byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), "UUID", Options{}, `"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`},
+		{"timestamptz", ts, "TIMESTAMPTZ", Options{}, `"2026-02-07T13:45:30.5Z"`},
+		{"date", ts, "DATE", Options{}, `"2026-02-07"`},
+		{"time", ts, "TIME", Options{}, `"13:45:30.5"`},
+		{"float infinity", math.Inf(1), "FLOAT8", Options{}, `"Infinity"`},
+		{"float", 3.5, "FLOAT8", Options{}, `3.5`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := decodeValue(tc.val, tc.typeName, tc.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got := marshalValue(t, decoded); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeValue_Arrays(t *testing.T) {
+	testCases := []struct {
+		name     string
+		literal  string
+		typeName string
+		expected string
+	}{
+		{"int array", `{1,2,3}`, "_INT4", `[1,2,3]`},
+		{"empty array", `{}`, "_INT4", `[]`},
+		{"array with null", `{1,NULL,3}`, "_INT8", `[1,null,3]`},
+		{"multi-dimensional", `{{1,2},{3,4}}`, "_INT4", `[[1,2],[3,4]]`},
+		{"text array", `{alpha,"hello world","quote\"d",NULL,"NULL"}`, "_TEXT", `["alpha","hello world","quote\"d",null,"NULL"]`},
+		{"bool array", `{t,f}`, "_BOOL", `[true,false]`},
+		{"numeric array", `{1.50,NaN}`, "_NUMERIC", `[1.50,"NaN"]`},
+		{"jsonb array", `{"{\"a\": 1}","[2]"}`, "_JSONB", `[{"a":1},[2]]`},
+		{"custom bounds", `[0:1]={7,8}`, "_INT4", `[7,8]`},
+		{"bytea array", `{"\\x0102ff",NULL}`, "_BYTEA", `["AQL/",null]`},
+		{"timestamptz array", `{"2024-03-01 12:30:45.5+00","2024-03-01 14:00:00+05:30"}`, "_TIMESTAMPTZ", `["2024-03-01T12:30:45.5Z","2024-03-01T14:00:00+05:30"]`},
+		{"timestamp array", `{"2024-03-01 12:30:45",infinity}`, "_TIMESTAMP", `["2024-03-01T12:30:45Z","infinity"]`},
+		{"date array", `{2024-03-01,NULL}`, "_DATE", `["2024-03-01",null]`},
+		{"timetz array", `{12:30:45+02,"08:00:00-05:30"}`, "_TIMETZ", `["12:30:45+02:00","08:00:00-05:30"]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := decodeValue([]byte(tc.literal), tc.typeName, Options{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got := marshalValue(t, decoded); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeValue_MalformedArray(t *testing.T) {
+	malformed := []string{`{1,2`, `1,2}`, `{"abc}`, `{1}x`}
+
+	for _, literal := range malformed {
+		if _, err := decodeValue([]byte(literal), "_INT4", Options{}); err == nil {
+			t.Errorf("Expected error for malformed array %q", literal)
+		}
+	}
+}
@@ -5,94 +5,290 @@ import (
 	"database/sql"
 	"time"
 
-	"github.com/vibesql/vibe/internal/postgres"
-)
+	"github.com/lib/pq"
 
-var (
-	QueryTimeout = 5 * time.Second
+	"github.com/vibesql/vibe/internal/postgres"
 )
 
 type ExecutionResult struct {
-	Rows          []map[string]interface{}
+	Columns []Column
+	// Rows holds each row keyed by column name. Duplicate column names
+	// collapse to the last value; use Values when order matters.
+	Rows []map[string]interface{}
+	// Values holds each row as positional values ordered like Columns
+	Values        [][]interface{}
 	RowCount      int
 	ExecutionTime time.Duration
+	// RowsAffected is the row count of the command tag: rows changed by
+	// INSERT, UPDATE or DELETE, rows returned by a query
+	RowsAffected int64
+	// CommandTag mirrors PostgreSQL's command tag, e.g. "UPDATE 3". It is
+	// empty for truncated and paginated results, and for multi-statement
+	// queries that return rows.
+	CommandTag string
+	// Truncated is set when Options.Truncate cut the result at the row limit
+	Truncated bool
+	// NextCursor identifies the cursor holding the next page of a paginated
+	// query, empty on the last page
+	NextCursor string
+}
+
+// Options controls how a single query is executed and how its result
+// values are encoded
+type Options struct {
+	// NumericAsString returns numeric columns as JSON strings instead of
+	// numbers, for clients that cannot hold arbitrary-precision numbers
+	NumericAsString bool
+	// Truncate returns the first rows up to the row limit, marking the
+	// result as truncated, instead of failing with RESULT_TOO_LARGE
+	Truncate bool
+	// ReadOnly runs the query in a READ ONLY transaction, so PostgreSQL
+	// rejects any write whatever the statement looks like
+	ReadOnly bool
+	// Role runs the query in a transaction as this PostgreSQL role, with
+	// SET LOCAL ROLE, so the role's privileges and row-level security
+	// policies apply. The connecting role must be a member of it.
+	Role string
+	// Owner identifies the client, such as its API key, that opens an
+	// interactive transaction or cursor. Only requests with the same Owner
+	// may use the session afterwards.
+	Owner string
 }
 
 type Executor struct {
-	db *sql.DB
+	db       *sql.DB
+	limits   Limits
+	sessions *txSessions
 }
 
+// NewExecutor creates an executor with the default limits
 func NewExecutor(db *sql.DB) *Executor {
-	return &Executor{db: db}
+	return NewExecutorWithLimits(db, DefaultLimits())
+}
+
+// NewExecutorWithLimits creates an executor enforcing the given limits
+func NewExecutorWithLimits(db *sql.DB, limits Limits) *Executor {
+	limits = limits.WithDefaults()
+	return &Executor{
+		db:       db,
+		limits:   limits,
+		sessions: newTxSessions(db, limits),
+	}
+}
+
+// Limits returns the limits enforced by the executor
+func (e *Executor) Limits() Limits {
+	return e.limits
 }
 
-func (e *Executor) Execute(sql string) (*ExecutionResult, error) {
+// Execute runs a SQL statement, binding params to its $1..$n placeholders
+func (e *Executor) Execute(sql string, params ...interface{}) (*ExecutionResult, error) {
+	return e.ExecuteWithOptions(sql, params, Options{})
+}
+
+// ExecuteWithOptions runs a SQL statement with per-query options
+func (e *Executor) ExecuteWithOptions(sql string, params []interface{}, opts Options) (*ExecutionResult, error) {
+	return e.run(opts, func(q queryer) (*ExecutionResult, error) {
+		return runQuery(q, e.limits, sql, params, opts)
+	})
+}
+
+// run calls fn with the connection pool, or with a transaction that is
+// committed after fn succeeds when opts.ReadOnly or opts.Role is set
+func (e *Executor) run(opts Options, fn func(q queryer) (*ExecutionResult, error)) (*ExecutionResult, error) {
+	if !opts.ReadOnly && opts.Role == "" {
+		return fn(e.db)
+	}
+
+	tx, err := beginTx(e.db, opts)
+	if err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+
+	result, err := fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+	return result, nil
+}
+
+// beginTx starts a transaction, made READ ONLY with SET TRANSACTION when
+// opts.ReadOnly is set, and run as opts.Role when set
+func beginTx(db *sql.DB, opts Options) (*sql.Tx, error) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := setupTx(context.Background(), tx, opts); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// setupTx applies opts.ReadOnly and opts.Role to a new transaction
+func setupTx(ctx context.Context, tx *sql.Tx, opts Options) error {
+	if opts.ReadOnly {
+		if _, err := tx.ExecContext(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+			return err
+		}
+	}
+	return setRole(ctx, tx, opts.Role)
+}
+
+// setRole switches a transaction to role until it ends. An empty role
+// keeps the connecting role.
+func setRole(ctx context.Context, q queryer, role string) error {
+	if role == "" {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, "SET LOCAL ROLE "+pq.QuoteIdentifier(role))
+	return err
+}
+
+// queryer is satisfied by *sql.DB, *sql.Tx and *sql.Conn
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// runQuery executes a single statement on q within the given limits.
+// Statements that cannot return rows are run with Exec, so the result
+// reports how many rows they affected.
+func runQuery(q queryer, limits Limits, sql string, params []interface{}, opts Options) (*ExecutionResult, error) {
 	startTime := time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), QueryTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), limits.QueryTimeout)
 	defer cancel()
 
-	rows, err := e.db.QueryContext(ctx, sql)
+	// Statements that fail to parse are left for PostgreSQL to report
+	statements, _ := ParseStatements(sql)
+	if len(statements) > 0 && !anyReturnsRows(statements) {
+		return runCommand(ctx, q, limits, sql, params, statements[len(statements)-1], startTime)
+	}
+
+	rows, err := q.QueryContext(ctx, copyAsQuery(sql), params...)
 	if err != nil {
-		vibeErr := postgres.TranslateError(err)
+		vibeErr := limits.translateError(err)
 		return nil, vibeErr
 	}
 	defer rows.Close()
 
-	result, err := parseRows(rows)
+	result, err := parseRows(rows, limits, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	executionTime := time.Since(startTime)
+	if len(statements) == 1 && !result.Truncated {
+		result.RowsAffected = int64(result.RowCount)
+		result.CommandTag = commandTag(statements[0], result.RowsAffected)
+	}
+	result.ExecutionTime = time.Since(startTime)
+	return result, nil
+}
+
+// runCommand executes statements that return no rows. With several
+// statements, the row count and command tag are those of the last one.
+// The driver does not parse MERGE's row count, which is reported as 0;
+// MERGE ... RETURNING counts the rows it returns instead.
+func runCommand(ctx context.Context, q queryer, limits Limits, sql string, params []interface{}, last *ParsedStatement, startTime time.Time) (*ExecutionResult, error) {
+	res, err := q.ExecContext(ctx, sql, params...)
+	if err != nil {
+		return nil, limits.translateError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, limits.translateError(err)
+	}
 
 	return &ExecutionResult{
-		Rows:          result,
-		RowCount:      len(result),
-		ExecutionTime: executionTime,
+		RowsAffected:  affected,
+		CommandTag:    commandTag(last, affected),
+		ExecutionTime: time.Since(startTime),
 	}, nil
 }
 
-func parseRows(rows *sql.Rows) ([]map[string]interface{}, error) {
-	columns, err := rows.Columns()
+// anyReturnsRows reports whether any of the statements may return rows
+func anyReturnsRows(statements []*ParsedStatement) bool {
+	for _, stmt := range statements {
+		if returnsRows(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRows(rows *sql.Rows, limits Limits, opts Options) (*ExecutionResult, error) {
+	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
-		return nil, postgres.TranslateError(err)
+		return nil, limits.translateError(err)
 	}
+	columns := describeColumns(columnTypes)
 
 	var results []map[string]interface{}
+	var positional [][]interface{}
+	truncated := false
 
 	for rows.Next() {
-		if err := CheckRowLimit(len(results)); err != nil {
+		if opts.Truncate && len(results) >= limits.MaxResultRows {
+			truncated = true
+			break
+		}
+		if err := limits.CheckRowLimit(len(results)); err != nil {
 			return nil, err
 		}
 
 		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+		if err := scanRow(rows, columnTypes, values, opts); err != nil {
+			return nil, limits.translateError(err)
 		}
 
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, postgres.TranslateError(err)
-		}
-
-		row := make(map[string]interface{})
+		row := make(map[string]interface{}, len(columns))
 		for i, col := range columns {
-			val := values[i]
-			
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
+			row[col.Name] = values[i]
 		}
 
 		results = append(results, row)
+		positional = append(positional, values)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, postgres.TranslateError(err)
+		return nil, limits.translateError(err)
+	}
+
+	return &ExecutionResult{
+		Columns:   columns,
+		Rows:      results,
+		Values:    positional,
+		RowCount:  len(results),
+		Truncated: truncated,
+	}, nil
+}
+
+// scanRow scans the current row into values, which must have one element
+// per column, and decodes each value for JSON encoding
+func scanRow(rows *sql.Rows, columnTypes []*sql.ColumnType, values []interface{}, opts Options) error {
+	valuePtrs := make([]interface{}, len(values))
+	for i := range values {
+		valuePtrs[i] = &values[i]
 	}
 
-	return results, nil
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return err
+	}
+
+	for i, col := range columnTypes {
+		val, err := decodeValue(values[i], col.DatabaseTypeName(), opts)
+		if err != nil {
+			return err
+		}
+		values[i] = val
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package query
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -238,6 +239,10 @@ func TestExecutor_Execute_VariousDataTypes(t *testing.T) {
 	if row["jsonb_col"] == nil {
 		t.Error("Expected jsonb_col to have value, got nil")
 	}
+
+	if _, ok := row["jsonb_col"].(json.RawMessage); !ok {
+		t.Errorf("Expected jsonb_col to be decoded as JSON, got %T", row["jsonb_col"])
+	}
 }
 
 func TestExecutor_Execute_TimeoutPrecision(t *testing.T) {
@@ -329,8 +334,142 @@ func TestCheckRowLimit(t *testing.T) {
 	}
 }
 
+func TestLimits_CheckRowLimit(t *testing.T) {
+	limits := Limits{MaxResultRows: 50000}.WithDefaults()
+
+	if err := limits.CheckRowLimit(49999); err != nil {
+		t.Errorf("Expected no error below the limit, got: %v", err)
+	}
+
+	err := limits.CheckRowLimit(50000)
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T", err)
+	}
+	if vibeErr.Detail != "Query returned more than the maximum allowed 50000 rows" {
+		t.Errorf("Expected detail to report the configured limit, got %q", vibeErr.Detail)
+	}
+}
+
+func TestLimits_WithDefaults(t *testing.T) {
+	limits := Limits{QueryTimeout: time.Minute}.WithDefaults()
+
+	expected := Limits{QueryTimeout: time.Minute, MaxResultRows: MaxResultRows, MaxQuerySize: MaxQuerySize, MaxTxSessions: MaxTxSessions, ImportTimeout: ImportTimeout}
+	if limits != expected {
+		t.Errorf("Expected %+v, got %+v", expected, limits)
+	}
+	if NewExecutorWithLimits(nil, Limits{}).Limits() != DefaultLimits() {
+		t.Error("Expected zero limits to fall back to the defaults")
+	}
+}
+
+func TestExecutor_QueryTimeoutFromLimits(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutorWithLimits(db, Limits{QueryTimeout: time.Second})
+
+	start := time.Now()
+	_, err := executor.Execute("SELECT pg_sleep(3)")
+	elapsed := time.Since(start)
+
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T: %v", err, err)
+	}
+	if vibeErr.Code != postgres.ErrorCodeQueryTimeout {
+		t.Errorf("Expected %s, got %s", postgres.ErrorCodeQueryTimeout, vibeErr.Code)
+	}
+	if vibeErr.Detail != "Query exceeded the maximum execution time of 1 second" {
+		t.Errorf("Expected detail to report the configured timeout, got %q", vibeErr.Detail)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected query to be canceled after ~1s, took %v", elapsed)
+	}
+}
+
 func BenchmarkCheckRowLimit(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = CheckRowLimit(500)
 	}
 }
+
+func TestExecutor_Execute_WithParams(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+
+	result, err := executor.Execute("SELECT $1::int + 1 AS num, $2::text AS name", int64(41), "O'Brien")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.RowCount != 1 {
+		t.Fatalf("Expected 1 row, got %d", result.RowCount)
+	}
+
+	if result.Rows[0]["num"] != int64(42) {
+		t.Errorf("Expected num = 42, got %v", result.Rows[0]["num"])
+	}
+
+	if result.Rows[0]["name"] != "O'Brien" {
+		t.Errorf("Expected name = O'Brien, got %v", result.Rows[0]["name"])
+	}
+}
+
+func TestExecutor_Execute_ParamCountMismatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+
+	_, err := executor.Execute("SELECT $1::int, $2::int", int64(1))
+	if err == nil {
+		t.Fatal("Expected error for missing parameter, got nil")
+	}
+
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T", err)
+	}
+
+	if vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+		t.Errorf("Expected INVALID_SQL error, got %s", vibeErr.Code)
+	}
+}
+
+func TestExecutor_Execute_ColumnsAndValues(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+
+	result, err := executor.Execute("SELECT 1 AS id, 'a' AS name, 2 AS id")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.Columns) != 3 {
+		t.Fatalf("Expected 3 columns, got %d", len(result.Columns))
+	}
+
+	expectedNames := []string{"id", "name", "id"}
+	for i, name := range expectedNames {
+		if result.Columns[i].Name != name {
+			t.Errorf("Column %d: expected name %s, got %s", i, name, result.Columns[i].Name)
+		}
+	}
+
+	if result.Columns[0].Type != "int4" || result.Columns[0].TypeOID != 23 {
+		t.Errorf("Expected int4 (23), got %s (%d)", result.Columns[0].Type, result.Columns[0].TypeOID)
+	}
+
+	if len(result.Values) != 1 || len(result.Values[0]) != 3 {
+		t.Fatalf("Expected 1 positional row of 3 values, got %v", result.Values)
+	}
+
+	if result.Values[0][0] != int64(1) || result.Values[0][2] != int64(2) {
+		t.Errorf("Expected duplicate columns preserved positionally, got %v", result.Values[0])
+	}
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// ExplainOptions selects the EXPLAIN options added to FORMAT JSON
+type ExplainOptions struct {
+	// Analyze runs the statement to report actual row counts and timings
+	Analyze bool
+	// Buffers reports shared buffer usage
+	Buffers bool
+}
+
+// ExplainResult is the plan PostgreSQL chose for a statement
+type ExplainResult struct {
+	// Plan is the root node of the plan tree, as decoded from EXPLAIN's
+	// JSON output. Child nodes are under "Plans".
+	Plan map[string]interface{}
+	// PlanningTime and RunTime are PostgreSQL's own timings, reported
+	// when the statement was analyzed
+	PlanningTime time.Duration
+	RunTime      time.Duration
+	Analyzed     bool
+	// ExecutionTime is the time taken by the whole EXPLAIN
+	ExecutionTime time.Duration
+}
+
+// Explain returns the plan of a single statement using EXPLAIN (FORMAT
+// JSON). The EXPLAIN runs in a transaction that is always rolled back, so
+// with opts.Analyze a data-modifying statement runs but leaves no changes.
+func (e *Executor) Explain(sql string, params []interface{}, opts Options, explain ExplainOptions) (*ExplainResult, error) {
+	startTime := time.Now()
+
+	stmt, err := singleStatement(sql)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.Class == ClassExplain {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid EXPLAIN request",
+			"The statement is already an EXPLAIN. Send the statement to explain on its own",
+		)
+	}
+
+	options := []string{"FORMAT JSON"}
+	if explain.Analyze {
+		options = append(options, "ANALYZE")
+	}
+	if explain.Buffers {
+		options = append(options, "BUFFERS")
+	}
+	explainSQL := fmt.Sprintf("EXPLAIN (%s) %s", strings.Join(options, ", "), copyAsQuery(stmt.SQL))
+
+	tx, err := beginTx(e.db, opts)
+	if err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.limits.QueryTimeout)
+	defer cancel()
+
+	var output []byte
+	if err := tx.QueryRowContext(ctx, explainSQL, params...).Scan(&output); err != nil {
+		return nil, e.limits.translateError(err)
+	}
+
+	var plans []struct {
+		Plan          map[string]interface{} `json:"Plan"`
+		PlanningTime  *float64               `json:"Planning Time"`
+		ExecutionTime *float64               `json:"Execution Time"`
+	}
+	if err := json.Unmarshal(output, &plans); err != nil || len(plans) == 0 {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInternalError,
+			"An internal error occurred",
+			fmt.Sprintf("Unexpected EXPLAIN output: %.200s", output),
+		)
+	}
+
+	result := &ExplainResult{
+		Plan:     plans[0].Plan,
+		Analyzed: explain.Analyze,
+	}
+	if plans[0].PlanningTime != nil {
+		result.PlanningTime = milliseconds(*plans[0].PlanningTime)
+	}
+	if plans[0].ExecutionTime != nil {
+		result.RunTime = milliseconds(*plans[0].ExecutionTime)
+	}
+	result.ExecutionTime = time.Since(startTime)
+	return result, nil
+}
+
+// StatementCount is the number of rows one statement of a dry run affected
+type StatementCount struct {
+	Command      string
+	CommandTag   string
+	RowsAffected int64
+}
+
+// DryRunResult reports what a dry run would have changed
+type DryRunResult struct {
+	Statements    []StatementCount
+	RowsAffected  int64
+	ExecutionTime time.Duration
+}
+
+// DryRun executes every statement of sql inside a transaction that is
+// always rolled back, and reports how many rows each statement affected.
+// Params are only accepted for a single statement. A failing statement is
+// reported as a *StatementError.
+func (e *Executor) DryRun(sql string, params []interface{}, opts Options) (*DryRunResult, error) {
+	startTime := time.Now()
+
+	statements, err := ParseStatements(sql)
+	if err != nil {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid SQL syntax",
+			capitalize(err.Error()),
+		)
+	}
+	if len(params) > 0 && len(statements) > 1 {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid dry run request",
+			fmt.Sprintf("Params can only be used with a single statement, got %d statements", len(statements)),
+		)
+	}
+
+	tx, err := beginTx(e.db, opts)
+	if err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+	defer tx.Rollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.limits.QueryTimeout)
+	defer cancel()
+
+	result := &DryRunResult{Statements: make([]StatementCount, 0, len(statements))}
+	for i, stmt := range statements {
+		res, err := tx.ExecContext(ctx, copyAsQuery(stmt.SQL), params...)
+		if err != nil {
+			return nil, &StatementError{Index: i, Err: e.limits.translateError(err)}
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, &StatementError{Index: i, Err: e.limits.translateError(err)}
+		}
+
+		result.Statements = append(result.Statements, StatementCount{
+			Command:      stmt.Command,
+			CommandTag:   commandTag(stmt, affected),
+			RowsAffected: affected,
+		})
+		result.RowsAffected += affected
+	}
+
+	result.ExecutionTime = time.Since(startTime)
+	return result, nil
+}
+
+// singleStatement parses sql, which must hold exactly one statement
+func singleStatement(sql string) (*ParsedStatement, error) {
+	statements, err := ParseStatements(sql)
+	if err != nil {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid SQL syntax",
+			capitalize(err.Error()),
+		)
+	}
+	if len(statements) != 1 {
+		return nil, postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid EXPLAIN request",
+			fmt.Sprintf("EXPLAIN takes a single statement, got %d", len(statements)),
+		)
+	}
+	return statements[0], nil
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,95 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func TestExecutor_Explain_RejectsStatementLists(t *testing.T) {
+	executor := NewExecutor(nil)
+
+	for _, sql := range []string{"SELECT 1; SELECT 2", "EXPLAIN SELECT 1", "SELECT 'unterminated"} {
+		_, err := executor.Explain(sql, nil, Options{}, ExplainOptions{})
+		vibeErr, ok := err.(*postgres.VibeError)
+		if !ok || vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+			t.Errorf("Explain(%q): expected INVALID_SQL, got %v", sql, err)
+		}
+	}
+}
+
+func TestExecutor_DryRun_ParamsNeedOneStatement(t *testing.T) {
+	executor := NewExecutor(nil)
+
+	_, err := executor.DryRun("UPDATE t SET a = $1 WHERE id = 1; DELETE FROM t WHERE id = $1", []interface{}{int64(1)}, Options{})
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok || vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+		t.Errorf("Expected INVALID_SQL, got %v", err)
+	}
+}
+
+func TestExecutor_Explain(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+
+	result, err := executor.Explain("SELECT * FROM generate_series(1, $1) AS n", []interface{}{int64(10)}, Options{}, ExplainOptions{})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if result.Plan["Node Type"] != "Function Scan" {
+		t.Errorf("Expected a Function Scan plan, got %v", result.Plan)
+	}
+	if result.Analyzed || result.RunTime != 0 {
+		t.Error("Expected no run time without analyze")
+	}
+
+	result, err = executor.Explain("SELECT * FROM generate_series(1, 10) AS n", nil, Options{}, ExplainOptions{Analyze: true, Buffers: true})
+	if err != nil {
+		t.Fatalf("Explain analyze failed: %v", err)
+	}
+	if result.Plan["Actual Rows"] != float64(10) {
+		t.Errorf("Expected 10 actual rows, got %v", result.Plan["Actual Rows"])
+	}
+	if !result.Analyzed || result.RunTime <= 0 {
+		t.Errorf("Expected a run time with analyze, got %v", result.RunTime)
+	}
+}
+
+func TestExecutor_DryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("DROP TABLE IF EXISTS dry_run_test; CREATE TABLE dry_run_test (id int); INSERT INTO dry_run_test SELECT generate_series(1, 5)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS dry_run_test")
+
+	executor := NewExecutor(db)
+
+	result, err := executor.DryRun("UPDATE dry_run_test SET id = id + 10 WHERE id > 2; DELETE FROM dry_run_test WHERE id > 12", nil, Options{})
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if len(result.Statements) != 2 || result.Statements[0].RowsAffected != 3 || result.Statements[1].RowsAffected != 2 {
+		t.Errorf("Unexpected counts: %+v", result.Statements)
+	}
+	if result.Statements[0].Command != "UPDATE" || result.RowsAffected != 5 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM dry_run_test WHERE id > 10").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the dry run to be rolled back, found %d changed rows", count)
+	}
+
+	_, err = executor.DryRun("SELECT 1; SELECT * FROM dry_run_missing", nil, Options{})
+	stmtErr, ok := err.(*StatementError)
+	if !ok || stmtErr.Index != 1 {
+		t.Errorf("Expected a StatementError for statement 1, got %v", err)
+	}
+}
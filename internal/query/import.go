@@ -0,0 +1,523 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// Import formats
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+	ImportJSON   = "json"
+)
+
+// Table creation modes of an import
+const (
+	// CreateInfer creates a missing table with a column per field, typed
+	// from the first records
+	CreateInfer = "infer"
+	// CreateJSONB creates a missing table with a single jsonb column,
+	// JSONBColumn, holding each record as an object
+	CreateJSONB = "jsonb"
+)
+
+const (
+	// JSONBColumn is the column records are loaded into with CreateJSONB
+	JSONBColumn = "data"
+
+	// importSampleSize is the number of records read before loading
+	// starts, to find the fields and infer column types
+	importSampleSize = 1000
+	// maxImportRejections is the number of rejected records described in
+	// an import result
+	maxImportRejections = 10
+)
+
+// ImportOptions describes the data of an import and where it goes
+type ImportOptions struct {
+	// Table is the target table, optionally schema-qualified
+	Table string
+	// Format is ImportCSV, ImportNDJSON or ImportJSON
+	Format string
+	// Columns selects the source fields to load, in order. They name the
+	// fields of CSV data without a header. Empty loads every field.
+	Columns []string
+	// Mapping renames source fields to the table columns they load into
+	Mapping map[string]string
+	// NoHeader marks CSV data without a header row
+	NoHeader bool
+	// Create is CreateInfer or CreateJSONB to create the table if it does
+	// not exist, empty to require an existing table
+	Create string
+	// Role runs the import as this PostgreSQL role, like Options.Role
+	Role string
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	Table string
+	// Columns are the table columns that were loaded
+	Columns      []string
+	Created      bool
+	RowsLoaded   int64
+	RowsRejected int64
+	// Rejections describes the first rejected records
+	Rejections    []Rejection
+	ExecutionTime time.Duration
+}
+
+// Rejection describes a record that was skipped
+type Rejection struct {
+	// Row is the 1-based position of the record in the data, not counting
+	// a CSV header
+	Row    int64
+	Reason string
+}
+
+// Import loads records into a table with COPY FROM STDIN. Malformed
+// records are skipped and reported; every other record is loaded in a
+// single transaction, so a value PostgreSQL rejects fails the whole import.
+func (e *Executor) Import(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	startTime := time.Now()
+
+	schema, table, err := splitTableName(opts.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := newRecordReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &importer{records: records, opts: opts, result: &ImportResult{Table: opts.Table}}
+
+	// The first records decide the fields and column types
+	var sample []*importRecord
+	for len(sample) < importSampleSize {
+		record, err := im.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		sample = append(sample, record)
+	}
+
+	if err := im.plan(sample); err != nil {
+		return nil, err
+	}
+	im.result.Columns = im.columns
+	if len(im.columns) == 0 {
+		im.result.ExecutionTime = time.Since(startTime)
+		return im.result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.limits.ImportTimeout)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+	defer tx.Rollback()
+
+	// The pool's statement_timeout follows the query timeout, which the
+	// COPY would outlast
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", e.limits.ImportTimeout.Milliseconds())); err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+	if err := setRole(ctx, tx, opts.Role); err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+
+	if opts.Create != "" {
+		created, err := createTable(ctx, tx, quoteTable(schema, table), im.columnDefinitions(sample))
+		if err != nil {
+			return nil, e.limits.translateImportError(err)
+		}
+		im.result.Created = created
+	}
+
+	copyIn := pq.CopyIn(table, im.columns...)
+	if schema != "" {
+		copyIn = pq.CopyInSchema(schema, table, im.columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, copyIn)
+	if err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+	defer stmt.Close()
+
+	load := func(record *importRecord) error {
+		values, reason := im.values(record)
+		if reason != "" {
+			im.reject(reason)
+			return nil
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return e.limits.translateImportError(err)
+		}
+		im.result.RowsLoaded++
+		return nil
+	}
+
+	for _, record := range sample {
+		if err := load(record); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		record, err := im.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := load(record); err != nil {
+			return nil, err
+		}
+	}
+
+	// Executing the COPY statement without values ends the data
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, e.limits.translateImportError(err)
+	}
+
+	im.result.ExecutionTime = time.Since(startTime)
+	return im.result, nil
+}
+
+// importer tracks the state of one import
+type importer struct {
+	records recordReader
+	opts    ImportOptions
+	result  *ImportResult
+	row     int64
+
+	// fields are the source fields loaded into columns, position by position
+	fields  []string
+	columns []string
+	// explicit is set when the fields were chosen by ImportOptions.Columns,
+	// so other fields of a record are ignored rather than rejected
+	explicit bool
+}
+
+// read returns the next record that can be loaded, recording the rejected
+// ones, or io.EOF after the last record
+func (im *importer) read() (*importRecord, error) {
+	for {
+		record, err := im.records.next()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		im.row++
+
+		var rejected *rejectedRecord
+		if errors.As(err, &rejected) {
+			im.reject(rejected.reason)
+			continue
+		}
+		if err != nil {
+			return nil, asImportError(err)
+		}
+		return record, nil
+	}
+}
+
+func (im *importer) reject(reason string) {
+	im.result.RowsRejected++
+	if len(im.result.Rejections) < maxImportRejections {
+		im.result.Rejections = append(im.result.Rejections, Rejection{Row: im.row, Reason: reason})
+	}
+}
+
+// plan decides which fields are loaded into which columns. Without explicit
+// columns, the fields are those of the CSV header, or every field of the
+// sampled JSON records in order of appearance.
+func (im *importer) plan(sample []*importRecord) error {
+	if im.opts.Create == CreateJSONB {
+		if len(im.opts.Mapping) > 0 {
+			return invalidImportError("Field mapping cannot be used when loading records into a jsonb column")
+		}
+		if len(sample) > 0 {
+			im.columns = []string{JSONBColumn}
+		}
+		return nil
+	}
+
+	available := make(map[string]bool)
+	var discovered []string
+	for _, record := range sample {
+		for _, key := range record.keys {
+			if !available[key] {
+				available[key] = true
+				discovered = append(discovered, key)
+			}
+		}
+	}
+	if c, ok := im.records.(*csvRecords); ok {
+		discovered = c.header
+		for _, name := range discovered {
+			available[name] = true
+		}
+	}
+
+	im.fields = discovered
+	if len(im.opts.Columns) > 0 {
+		im.fields = im.opts.Columns
+		im.explicit = true
+		if im.opts.Format == ImportCSV {
+			for _, name := range im.fields {
+				if !available[name] {
+					return invalidImportError(fmt.Sprintf("Column %q is not in the CSV header", name))
+				}
+			}
+		}
+	}
+
+	for source := range im.opts.Mapping {
+		if !contains(im.fields, source) {
+			return invalidImportError(fmt.Sprintf("Mapped field %q is not loaded", source))
+		}
+	}
+
+	seen := make(map[string]bool, len(im.fields))
+	im.columns = make([]string, len(im.fields))
+	for i, field := range im.fields {
+		column := field
+		if target, ok := im.opts.Mapping[field]; ok {
+			column = target
+		}
+		if seen[column] {
+			return invalidImportError(fmt.Sprintf("Column %q is loaded twice", column))
+		}
+		seen[column] = true
+		im.columns[i] = column
+	}
+	return nil
+}
+
+// values returns the COPY values of a record, or the reason it is rejected
+func (im *importer) values(record *importRecord) ([]interface{}, string) {
+	if im.opts.Create == CreateJSONB {
+		if record.raw != nil {
+			return []interface{}{string(record.raw)}, ""
+		}
+		raw, err := json.Marshal(record.fields)
+		if err != nil {
+			return nil, capitalize(err.Error())
+		}
+		return []interface{}{string(raw)}, ""
+	}
+
+	if !im.explicit {
+		for _, key := range record.keys {
+			if !contains(im.fields, key) {
+				return nil, fmt.Sprintf("Unexpected field %q", key)
+			}
+		}
+	}
+
+	values := make([]interface{}, len(im.fields))
+	for i, field := range im.fields {
+		value, err := copyValue(record.fields[field])
+		if err != nil {
+			return nil, fmt.Sprintf("Field %q: %v", field, err)
+		}
+		values[i] = value
+	}
+	return values, ""
+}
+
+// columnDefinitions returns the column definitions of a table created for
+// the import, with types inferred from the sampled records
+func (im *importer) columnDefinitions(sample []*importRecord) []string {
+	if im.opts.Create == CreateJSONB {
+		return []string{pq.QuoteIdentifier(JSONBColumn) + " jsonb"}
+	}
+
+	definitions := make([]string, len(im.fields))
+	for i, field := range im.fields {
+		columnType := ""
+		for _, record := range sample {
+			columnType = mergeTypes(columnType, valueType(record.fields[field], record.raw == nil))
+		}
+		if columnType == "" {
+			columnType = "text"
+		}
+		definitions[i] = pq.QuoteIdentifier(im.columns[i]) + " " + columnType
+	}
+	return definitions
+}
+
+// createTable creates a table unless it already exists, reporting whether
+// it was created
+func createTable(ctx context.Context, tx *sql.Tx, table string, definitions []string) (bool, error) {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	create := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(definitions, ", "))
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// copyValue converts a record value into its COPY text, or nil for NULL.
+// JSON objects and arrays are loaded as JSON text.
+func copyValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+var (
+	integerPattern = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	decimalPattern = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// valueType infers the PostgreSQL type of one value, or "" for NULL.
+// Numbers and booleans are only recognized in text when parseText is set,
+// as in CSV data; in JSON they have their own types.
+func valueType(value interface{}, parseText bool) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "bigint"
+		}
+		return "numeric"
+	case map[string]interface{}, []interface{}:
+		return "jsonb"
+	case string:
+		if parseText {
+			switch {
+			case integerPattern.MatchString(v):
+				if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+					return "bigint"
+				}
+				return "numeric"
+			case decimalPattern.MatchString(v):
+				return "numeric"
+			case strings.EqualFold(v, "true"), strings.EqualFold(v, "false"):
+				return "boolean"
+			}
+		}
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return "timestamptz"
+		}
+		if _, err := time.Parse("2006-01-02", v); err == nil {
+			return "date"
+		}
+	}
+	return "text"
+}
+
+// mergeTypes returns a type that holds values of both types, "" standing
+// for a column with only NULLs so far
+func mergeTypes(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case (a == "bigint" && b == "numeric") || (a == "numeric" && b == "bigint"):
+		return "numeric"
+	case (a == "date" && b == "timestamptz") || (a == "timestamptz" && b == "date"):
+		return "timestamptz"
+	}
+	return "text"
+}
+
+// splitTableName splits an optionally schema-qualified table name
+func splitTableName(name string) (schema, table string, err error) {
+	parts := strings.Split(name, ".")
+	for _, part := range parts {
+		if part == "" {
+			return "", "", invalidImportError(fmt.Sprintf("Invalid table name %q", name))
+		}
+	}
+	switch len(parts) {
+	case 1:
+		return "", parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	}
+	return "", "", invalidImportError(fmt.Sprintf("Invalid table name %q", name))
+}
+
+// quoteTable quotes an optionally schema-qualified table name
+func quoteTable(schema, table string) string {
+	if schema == "" {
+		return pq.QuoteIdentifier(table)
+	}
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(table)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidImportError(detail string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		postgres.ErrorCodeInvalidSQL,
+		"Invalid import data",
+		detail,
+	)
+}
+
+// asImportError reports a failure to read the import data
+func asImportError(err error) *postgres.VibeError {
+	var vibeErr *postgres.VibeError
+	if errors.As(err, &vibeErr) {
+		return vibeErr
+	}
+	return invalidImportError(fmt.Sprintf("Failed to read import data: %v", err))
+}
@@ -0,0 +1,192 @@
+package query
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func TestValueType(t *testing.T) {
+	tests := []struct {
+		value     interface{}
+		parseText bool
+		want      string
+	}{
+		{nil, true, ""},
+		{true, false, "boolean"},
+		{json.Number("42"), false, "bigint"},
+		{json.Number("4.2"), false, "numeric"},
+		{json.Number("99999999999999999999"), false, "numeric"},
+		{map[string]interface{}{"a": 1}, false, "jsonb"},
+		{[]interface{}{1}, false, "jsonb"},
+		{"42", true, "bigint"},
+		{"-4.2e3", true, "numeric"},
+		{"TRUE", true, "boolean"},
+		{"42", false, "text"},
+		{"NaN", true, "text"},
+		{"2026-02-07T13:45:30Z", false, "timestamptz"},
+		{"2026-02-07", true, "date"},
+		{"Alice", true, "text"},
+	}
+
+	for _, tt := range tests {
+		if got := valueType(tt.value, tt.parseText); got != tt.want {
+			t.Errorf("valueType(%#v, %v) = %q, want %q", tt.value, tt.parseText, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTypes(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"", "bigint", "bigint"},
+		{"bigint", "", "bigint"},
+		{"bigint", "numeric", "numeric"},
+		{"date", "timestamptz", "timestamptz"},
+		{"boolean", "bigint", "text"},
+		{"jsonb", "text", "text"},
+	}
+
+	for _, tt := range tests {
+		if got := mergeTypes(tt.a, tt.b); got != tt.want {
+			t.Errorf("mergeTypes(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTableName(t *testing.T) {
+	if schema, table, err := splitTableName("public.users"); err != nil || schema != "public" || table != "users" {
+		t.Errorf("Expected public and users, got %q, %q, %v", schema, table, err)
+	}
+	if schema, table, err := splitTableName("users"); err != nil || schema != "" || table != "users" {
+		t.Errorf("Expected users, got %q, %q, %v", schema, table, err)
+	}
+	for _, name := range []string{"", "a.b.c", ".users", "public."} {
+		if _, _, err := splitTableName(name); err == nil {
+			t.Errorf("splitTableName(%q) expected an error", name)
+		}
+	}
+}
+
+func planImport(t *testing.T, data string, opts ImportOptions) (*importer, []*importRecord, error) {
+	t.Helper()
+	reader, err := newRecordReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("newRecordReader unexpected error = %v", err)
+	}
+
+	im := &importer{records: reader, opts: opts, result: &ImportResult{}}
+	var sample []*importRecord
+	for {
+		record, err := im.read()
+		if err != nil {
+			break
+		}
+		sample = append(sample, record)
+	}
+	return im, sample, im.plan(sample)
+}
+
+func TestImporter_Plan(t *testing.T) {
+	data := `{"id": 1, "name": "Alice"}` + "\n" + `{"id": 2, "email": "bob@example.com"}`
+	im, sample, err := planImport(t, data, ImportOptions{Format: ImportNDJSON, Mapping: map[string]string{"name": "full_name"}})
+	if err != nil {
+		t.Fatalf("plan unexpected error = %v", err)
+	}
+
+	if !reflect.DeepEqual(im.columns, []string{"id", "full_name", "email"}) {
+		t.Errorf("Expected every field in order of appearance, mapped, got %v", im.columns)
+	}
+
+	definitions := im.columnDefinitions(sample)
+	want := []string{`"id" bigint`, `"full_name" text`, `"email" text`}
+	if !reflect.DeepEqual(definitions, want) {
+		t.Errorf("Expected %v, got %v", want, definitions)
+	}
+
+	values, reason := im.values(sample[1])
+	if reason != "" || !reflect.DeepEqual(values, []interface{}{"2", nil, "bob@example.com"}) {
+		t.Errorf("Expected missing fields to be NULL, got %v (%s)", values, reason)
+	}
+
+	if _, reason := im.values(&importRecord{fields: map[string]interface{}{"age": json.Number("3")}, keys: []string{"age"}}); !strings.Contains(reason, "age") {
+		t.Errorf("Expected a record with a new field to be rejected, got %q", reason)
+	}
+}
+
+func TestImporter_PlanColumns(t *testing.T) {
+	im, sample, err := planImport(t, "id,name,email\n1,Alice,a@example.com\n", ImportOptions{Format: ImportCSV, Columns: []string{"email", "id"}})
+	if err != nil {
+		t.Fatalf("plan unexpected error = %v", err)
+	}
+	values, _ := im.values(sample[0])
+	if !reflect.DeepEqual(values, []interface{}{"a@example.com", "1"}) {
+		t.Errorf("Expected the selected columns in order, got %v", values)
+	}
+
+	invalid := []ImportOptions{
+		{Format: ImportCSV, Columns: []string{"phone"}},
+		{Format: ImportCSV, Mapping: map[string]string{"phone": "tel"}},
+		{Format: ImportCSV, Mapping: map[string]string{"name": "id"}},
+		{Format: ImportCSV, Create: CreateJSONB, Mapping: map[string]string{"name": "n"}},
+	}
+	for _, opts := range invalid {
+		_, _, err := planImport(t, "id,name\n1,Alice\n", opts)
+		if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+			t.Errorf("Expected %+v to be rejected, got %v", opts, err)
+		}
+	}
+}
+
+func TestImporter_JSONBValues(t *testing.T) {
+	im, sample, err := planImport(t, "id,name\n1,Alice\n", ImportOptions{Format: ImportCSV, Create: CreateJSONB})
+	if err != nil {
+		t.Fatalf("plan unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(im.columns, []string{JSONBColumn}) {
+		t.Errorf("Expected the jsonb column, got %v", im.columns)
+	}
+	values, _ := im.values(sample[0])
+	if !reflect.DeepEqual(values, []interface{}{`{"id":"1","name":"Alice"}`}) {
+		t.Errorf("Expected the record as an object, got %v", values)
+	}
+}
+
+func TestExecutor_Import(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	executor.Execute("DROP TABLE IF EXISTS import_check")
+	defer executor.Execute("DROP TABLE IF EXISTS import_check")
+
+	data := "id,name,joined\n1,Alice,2026-01-02\n2,Bob\n3,Carol,2026-03-04\n"
+	result, err := executor.Import(strings.NewReader(data), ImportOptions{Table: "import_check", Format: ImportCSV, Create: CreateInfer})
+	if err != nil {
+		t.Fatalf("Import unexpected error = %v", err)
+	}
+	if !result.Created || result.RowsLoaded != 2 || result.RowsRejected != 1 || result.Rejections[0].Row != 2 {
+		t.Errorf("Expected 2 rows loaded and row 2 rejected into a new table, got %+v", result)
+	}
+
+	rows, err := executor.Execute("SELECT id + 1 AS next, joined FROM import_check ORDER BY id")
+	if err != nil {
+		t.Fatalf("Failed to query imported rows: %v", err)
+	}
+	if rows.RowCount != 2 || rows.Rows[0]["next"] != int64(2) || rows.Rows[1]["joined"] != "2026-03-04" {
+		t.Errorf("Expected typed columns, got %v", rows.Rows)
+	}
+
+	// A value the column cannot hold fails the whole import
+	_, err = executor.Import(strings.NewReader(`[{"id": 4, "name": "Dave"}, {"id": "x"}]`), ImportOptions{Table: "import_check", Format: ImportJSON})
+	if err == nil {
+		t.Fatal("Expected an invalid bigint to fail the import")
+	}
+	if rows, _ := executor.Execute("SELECT * FROM import_check"); rows.RowCount != 2 {
+		t.Errorf("Expected a failed import to load nothing, got %d rows", rows.RowCount)
+	}
+}
@@ -1,8 +1,28 @@
 package query
 
+import "io"
+
 // QueryExecutor defines the interface for executing SQL queries
 type QueryExecutor interface {
-	Execute(sql string) (*ExecutionResult, error)
+	ExecuteWithOptions(sql string, params []interface{}, opts Options) (*ExecutionResult, error)
+	ExecuteTransaction(statements []Statement, opts Options) ([]*ExecutionResult, error)
+	ExecuteStream(sql string, params []interface{}, opts Options, w RowWriter) (*ExecutionResult, error)
+	ExecuteBatch(statements []Statement, opts Options, batch BatchOptions) []BatchResult
+	Import(r io.Reader, opts ImportOptions) (*ImportResult, error)
+
+	// Plans and dry runs, rolled back after they run
+	Explain(sql string, params []interface{}, opts Options, explain ExplainOptions) (*ExplainResult, error)
+	DryRun(sql string, params []interface{}, opts Options) (*DryRunResult, error)
+
+	// Paginated queries
+	ExecutePage(sql string, params []interface{}, opts Options, limit int) (*ExecutionResult, error)
+	FetchPage(cursor string, opts Options, limit int) (*ExecutionResult, error)
+
+	// Interactive transactions
+	BeginSession(opts Options) (string, error)
+	ExecuteInSession(id string, sql string, params []interface{}, opts Options) (*ExecutionResult, error)
+	CommitSession(id string, opts Options) error
+	RollbackSession(id string, opts Options) error
 }
 
 // Ensure Executor implements QueryExecutor
@@ -1,20 +1,96 @@
 package query
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/vibesql/vibe/internal/postgres"
 )
 
 const (
+	// MaxResultRows is the default maximum number of rows a query may return
 	MaxResultRows = 1000
+
+	// QueryTimeout is the default maximum execution time of a query
+	QueryTimeout = 5 * time.Second
+
+	// ImportTimeout is the default maximum time a bulk import may take
+	ImportTimeout = 5 * time.Minute
 )
 
+// Limits bounds the size and running time of queries. Each server carries
+// its own limits; zero fields fall back to the package defaults.
+type Limits struct {
+	QueryTimeout  time.Duration
+	MaxResultRows int
+	MaxQuerySize  int
+	// MaxTxSessions caps the interactive transactions and cursors open at
+	// once. Each holds a pool connection, so it should stay below the pool
+	// size.
+	MaxTxSessions int
+	// ImportTimeout bounds a bulk import, upload included, in place of
+	// QueryTimeout
+	ImportTimeout time.Duration
+}
+
+// DefaultLimits returns the built-in limits
+func DefaultLimits() Limits {
+	return Limits{
+		QueryTimeout:  QueryTimeout,
+		MaxResultRows: MaxResultRows,
+		MaxQuerySize:  MaxQuerySize,
+		MaxTxSessions: MaxTxSessions,
+		ImportTimeout: ImportTimeout,
+	}
+}
+
+// WithDefaults returns l with zero fields replaced by the defaults
+func (l Limits) WithDefaults() Limits {
+	defaults := DefaultLimits()
+	if l.QueryTimeout <= 0 {
+		l.QueryTimeout = defaults.QueryTimeout
+	}
+	if l.MaxResultRows <= 0 {
+		l.MaxResultRows = defaults.MaxResultRows
+	}
+	if l.MaxQuerySize <= 0 {
+		l.MaxQuerySize = defaults.MaxQuerySize
+	}
+	if l.MaxTxSessions <= 0 {
+		l.MaxTxSessions = defaults.MaxTxSessions
+	}
+	if l.ImportTimeout <= 0 {
+		l.ImportTimeout = defaults.ImportTimeout
+	}
+	return l
+}
+
+// CheckRowLimit checks a row count against the default row limit
 func CheckRowLimit(currentRowCount int) error {
-	if currentRowCount >= MaxResultRows {
+	return DefaultLimits().CheckRowLimit(currentRowCount)
+}
+
+// CheckRowLimit returns RESULT_TOO_LARGE once currentRowCount reaches the
+// row limit
+func (l Limits) CheckRowLimit(currentRowCount int) error {
+	if currentRowCount >= l.MaxResultRows {
 		return postgres.NewVibeError(
 			postgres.ErrorCodeResultTooLarge,
 			"Result set too large",
-			"Query returned more than the maximum allowed 1000 rows",
+			fmt.Sprintf("Query returned more than the maximum allowed %d rows", l.MaxResultRows),
 		)
 	}
 	return nil
 }
+
+// translateError translates a driver error, reporting timeouts against the
+// configured query timeout
+func (l Limits) translateError(err error) *postgres.VibeError {
+	return postgres.TranslateErrorWithTimeout(err, l.QueryTimeout)
+}
+
+// translateImportError translates a driver error from an import, reporting
+// timeouts against the import timeout
+func (l Limits) translateImportError(err error) *postgres.VibeError {
+	return postgres.TranslateErrorWithTimeout(err, l.ImportTimeout)
+}
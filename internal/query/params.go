@@ -0,0 +1,80 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// BindParams converts JSON-decoded request parameters into values suitable
+// for binding to $1..$n placeholders.
+//
+// The request body must be decoded with json.Decoder.UseNumber so numbers
+// arrive as json.Number:
+//   - integral numbers bind as int64
+//   - other numbers bind as their literal text, so PostgreSQL parses them
+//     into numeric/float without losing precision
+//   - strings, booleans and null bind as-is
+//   - objects and arrays bind as JSON text, which PostgreSQL accepts
+//     wherever a json/jsonb value is expected
+func BindParams(values []interface{}) ([]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	bound := make([]interface{}, len(values))
+	for i, v := range values {
+		b, err := bindParam(v)
+		if err != nil {
+			return nil, err
+		}
+		bound[i] = b
+	}
+	return bound, nil
+}
+
+func bindParam(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, string, bool:
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, nil
+		}
+		return val.String(), nil
+	case float64:
+		return val, nil
+	default:
+		// Objects and arrays
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+}
+
+// CheckParams reports an error when the number of params differs from the
+// number of $1..$n placeholders sql uses, the highest n. Placeholders
+// inside string constants and function bodies don't count. SQL that fails
+// to tokenize is left for PostgreSQL to report.
+func CheckParams(sql string, params []interface{}) error {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil
+	}
+
+	required := 0
+	for _, t := range tokens {
+		if t.kind != tokenParam {
+			continue
+		}
+		if n, err := strconv.Atoi(t.text[1:]); err == nil && n > required {
+			required = n
+		}
+	}
+	if required != len(params) {
+		return fmt.Errorf("the query uses %d parameter(s) but %d were given", required, len(params))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeParams(t *testing.T, raw string) []interface{} {
+	t.Helper()
+	var values []interface{}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		t.Fatalf("Failed to decode params %s: %v", raw, err)
+	}
+	return values
+}
+
+func TestBindParams_Empty(t *testing.T) {
+	bound, err := BindParams(nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bound != nil {
+		t.Errorf("Expected nil params, got %v", bound)
+	}
+}
+
+func TestBindParams_Types(t *testing.T) {
+	values := decodeParams(t, `[42, 3.14, 12345678901234567890, "Alice", true, null, {"name": "Bob"}, [1, 2]]`)
+
+	bound, err := BindParams(values)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []interface{}{
+		int64(42),
+		"3.14",
+		"12345678901234567890",
+		"Alice",
+		true,
+		nil,
+		`{"name":"Bob"}`,
+		`[1,2]`,
+	}
+
+	if len(bound) != len(expected) {
+		t.Fatalf("Expected %d params, got %d", len(expected), len(bound))
+	}
+
+	for i := range expected {
+		if bound[i] != expected[i] {
+			t.Errorf("Param $%d: expected %v (%T), got %v (%T)", i+1, expected[i], expected[i], bound[i], bound[i])
+		}
+	}
+}
+
+func TestBindParams_PlainFloat(t *testing.T) {
+	bound, err := BindParams([]interface{}{1.5})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bound[0] != 1.5 {
+		t.Errorf("Expected 1.5, got %v", bound[0])
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	tests := []struct {
+		sql     string
+		params  int
+		wantErr bool
+	}{
+		{"SELECT 1", 0, false},
+		{"SELECT $1, $2", 2, false},
+		{"SELECT $1 WHERE $1 > 0", 1, false},
+		{"SELECT $2", 2, false},
+		{"SELECT $1, $2", 1, true},
+		{"SELECT $1", 0, true},
+		{"SELECT 1", 1, true},
+		{"SELECT '$1', $$ $2 $$", 0, false},
+		{"SELECT 'unterminated", 3, false},
+	}
+	for _, tt := range tests {
+		err := CheckParams(tt.sql, make([]interface{}, tt.params))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckParams(%q, %d params) error = %v, wantErr %v", tt.sql, tt.params, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,619 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+// Safety profiles selectable by name
+const (
+	// ProfileDefault allows the default statement classes and requires a
+	// WHERE clause on every UPDATE and DELETE
+	ProfileDefault = "default"
+	// ProfileReadOnly only allows statements that read data, and runs them
+	// in READ ONLY transactions so PostgreSQL rejects any write as well
+	ProfileReadOnly = "readonly"
+	// ProfileNoDDL is the default profile without DDL and TRUNCATE
+	ProfileNoDDL = "no-ddl"
+	// ProfilePermissive allows every statement class except session and
+	// transaction control and does not require WHERE clauses
+	ProfilePermissive = "permissive"
+)
+
+// DefaultForbiddenFunctions are the functions a policy rejects unless it
+// sets its own list. They read or write server files, signal other
+// backends, change server configuration or reach other servers, or run
+// SQL passed to them as a string, where the calls it makes can't be seen.
+var DefaultForbiddenFunctions = []string{
+	"pg_read_file",
+	"pg_read_binary_file",
+	"pg_ls_dir",
+	"pg_stat_file",
+	"pg_ls_logdir",
+	"pg_ls_waldir",
+	"pg_ls_tmpdir",
+	"pg_ls_archive_statusdir",
+	"lo_import",
+	"lo_export",
+	"pg_terminate_backend",
+	"pg_cancel_backend",
+	"pg_reload_conf",
+	"pg_rotate_logfile",
+	"pg_promote",
+	"pg_switch_wal",
+	"pg_create_restore_point",
+	"pg_start_backup",
+	"pg_stop_backup",
+	"pg_backup_start",
+	"pg_backup_stop",
+	"set_config",
+	"dblink",
+	"dblink_exec",
+	"dblink_connect",
+	"dblink_connect_u",
+	"dblink_send_query",
+	"query_to_xml",
+	"query_to_xmlschema",
+	"query_to_xml_and_xmlschema",
+	"cursor_to_xml",
+	"cursor_to_xmlschema",
+	"table_to_xml",
+	"table_to_xmlschema",
+	"table_to_xml_and_xmlschema",
+	"ts_stat",
+	"ts_rewrite",
+}
+
+// Policy decides which SQL the API accepts, on top of the validation done
+// by Limits. The zero value is the default profile.
+type Policy struct {
+	// Profile is the name of the profile the policy was built from
+	Profile string
+	// Statements decides which statement classes may run
+	Statements StatementPolicy
+	// ForbiddenFunctions are rejected wherever they are called. Nil means
+	// DefaultForbiddenFunctions; an empty list forbids none.
+	ForbiddenFunctions []string
+	// AllowUnfilteredWrites accepts UPDATE and DELETE without a WHERE clause
+	AllowUnfilteredWrites bool
+	// ReadOnly runs queries in READ ONLY transactions
+	ReadOnly bool
+	// Role is the PostgreSQL role queries run as, empty for the connecting
+	// role. Statements that could switch back to the connecting role are
+	// rejected, in function bodies too, and so is set_config whatever
+	// ForbiddenFunctions says. So are the roleDeniedClasses.
+	Role string
+}
+
+// roleDeniedClasses are refused while queries run as a configured role.
+// DDL and procedures could define and run code that changes the role
+// where it can't be checked, and ending the transaction ends SET LOCAL
+// ROLE.
+var roleDeniedClasses = []StatementClass{ClassDDL, ClassProcedure, ClassTransaction}
+
+// DefaultPolicy returns the policy of the default profile
+func DefaultPolicy() Policy {
+	return Policy{Profile: ProfileDefault}
+}
+
+// PolicyProfile returns the policy of a named profile
+func PolicyProfile(name string) (Policy, error) {
+	switch name {
+	case ProfileDefault, "":
+		return DefaultPolicy(), nil
+
+	case ProfileReadOnly:
+		var statements StatementPolicy
+		for _, class := range StatementClasses {
+			switch class {
+			case ClassQuery, ClassExplain, ClassShow, ClassCopy:
+				statements = statements.Allow(class)
+			default:
+				statements = statements.Deny(class)
+			}
+		}
+		return Policy{Profile: name, Statements: statements, ReadOnly: true}, nil
+
+	case ProfileNoDDL:
+		return Policy{
+			Profile:    name,
+			Statements: StatementPolicy{}.Deny(ClassDDL, ClassTruncate),
+		}, nil
+
+	case ProfilePermissive:
+		var statements StatementPolicy
+		for _, class := range StatementClasses {
+			switch class {
+			case ClassSession, ClassTransaction:
+				statements = statements.Deny(class)
+			default:
+				statements = statements.Allow(class)
+			}
+		}
+		return Policy{Profile: name, Statements: statements, AllowUnfilteredWrites: true}, nil
+	}
+
+	return Policy{}, fmt.Errorf("unknown safety profile %q (expected one of %s)", name, strings.Join(ProfileNames(), ", "))
+}
+
+// ProfileNames returns the names of the built-in profiles
+func ProfileNames() []string {
+	return []string{ProfileDefault, ProfileReadOnly, ProfileNoDDL, ProfilePermissive}
+}
+
+// Allow returns a copy of the policy that also allows the given classes
+func (p Policy) Allow(classes ...StatementClass) Policy {
+	p.Statements = p.Statements.Allow(classes...)
+	return p
+}
+
+// Deny returns a copy of the policy that rejects the given classes
+func (p Policy) Deny(classes ...StatementClass) Policy {
+	p.Statements = p.Statements.Deny(classes...)
+	return p
+}
+
+// Name returns the policy's profile name, "default" when unset
+func (p Policy) Name() string {
+	if p.Profile == "" {
+		return ProfileDefault
+	}
+	return p.Profile
+}
+
+// Check enforces the policy on every statement of sql: its statement class,
+// the WHERE rule for UPDATE and DELETE, and the forbidden functions.
+func (p Policy) Check(sql string) error {
+	statements, err := ParseStatements(sql)
+	if err != nil {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid SQL syntax",
+			capitalize(err.Error()),
+		)
+	}
+
+	forbidden := p.forbiddenFunctions()
+	for _, stmt := range statements {
+		if err := p.checkStatement(stmt, forbidden); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckClient enforces the policy on SQL from a PostgreSQL protocol
+// client. Such a client has a connection of its own, so on top of Check it
+// may control transactions, though not start READ WRITE ones under a
+// read-only policy, and COPY FROM STDIN is checked as an import. Its role
+// is set for the whole session, so transactions stay allowed under a Role.
+func (p Policy) CheckClient(sql string) error {
+	statements, err := ParseStatements(sql)
+	if err != nil {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid SQL syntax",
+			capitalize(err.Error()),
+		)
+	}
+
+	forbidden := p.forbiddenFunctions()
+	for _, stmt := range statements {
+		switch {
+		case stmt.Class == ClassTransaction:
+			if p.ReadOnly && startsReadWrite(stmt.tokens) {
+				return postgres.NewVibeError(
+					postgres.ErrorCodeStatementNotAllowed,
+					"Statement not allowed",
+					fmt.Sprintf("READ WRITE transactions are not allowed by the %s profile", p.Name()),
+				)
+			}
+		case stmt.Command == "COPY" && copiesFromStdin(stmt.tokens):
+			if err := p.CheckImport(false); err != nil {
+				return err
+			}
+		default:
+			if err := p.checkStatement(stmt, forbidden); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkStatement enforces the policy on one statement: its statement
+// class, the WHERE rule for UPDATE and DELETE, and the forbidden functions
+func (p Policy) checkStatement(stmt *ParsedStatement, forbidden map[string]bool) error {
+	if err := p.Statements.Check(stmt); err != nil {
+		return err
+	}
+	if !p.AllowUnfilteredWrites {
+		if err := checkStatementSafety(stmt); err != nil {
+			return err
+		}
+	}
+	if p.Role != "" {
+		for _, class := range roleDeniedClasses {
+			if stmt.Class == class {
+				return postgres.NewVibeError(
+					postgres.ErrorCodeStatementNotAllowed,
+					"Statement not allowed",
+					fmt.Sprintf("%s statements (%s) are not allowed while queries run as role %s", stmt.Command, stmt.Class, p.Role),
+				)
+			}
+		}
+		if changesRole(stmt) || bodyChangesRole(stmt.tokens) {
+			return postgres.NewVibeError(
+				postgres.ErrorCodeStatementNotAllowed,
+				"Statement not allowed",
+				fmt.Sprintf("Queries run as role %s and may not change it", p.Role),
+			)
+		}
+	}
+	if name := findForbiddenCall(stmt.tokens, forbidden); name != "" {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeStatementNotAllowed,
+			"Function not allowed",
+			fmt.Sprintf("Calling %s() is not allowed", name),
+		)
+	}
+	return nil
+}
+
+// changesRole reports whether a statement could change the current role
+func changesRole(stmt *ParsedStatement) bool {
+	switch stmt.Command {
+	case "SET", "RESET", "DISCARD":
+		return roleChangeAt(stmt.tokens, 0)
+	}
+	return false
+}
+
+// bodyChangesRole reports whether a string in tokens could change the
+// current role if it were run as SQL. Every string is searched, not only
+// function bodies, since functions such as query_to_xml run a string
+// argument as a query, so under a role a string literal like
+// 'SET ROLE x' is rejected even where it is only data.
+func bodyChangesRole(tokens []token) bool {
+	return searchCode(tokens, true, func(body []token) bool {
+		for i := range body {
+			if roleChangeAt(body, i) {
+				return true
+			}
+		}
+		return findCall(body, roleFunctions) != ""
+	})
+}
+
+// roleFunctions are the functions that can change the current role
+var roleFunctions = map[string]bool{"set_config": true}
+
+// roleChangeAt reports whether the command at tokens[i] could change the
+// current role: SET or RESET of ROLE or SESSION AUTHORIZATION, RESET ALL
+// or DISCARD ALL
+func roleChangeAt(tokens []token, i int) bool {
+	command := tokens[i]
+	if command.is("DISCARD") {
+		return i+1 < len(tokens) && tokens[i+1].is("ALL")
+	}
+	if !command.isAny("SET", "RESET") {
+		return false
+	}
+	for j := i + 1; j < len(tokens) && j <= i+2; j++ {
+		switch settingName(tokens[j]) {
+		case "ROLE", "AUTHORIZATION", "SESSION_AUTHORIZATION":
+			return true
+		case "ALL":
+			return command.is("RESET")
+		case "SESSION", "LOCAL":
+			continue
+		}
+		return false
+	}
+	return false
+}
+
+// settingName returns a keyword or identifier token upper-cased, without
+// the quotes of a quoted identifier
+func settingName(t token) string {
+	switch t.kind {
+	case tokenWord:
+		return strings.ToUpper(t.text)
+	case tokenIdent:
+		return strings.ToUpper(strings.ReplaceAll(strings.Trim(t.text, `"`), `""`, `"`))
+	}
+	return ""
+}
+
+// startsReadWrite reports whether a transaction statement asks for a READ
+// WRITE transaction
+func startsReadWrite(tokens []token) bool {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].is("READ") && tokens[i+1].is("WRITE") {
+			return true
+		}
+	}
+	return false
+}
+
+// copiesFromStdin reports whether a COPY statement reads from STDIN, the
+// client's data, rather than a server file or program
+func copiesFromStdin(tokens []token) bool {
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("FROM"):
+			return i+1 < len(tokens) && tokens[i+1].is("STDIN")
+		}
+	}
+	return false
+}
+
+// CheckImport enforces the policy on a bulk import, which inserts rows
+// with COPY FROM STDIN and, when create is set, may create the table
+func (p Policy) CheckImport(create bool) error {
+	if p.ReadOnly {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeStatementNotAllowed,
+			"Statement not allowed",
+			fmt.Sprintf("Imports are not allowed by the %s profile", p.Name()),
+		)
+	}
+	if err := p.Statements.Check(&ParsedStatement{Command: "COPY", Class: ClassInsert}); err != nil {
+		return err
+	}
+	if create {
+		return p.Statements.Check(&ParsedStatement{Command: "CREATE", Class: ClassDDL})
+	}
+	return nil
+}
+
+// forbiddenFunctions returns the forbidden function names as a set
+func (p Policy) forbiddenFunctions() map[string]bool {
+	names := p.ForbiddenFunctions
+	if names == nil {
+		names = DefaultForbiddenFunctions
+	}
+
+	set := make(map[string]bool, len(names)+1)
+	for _, name := range names {
+		set[strings.ToLower(name)] = true
+	}
+	if p.Role != "" {
+		// set_config('role', ...) would change the role like SET ROLE
+		for name := range roleFunctions {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// findForbiddenCall returns the name of the first forbidden function called
+// in tokens, or "". A call is a name followed by an opening parenthesis,
+// whatever schema qualifies it. Strings that may hold code, such as
+// function bodies, are searched as well.
+func findForbiddenCall(tokens []token, forbidden map[string]bool) string {
+	if len(forbidden) == 0 {
+		return ""
+	}
+
+	name := findCall(tokens, forbidden)
+	if name == "" {
+		searchCode(tokens, false, func(body []token) bool {
+			name = findCall(body, forbidden)
+			return name != ""
+		})
+	}
+	return name
+}
+
+// searchCode calls found with the tokens of each string in tokens that may
+// hold code, and of the strings within those, until found returns true.
+// Inside a body every string is searched, since procedural code may run
+// one with EXECUTE.
+func searchCode(tokens []token, inBody bool, found func(body []token) bool) bool {
+	routine := definesRoutine(tokens)
+	for i, t := range tokens {
+		if t.kind != tokenString {
+			continue
+		}
+
+		var body []token
+		switch {
+		case strings.HasPrefix(t.text, "$"):
+			body = dollarQuotedBody(t.text)
+		case inBody || (routine && (tokens[0].is("DO") || (i > 0 && tokens[i-1].is("AS")))):
+			body = quotedBody(t.text)
+		}
+		if len(body) > 0 && (found(body) || searchCode(body, true, found)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findCall returns the first forbidden function called in tokens, not
+// searching strings
+func findCall(tokens []token, forbidden map[string]bool) string {
+	for i, t := range tokens {
+		if i+1 >= len(tokens) || !tokens[i+1].isPunct("(") {
+			continue
+		}
+
+		var name string
+		switch t.kind {
+		case tokenWord:
+			name = strings.ToLower(t.text)
+		case tokenIdent:
+			name = strings.ReplaceAll(t.text[1:len(t.text)-1], `""`, `"`)
+		default:
+			continue
+		}
+		if forbidden[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// dollarQuotedBody tokenizes the contents of a dollar-quoted string. Bodies
+// that are not SQL, such as those of other procedural languages, give no
+// tokens.
+func dollarQuotedBody(text string) []token {
+	delimiter := text[:strings.IndexByte(text[1:], '$')+2]
+	tokens, err := tokenize(text[len(delimiter) : len(text)-len(delimiter)])
+	if err != nil {
+		return nil
+	}
+	return tokens
+}
+
+// definesRoutine reports whether tokens start a DO statement or a CREATE
+// FUNCTION or CREATE PROCEDURE statement, whose quoted body is code
+func definesRoutine(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	if tokens[0].is("DO") {
+		return true
+	}
+	if !tokens[0].is("CREATE") {
+		return false
+	}
+	i := 1
+	if i+1 < len(tokens) && tokens[i].is("OR") && tokens[i+1].is("REPLACE") {
+		i += 2
+	}
+	return i < len(tokens) && tokens[i].isAny("FUNCTION", "PROCEDURE")
+}
+
+// quotedBody tokenizes the contents of a quoted string, undoing its
+// escapes. Contents that are not SQL give no tokens.
+func quotedBody(text string) []token {
+	quote := strings.IndexByte(text, '\'')
+	if quote < 0 {
+		return nil
+	}
+	prefix := strings.ToUpper(text[:quote])
+	body := text[quote+1 : len(text)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\'':
+			// A doubled quote
+			i++
+		case c == '\\' && prefix == "E" && i+1 < len(body):
+			n, r := backslashEscape(body[i+1:])
+			i += n
+			b.WriteRune(r)
+			continue
+		case c == '\\' && prefix == "U&" && i+1 < len(body):
+			n, r := unicodeEscape(body[i+1:])
+			i += n
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteByte(body[i])
+	}
+
+	tokens, err := tokenize(b.String())
+	if err != nil {
+		return nil
+	}
+	return tokens
+}
+
+// backslashEscape decodes the escape after a backslash in an E-prefixed
+// string, returning the bytes it takes and the character it stands for
+func backslashEscape(s string) (int, rune) {
+	switch s[0] {
+	case 'b':
+		return 1, '\b'
+	case 'f':
+		return 1, '\f'
+	case 'n':
+		return 1, '\n'
+	case 'r':
+		return 1, '\r'
+	case 't':
+		return 1, '\t'
+	case 'x':
+		return hexEscape(s, 1, 2)
+	case 'u':
+		return hexEscape(s, 4, 4)
+	case 'U':
+		return hexEscape(s, 8, 8)
+	}
+
+	n := 0
+	for n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+		n++
+	}
+	if n == 0 {
+		return 1, rune(s[0])
+	}
+	v, _ := strconv.ParseUint(s[:n], 8, 32)
+	return n, rune(v)
+}
+
+// unicodeEscape decodes the escape after a backslash in a U&-prefixed
+// string: four hex digits, or + and six hex digits
+func unicodeEscape(s string) (int, rune) {
+	digits, skip := 4, 0
+	if s[0] == '+' {
+		digits, skip = 6, 1
+	}
+	if len(s) < skip+digits {
+		return 1, rune(s[0])
+	}
+	v, err := strconv.ParseUint(s[skip:skip+digits], 16, 32)
+	if err != nil {
+		return 1, rune(s[0])
+	}
+	return skip + digits, rune(v)
+}
+
+// hexEscape decodes up to most hex digits, and at least fewest, following
+// the first byte of s, returning the bytes taken and the character they
+// stand for
+func hexEscape(s string, fewest, most int) (int, rune) {
+	n := 1
+	for n <= most && n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) >= 0 {
+		n++
+	}
+	if n-1 < fewest {
+		return 1, rune(s[0])
+	}
+	v, _ := strconv.ParseUint(s[1:n], 16, 32)
+	return n, rune(v)
+}
+
+// ParseStatementClasses parses a list of statement class names
+func ParseStatementClasses(names []string) ([]StatementClass, error) {
+	classes := make([]StatementClass, 0, len(names))
+	for _, name := range names {
+		class := StatementClass(strings.ToLower(strings.TrimSpace(name)))
+		if classBit(class) == 0 {
+			valid := make([]string, len(StatementClasses))
+			for i, c := range StatementClasses {
+				valid[i] = string(c)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("unknown statement class %q (expected one of %s)", name, strings.Join(valid, ", "))
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
@@ -0,0 +1,364 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func checkCode(t *testing.T, policy Policy, sql string, wantCode string) {
+	t.Helper()
+	err := policy.Check(sql)
+	if wantCode == "" {
+		if err != nil {
+			t.Errorf("%s policy: Check(%q) unexpected error = %v", policy.Name(), sql, err)
+		}
+		return
+	}
+
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Errorf("%s policy: Check(%q) expected VibeError, got %v", policy.Name(), sql, err)
+		return
+	}
+	if vibeErr.Code != wantCode {
+		t.Errorf("%s policy: Check(%q) expected %s, got %s (%s)", policy.Name(), sql, wantCode, vibeErr.Code, vibeErr.Detail)
+	}
+}
+
+func TestPolicyProfile(t *testing.T) {
+	const (
+		ok         = ""
+		notAllowed = postgres.ErrorCodeStatementNotAllowed
+		unsafe     = postgres.ErrorCodeUnsafeQuery
+	)
+
+	tests := []struct {
+		sql                                   string
+		defaults, readOnly, noDDL, permissive string
+	}{
+		{"SELECT * FROM users", ok, ok, ok, ok},
+		{"EXPLAIN SELECT 1", ok, ok, ok, ok},
+		{"SHOW server_version", ok, ok, ok, ok},
+		{"COPY users TO STDOUT", ok, ok, ok, ok},
+		{"INSERT INTO users (name) VALUES ('a')", ok, notAllowed, ok, ok},
+		{"UPDATE users SET name = 'a' WHERE id = 1", ok, notAllowed, ok, ok},
+		{"DELETE FROM users", unsafe, notAllowed, unsafe, ok},
+		{"WITH d AS (DELETE FROM users WHERE id = 1) SELECT 1", ok, notAllowed, ok, ok},
+		{"COPY (DELETE FROM users WHERE id = 1 RETURNING *) TO STDOUT", ok, notAllowed, ok, ok},
+		{"SELECT * FROM users WHERE id IN (SELECT id FROM locks FOR UPDATE)", ok, ok, ok, ok},
+		{"EXPLAIN ANALYZE DELETE FROM users WHERE id = 1", ok, notAllowed, ok, ok},
+		{"DROP TABLE users", ok, notAllowed, notAllowed, ok},
+		{"TRUNCATE users", ok, notAllowed, notAllowed, ok},
+		{"VACUUM users", notAllowed, notAllowed, notAllowed, ok},
+		{"GRANT SELECT ON users TO bob", notAllowed, notAllowed, notAllowed, ok},
+		{"SET search_path = other", notAllowed, notAllowed, notAllowed, notAllowed},
+		{"SELECT 1; COMMIT", notAllowed, notAllowed, notAllowed, notAllowed},
+	}
+
+	profiles := make(map[string]Policy)
+	for _, name := range ProfileNames() {
+		policy, err := PolicyProfile(name)
+		if err != nil {
+			t.Fatalf("PolicyProfile(%q) unexpected error = %v", name, err)
+		}
+		if policy.Name() != name {
+			t.Errorf("Expected profile name %s, got %s", name, policy.Name())
+		}
+		profiles[name] = policy
+	}
+
+	for _, tt := range tests {
+		checkCode(t, profiles[ProfileDefault], tt.sql, tt.defaults)
+		checkCode(t, profiles[ProfileReadOnly], tt.sql, tt.readOnly)
+		checkCode(t, profiles[ProfileNoDDL], tt.sql, tt.noDDL)
+		checkCode(t, profiles[ProfilePermissive], tt.sql, tt.permissive)
+	}
+
+	if !profiles[ProfileReadOnly].ReadOnly {
+		t.Error("Expected the readonly profile to run queries read-only")
+	}
+	for _, name := range []string{ProfileDefault, ProfileNoDDL, ProfilePermissive} {
+		if profiles[name].ReadOnly {
+			t.Errorf("Expected the %s profile not to be read-only", name)
+		}
+	}
+}
+
+func TestPolicyProfile_Unknown(t *testing.T) {
+	_, err := PolicyProfile("yolo")
+	if err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("Expected an error listing the profiles, got %v", err)
+	}
+
+	if policy, err := PolicyProfile(""); err != nil || policy.Name() != ProfileDefault {
+		t.Errorf("Expected an empty name to select the default profile, got %v, %v", policy.Name(), err)
+	}
+}
+
+func TestPolicy_ZeroValueIsDefault(t *testing.T) {
+	var policy Policy
+	checkCode(t, policy, "DELETE FROM users", postgres.ErrorCodeUnsafeQuery)
+	checkCode(t, policy, "BEGIN", postgres.ErrorCodeStatementNotAllowed)
+	checkCode(t, policy, "SELECT pg_read_file('/etc/passwd')", postgres.ErrorCodeStatementNotAllowed)
+	if policy.Name() != ProfileDefault {
+		t.Errorf("Expected zero policy name %s, got %s", ProfileDefault, policy.Name())
+	}
+}
+
+func TestPolicy_AllowDeny(t *testing.T) {
+	policy := DefaultPolicy().Deny(ClassDDL).Allow(ClassMaintenance)
+
+	checkCode(t, policy, "DROP TABLE users", postgres.ErrorCodeStatementNotAllowed)
+	checkCode(t, policy, "VACUUM users", "")
+	checkCode(t, DefaultPolicy(), "DROP TABLE users", "")
+}
+
+func TestPolicy_ForbiddenFunctions(t *testing.T) {
+	policy := DefaultPolicy()
+
+	forbidden := []string{
+		"SELECT pg_read_file('/etc/passwd')",
+		"SELECT PG_READ_FILE('/etc/passwd')",
+		"SELECT pg_catalog.pg_read_file('/etc/passwd')",
+		`SELECT "pg_read_file"('/etc/passwd')`,
+		"SELECT pg_terminate_backend /* pid */ (123)",
+		"SELECT * FROM users WHERE id IN (SELECT lo_import('/etc/passwd'))",
+		"SELECT 1; SELECT set_config('role', 'postgres', false)",
+		"CREATE FUNCTION f() RETURNS text AS $$ SELECT pg_read_file('/etc/passwd') $$ LANGUAGE sql",
+		"CREATE FUNCTION f() RETURNS text LANGUAGE sql AS 'SELECT pg_read_file(''/etc/passwd'')'",
+		"CREATE OR REPLACE PROCEDURE p() LANGUAGE sql AS E'SELECT\\npg_read_file(\\'x\\')'",
+		"CREATE FUNCTION f() RETURNS text LANGUAGE sql AS E'SELECT \\x70g_read_file(''x'')'",
+		"CREATE FUNCTION f() RETURNS text LANGUAGE sql AS U&'SELECT \\0070g_read_file(''x'')'",
+		"DO 'BEGIN PERFORM pg_read_file(''x''); END'",
+		"DO LANGUAGE plpgsql $$ BEGIN EXECUTE 'SELECT pg_read_file(''x'')'; END $$",
+		"UPDATE users SET name = pg_read_file('x') WHERE id = 1",
+		"SELECT query_to_xml('SELECT pg_read_file(''/etc/passwd'')', true, false, '')",
+		"SELECT pg_catalog.query_to_xml_and_xmlschema('SELECT 1', true, false, '')",
+		"SELECT ts_stat('SELECT to_tsvector(pg_read_file(''x''))')",
+	}
+	for _, sql := range forbidden {
+		checkCode(t, policy, sql, postgres.ErrorCodeStatementNotAllowed)
+	}
+
+	allowed := []string{
+		"SELECT 'pg_read_file(1)'",
+		"INSERT INTO notes (body) VALUES ('SELECT pg_read_file(''x'')')",
+		`SELECT "PG_READ_FILE"('x')`,
+		"SELECT pg_read_file FROM files",
+		"SELECT -- pg_read_file(\n 1",
+		"SELECT lower(name) FROM users",
+	}
+	for _, sql := range allowed {
+		checkCode(t, policy, sql, "")
+	}
+
+	err := policy.Check("SELECT pg_terminate_backend(1)")
+	if vibeErr, ok := err.(*postgres.VibeError); !ok || !strings.Contains(vibeErr.Detail, "pg_terminate_backend()") {
+		t.Errorf("Expected the function to be named in the error, got %v", err)
+	}
+}
+
+func TestPolicy_CustomForbiddenFunctions(t *testing.T) {
+	custom := Policy{ForbiddenFunctions: []string{"Now"}}
+	checkCode(t, custom, "SELECT now()", postgres.ErrorCodeStatementNotAllowed)
+	checkCode(t, custom, "SELECT pg_read_file('x')", "")
+
+	none := Policy{ForbiddenFunctions: []string{}}
+	checkCode(t, none, "SELECT pg_read_file('x')", "")
+}
+
+func TestPolicy_CheckClient(t *testing.T) {
+	readOnly, _ := PolicyProfile(ProfileReadOnly)
+
+	tests := []struct {
+		policy Policy
+		sql    string
+		want   string
+	}{
+		{DefaultPolicy(), "BEGIN", ""},
+		{DefaultPolicy(), "BEGIN; UPDATE users SET name = 'a' WHERE id = 1; COMMIT", ""},
+		{DefaultPolicy(), "SAVEPOINT s1; ROLLBACK TO SAVEPOINT s1", ""},
+		{DefaultPolicy(), "BEGIN READ WRITE", ""},
+		{DefaultPolicy(), "COPY users (name) FROM STDIN", ""},
+		{DefaultPolicy(), "COPY users FROM '/etc/passwd'", postgres.ErrorCodeStatementNotAllowed},
+		{DefaultPolicy(), "DELETE FROM users", postgres.ErrorCodeUnsafeQuery},
+		{DefaultPolicy(), "SET ROLE postgres", postgres.ErrorCodeStatementNotAllowed},
+		{DefaultPolicy(), "SELECT pg_read_file('/etc/passwd')", postgres.ErrorCodeStatementNotAllowed},
+		{readOnly, "BEGIN", ""},
+		{readOnly, "START TRANSACTION ISOLATION LEVEL SERIALIZABLE, READ WRITE", postgres.ErrorCodeStatementNotAllowed},
+		{readOnly, "COPY users FROM STDIN", postgres.ErrorCodeStatementNotAllowed},
+		{readOnly, "COPY users TO STDOUT", ""},
+		{readOnly, "INSERT INTO users (name) VALUES ('a')", postgres.ErrorCodeStatementNotAllowed},
+	}
+
+	for _, tt := range tests {
+		err := tt.policy.CheckClient(tt.sql)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s policy: CheckClient(%q) unexpected error = %v", tt.policy.Name(), tt.sql, err)
+			}
+			continue
+		}
+		if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != tt.want {
+			t.Errorf("%s policy: CheckClient(%q) = %v, want %s", tt.policy.Name(), tt.sql, err, tt.want)
+		}
+	}
+}
+
+func TestBodyChangesRole(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"CREATE FUNCTION f() RETURNS void LANGUAGE sql AS $$ RESET ROLE $$", true},
+		{"CREATE FUNCTION f() RETURNS void LANGUAGE sql AS 'SET ROLE vibe_app'", true},
+		{"CREATE OR REPLACE FUNCTION f() RETURNS void LANGUAGE sql AS E'SET\\tSESSION AUTHORIZATION vibe_app'", true},
+		{"DO 'BEGIN SET LOCAL ROLE vibe_app; END'", true},
+		{"DO $fn$ BEGIN EXECUTE 'RESET ALL'; END $fn$", true},
+		{"CREATE FUNCTION f() RETURNS void LANGUAGE sql AS $$ DISCARD ALL $$", true},
+		{"CREATE FUNCTION f() RETURNS void LANGUAGE sql AS $$ SET search_path = app $$", false},
+		{"SELECT 'SET ROLE vibe_app'", true},
+		{"SELECT query_to_xml('SELECT set_config(''role'', ''vibe_app'', true)', true, false, '')", true},
+		{"SELECT x FROM f(E'RESET\\tROLE')", true},
+		{"SELECT 'role', set_config FROM settings", false},
+		{"SELECT 'SET search_path = app'", false},
+		{"SET ROLE vibe_app", false},
+	}
+	for _, tt := range tests {
+		tokens, err := tokenize(tt.sql)
+		if err != nil {
+			t.Fatalf("tokenize(%q) error = %v", tt.sql, err)
+		}
+		if got := bodyChangesRole(tokens); got != tt.want {
+			t.Errorf("bodyChangesRole(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Role(t *testing.T) {
+	// Session statements are allowed so only the role rule rejects them
+	policy := DefaultPolicy().Allow(ClassSession)
+	policy.Role = "analyst"
+
+	tests := []struct {
+		sql     string
+		allowed bool
+	}{
+		{"SELECT * FROM users", true},
+		{"SET search_path = app", true},
+		{"SET LOCAL statement_timeout = '1s'", true},
+		{"RESET search_path", true},
+		{"SET ROLE vibe_app", false},
+		{"set local role vibe_app", false},
+		{"SET SESSION ROLE vibe_app", false},
+		{`SET "role" = 'vibe_app'`, false},
+		{"SET SESSION AUTHORIZATION postgres", false},
+		{"SET session_authorization TO postgres", false},
+		{"RESET ROLE", false},
+		{"RESET ALL", false},
+		{"DISCARD ALL", false},
+		{"SELECT set_config('role', 'vibe_app', true)", false},
+		// Strings that functions such as query_to_xml could run as SQL
+		{"SELECT query_to_xml('SELECT set_config(''role'', ''vibe_app'', true)', true, false, '')", false},
+		{"SELECT my_exec('RESET ROLE')", false},
+		// Code and transaction control could leave the role behind
+		{"CREATE TABLE notes (id int)", false},
+		{"CREATE FUNCTION f() RETURNS text LANGUAGE sql AS 'RESET ROLE; SELECT 1'", false},
+		{"DO $$ BEGIN PERFORM 1; END $$", false},
+		{"CALL refresh()", false},
+		{"COMMIT", false},
+		{"SELECT 1; COMMIT; SELECT 2", false},
+	}
+
+	for _, tt := range tests {
+		err := policy.Check(tt.sql)
+		if tt.allowed && err != nil {
+			t.Errorf("Check(%q) unexpected error = %v", tt.sql, err)
+		}
+		if !tt.allowed {
+			if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeStatementNotAllowed {
+				t.Errorf("Check(%q) = %v, want %s", tt.sql, err, postgres.ErrorCodeStatementNotAllowed)
+			}
+		}
+	}
+
+	// Without a role, set_config follows ForbiddenFunctions
+	policy.Role = ""
+	policy.ForbiddenFunctions = []string{}
+	if err := policy.Check("SELECT set_config('role', 'vibe_app', true)"); err != nil {
+		t.Errorf("Expected set_config to be allowed without a role, got %v", err)
+	}
+}
+
+func TestParseStatementClasses(t *testing.T) {
+	classes, err := ParseStatementClasses([]string{"DDL", " truncate "})
+	if err != nil {
+		t.Fatalf("Unexpected error = %v", err)
+	}
+	if len(classes) != 2 || classes[0] != ClassDDL || classes[1] != ClassTruncate {
+		t.Errorf("Unexpected classes: %v", classes)
+	}
+
+	if _, err := ParseStatementClasses([]string{"drop"}); err == nil || !strings.Contains(err.Error(), "ddl") {
+		t.Errorf("Expected an error listing the classes, got %v", err)
+	}
+}
+
+func TestExecutor_ReadOnly(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	opts := Options{ReadOnly: true}
+	if _, err := executor.ExecuteWithOptions("SELECT 1", nil, opts); err != nil {
+		t.Fatalf("Expected read-only SELECT to succeed, got %v", err)
+	}
+
+	_, err := executor.ExecuteWithOptions("CREATE TEMP TABLE read_only_check (id int)", nil, opts)
+	if err == nil {
+		t.Fatal("Expected PostgreSQL to reject a write in a read-only transaction")
+	}
+
+	_, err = executor.ExecuteTransaction([]Statement{{SQL: "CREATE TEMP TABLE read_only_check (id int)"}}, opts)
+	if err == nil {
+		t.Fatal("Expected PostgreSQL to reject a write in a read-only transaction")
+	}
+}
+
+func TestExecutor_Role(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`DO $$ BEGIN CREATE ROLE vibe_test_reader NOLOGIN; EXCEPTION WHEN duplicate_object THEN NULL; END $$`); err != nil {
+		t.Skipf("Skipping test: cannot create a role: %v", err)
+	}
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	currentUser := func(opts Options) string {
+		t.Helper()
+		result, err := executor.ExecuteWithOptions("SELECT current_user AS role", nil, opts)
+		if err != nil {
+			t.Fatalf("Query as %q failed: %v", opts.Role, err)
+		}
+		return fmt.Sprint(result.Rows[0]["role"])
+	}
+
+	if got := currentUser(Options{Role: "vibe_test_reader"}); got != "vibe_test_reader" {
+		t.Errorf("current_user = %s, want vibe_test_reader", got)
+	}
+	// The role ends with the query's transaction
+	if got := currentUser(Options{}); got == "vibe_test_reader" {
+		t.Error("Expected the pooled connection to be back to its own role")
+	}
+
+	if _, err := executor.ExecuteWithOptions("SELECT 1", nil, Options{Role: "vibe_test_missing"}); err == nil {
+		t.Error("Expected an error for a role that doesn't exist")
+	}
+}
@@ -0,0 +1,222 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// importRecord is one record of import data
+type importRecord struct {
+	// fields holds the record's values by source field name: strings,
+	// json.Number, bool, nil, or decoded JSON objects and arrays
+	fields map[string]interface{}
+	// keys are the field names in input order
+	keys []string
+	// raw is the record's JSON text, nil for CSV records
+	raw json.RawMessage
+}
+
+// recordReader reads the records of import data one at a time
+type recordReader interface {
+	// next returns the next record, a *rejectedRecord error for a record
+	// that cannot be loaded, or io.EOF after the last record
+	next() (*importRecord, error)
+}
+
+// rejectedRecord reports a malformed record that is skipped
+type rejectedRecord struct {
+	reason string
+}
+
+func (e *rejectedRecord) Error() string {
+	return e.reason
+}
+
+// newRecordReader returns a reader for import data in the given format
+func newRecordReader(r io.Reader, opts ImportOptions) (recordReader, error) {
+	switch opts.Format {
+	case ImportCSV:
+		return newCSVRecords(r, opts)
+	case ImportNDJSON:
+		return &ndjsonRecords{r: bufio.NewReader(r)}, nil
+	case ImportJSON:
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		return &jsonArrayRecords{d: decoder}, nil
+	}
+	return nil, invalidImportError(fmt.Sprintf("Unknown import format %q (expected csv, ndjson or json)", opts.Format))
+}
+
+// csvRecords reads CSV rows, named by the header row or by the import's
+// columns. Empty fields are NULL.
+type csvRecords struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVRecords(r io.Reader, opts ImportOptions) (*csvRecords, error) {
+	records := &csvRecords{r: csv.NewReader(r)}
+
+	if opts.NoHeader {
+		if len(opts.Columns) == 0 {
+			return nil, invalidImportError("CSV data without a header needs the column names")
+		}
+		records.header = opts.Columns
+		records.r.FieldsPerRecord = len(opts.Columns)
+		return records, nil
+	}
+
+	header, err := records.r.Read()
+	if err == io.EOF {
+		return records, nil
+	}
+	if err != nil {
+		return nil, invalidImportError(fmt.Sprintf("Invalid CSV header: %v", err))
+	}
+
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+			header[i] = name
+		}
+		if seen[name] {
+			return nil, invalidImportError(fmt.Sprintf("CSV header names %q twice", name))
+		}
+		seen[name] = true
+	}
+	records.header = header
+	return records, nil
+}
+
+func (c *csvRecords) next() (*importRecord, error) {
+	values, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &rejectedRecord{reason: capitalize(parseErr.Err.Error())}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := &importRecord{
+		fields: make(map[string]interface{}, len(c.header)),
+		keys:   c.header,
+	}
+	for i, name := range c.header {
+		if values[i] == "" {
+			record.fields[name] = nil
+		} else {
+			record.fields[name] = values[i]
+		}
+	}
+	return record, nil
+}
+
+// ndjsonRecords reads one JSON object per line. Blank lines are skipped.
+type ndjsonRecords struct {
+	r *bufio.Reader
+}
+
+func (n *ndjsonRecords) next() (*importRecord, error) {
+	for {
+		line, err := n.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+		return jsonRecord(line)
+	}
+}
+
+// jsonArrayRecords reads the objects of a JSON array one at a time, so the
+// array is never held in memory
+type jsonArrayRecords struct {
+	d       *json.Decoder
+	started bool
+}
+
+func (j *jsonArrayRecords) next() (*importRecord, error) {
+	if !j.started {
+		tok, err := j.d.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil || tok != json.Delim('[') {
+			return nil, invalidImportError("JSON import data must be an array of objects")
+		}
+		j.started = true
+	}
+
+	if !j.d.More() {
+		if _, err := j.d.Token(); err != nil {
+			return nil, invalidImportError(fmt.Sprintf("Invalid JSON: %v", err))
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := j.d.Decode(&raw); err != nil {
+		return nil, invalidImportError(fmt.Sprintf("Invalid JSON: %v", err))
+	}
+	return jsonRecord(raw)
+}
+
+// jsonRecord decodes a JSON object, keeping its keys in input order
+func jsonRecord(raw []byte) (*importRecord, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, &rejectedRecord{reason: fmt.Sprintf("Invalid JSON: %v", err)}
+	}
+	if tok != json.Delim('{') {
+		return nil, &rejectedRecord{reason: "Record is not a JSON object"}
+	}
+
+	record := &importRecord{
+		fields: make(map[string]interface{}),
+		raw:    raw,
+	}
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, &rejectedRecord{reason: fmt.Sprintf("Invalid JSON: %v", err)}
+		}
+		key := tok.(string)
+
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, &rejectedRecord{reason: fmt.Sprintf("Invalid JSON: %v", err)}
+		}
+		if _, ok := record.fields[key]; !ok {
+			record.keys = append(record.keys, key)
+		}
+		record.fields[key] = value
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, &rejectedRecord{reason: fmt.Sprintf("Invalid JSON: %v", err)}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, &rejectedRecord{reason: "Unexpected data after the JSON object"}
+	}
+	return record, nil
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll reads every record, returning the loadable ones and the reasons
+// of the rejected ones
+func readAll(t *testing.T, data string, opts ImportOptions) ([]*importRecord, []string) {
+	t.Helper()
+	reader, err := newRecordReader(strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatalf("newRecordReader unexpected error = %v", err)
+	}
+
+	var records []*importRecord
+	var rejected []string
+	for {
+		record, err := reader.next()
+		if err == io.EOF {
+			return records, rejected
+		}
+		if rejection, ok := err.(*rejectedRecord); ok {
+			rejected = append(rejected, rejection.reason)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("next unexpected error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestCSVRecords(t *testing.T) {
+	data := "\ufeffid,name\n1,Alice\n2,\"Bob, Jr.\"\n3\n4,\n"
+	records, rejected := readAll(t, data, ImportOptions{Format: ImportCSV})
+
+	if len(records) != 3 || len(rejected) != 1 {
+		t.Fatalf("Expected 3 records and 1 rejection, got %d and %v", len(records), rejected)
+	}
+	if !reflect.DeepEqual(records[0].keys, []string{"id", "name"}) {
+		t.Errorf("Expected the header to name the fields, got %v", records[0].keys)
+	}
+	if records[1].fields["name"] != "Bob, Jr." {
+		t.Errorf("Expected a quoted field, got %v", records[1].fields["name"])
+	}
+	if records[2].fields["name"] != nil {
+		t.Errorf("Expected an empty field to be NULL, got %v", records[2].fields["name"])
+	}
+	if !strings.Contains(rejected[0], "number of fields") {
+		t.Errorf("Expected a field count rejection, got %q", rejected[0])
+	}
+}
+
+func TestCSVRecords_NoHeader(t *testing.T) {
+	records, _ := readAll(t, "1,Alice\n", ImportOptions{Format: ImportCSV, NoHeader: true, Columns: []string{"id", "name"}})
+	if len(records) != 1 || records[0].fields["id"] != "1" || records[0].fields["name"] != "Alice" {
+		t.Errorf("Expected the columns to name the fields, got %+v", records)
+	}
+
+	if _, err := newRecordReader(strings.NewReader("1,Alice\n"), ImportOptions{Format: ImportCSV, NoHeader: true}); err == nil {
+		t.Error("Expected CSV without a header or columns to be rejected")
+	}
+	if _, err := newRecordReader(strings.NewReader("a,a\n"), ImportOptions{Format: ImportCSV}); err == nil {
+		t.Error("Expected a duplicate header name to be rejected")
+	}
+}
+
+func TestNDJSONRecords(t *testing.T) {
+	data := `{"id": 1, "name": "Alice", "tags": ["a"]}` + "\n\n" +
+		`not json` + "\n" +
+		`[1, 2]` + "\n" +
+		`{"name": "Bob", "id": 2}` // no trailing newline
+	records, rejected := readAll(t, data, ImportOptions{Format: ImportNDJSON})
+
+	if len(records) != 2 || len(rejected) != 2 {
+		t.Fatalf("Expected 2 records and 2 rejections, got %d and %v", len(records), rejected)
+	}
+	if records[0].fields["id"] != json.Number("1") {
+		t.Errorf("Expected numbers to keep their text, got %#v", records[0].fields["id"])
+	}
+	if !reflect.DeepEqual(records[1].keys, []string{"name", "id"}) {
+		t.Errorf("Expected keys in input order, got %v", records[1].keys)
+	}
+	if string(records[1].raw) != `{"name": "Bob", "id": 2}` {
+		t.Errorf("Expected the raw record, got %s", records[1].raw)
+	}
+}
+
+func TestJSONArrayRecords(t *testing.T) {
+	records, rejected := readAll(t, `[{"id": 1}, "x", {"id": 2}]`, ImportOptions{Format: ImportJSON})
+	if len(records) != 2 || len(rejected) != 1 {
+		t.Fatalf("Expected 2 records and 1 rejection, got %d and %v", len(records), rejected)
+	}
+
+	reader, _ := newRecordReader(strings.NewReader(`{"id": 1}`), ImportOptions{Format: ImportJSON})
+	if _, err := reader.next(); err == nil || err == io.EOF {
+		t.Errorf("Expected an object instead of an array to fail, got %v", err)
+	}
+
+	reader, _ = newRecordReader(strings.NewReader(`[{"id": 1}, {"id": `), ImportOptions{Format: ImportJSON})
+	reader.next()
+	if _, err := reader.next(); err == nil || err == io.EOF {
+		t.Errorf("Expected a truncated array to fail, got %v", err)
+	}
+}
@@ -1,83 +1,138 @@
 package query
 
 import (
-	"regexp"
-	"strings"
-
 	"github.com/vibesql/vibe/internal/postgres"
 )
 
-var (
-	whereClausePattern = regexp.MustCompile(`\bWHERE\b`)
-	singleLineComment  = regexp.MustCompile(`--[^\n]*`)
-	multiLineComment   = regexp.MustCompile(`/\*[\s\S]*?\*/`)
-	stringLiteral      = regexp.MustCompile(`'(?:[^']|'')*'`)
-)
-
-// CheckSafety enforces safety rules on SQL queries
+// CheckSafety enforces the default safety policy on SQL queries. Every
+// UPDATE and DELETE must have a WHERE clause of its own, including those in
+// other statements of the same request, in data-modifying CTEs
+// (WITH d AS (DELETE ...) ...), in COPY (...) TO STDOUT, and in statements
+// run by EXPLAIN ANALYZE. See Policy for configurable rules.
 func CheckSafety(sql string) error {
-	trimmed := strings.TrimSpace(sql)
-	upperSQL := strings.ToUpper(trimmed)
+	return DefaultPolicy().Check(sql)
+}
+
+func checkStatementSafety(stmt *ParsedStatement) error {
+	if stmt.Class == ClassExplain {
+		// Plain EXPLAIN only plans the statement; EXPLAIN ANALYZE runs it
+		if stmt.Explained == nil || !stmt.analyzes() {
+			return nil
+		}
+		return checkStatementSafety(stmt.Explained)
+	}
+
+	for _, span := range modifyingStatements(stmt.tokens) {
+		if hasTopLevelWhere(stmt.tokens[span.start:span.end]) {
+			continue
+		}
 
-	// Check UPDATE without WHERE
-	if strings.HasPrefix(upperSQL, "UPDATE") {
-		if !hasWhereClause(trimmed) {
+		if stmt.tokens[span.start].is("UPDATE") {
 			return postgres.NewVibeError(
 				postgres.ErrorCodeUnsafeQuery,
 				"Unsafe query: UPDATE without WHERE clause",
 				"UPDATE queries must include a WHERE clause. Use 'WHERE 1=1' to update all rows explicitly",
 			)
 		}
+		return postgres.NewVibeError(
+			postgres.ErrorCodeUnsafeQuery,
+			"Unsafe query: DELETE without WHERE clause",
+			"DELETE queries must include a WHERE clause. Use 'WHERE 1=1' to delete all rows explicitly",
+		)
 	}
 
-	// Check DELETE without WHERE
-	if strings.HasPrefix(upperSQL, "DELETE") {
-		if !hasWhereClause(trimmed) {
-			return postgres.NewVibeError(
-				postgres.ErrorCodeUnsafeQuery,
-				"Unsafe query: DELETE without WHERE clause",
-				"DELETE queries must include a WHERE clause. Use 'WHERE 1=1' to delete all rows explicitly",
-			)
+	return nil
+}
+
+// tokenSpan is a range of token indexes, end exclusive
+type tokenSpan struct {
+	start int
+	end   int
+}
+
+// modifyingStatements finds every UPDATE and DELETE statement within a
+// statement's tokens. A nested statement can only start right after an
+// opening parenthesis (a CTE body or COPY's query), so UPDATE and DELETE
+// in other positions, such as FOR UPDATE, ON DELETE CASCADE, ON CONFLICT
+// DO UPDATE or GRANT UPDATE, are not statements. Each span runs from the
+// command keyword to the end of its enclosing parentheses.
+func modifyingStatements(tokens []token) []tokenSpan {
+	var spans []tokenSpan
+
+	// Leading parentheses wrap the top-level statement itself
+	first := 0
+	for first < len(tokens) && tokens[first].isPunct("(") {
+		first++
+	}
+
+	for i := first; i < len(tokens); i++ {
+		if i != first && !tokens[i-1].isPunct("(") {
+			continue
+		}
+
+		end := len(tokens)
+		if i != first {
+			if close := closingParen(tokens, i-1); close >= 0 {
+				end = close
+			}
+		}
+
+		command := i
+		if tokens[i].is("WITH") {
+			main := mainStatementIndex(tokens[:end], i+1)
+			if main < 0 {
+				continue
+			}
+			command = main
+		}
+
+		if startsModification(tokens[command:end]) {
+			spans = append(spans, tokenSpan{start: command, end: end})
 		}
 	}
 
-	return nil
+	return spans
 }
 
-// hasWhereClause checks if a SQL query contains a WHERE clause
-// It removes comments and string literals to avoid false positives
-func hasWhereClause(sql string) bool {
-	// Remove SQL comments before checking
-	sql = removeComments(sql)
-	
-	// Remove string literals to avoid false positives
-	// e.g., UPDATE users SET desc = 'WHERE is my data' should not match
-	sql = removeStringLiterals(sql)
-	
-	// Convert to uppercase for case-insensitive matching
-	upperSQL := strings.ToUpper(sql)
-	
-	// Check for WHERE keyword
-	// Using word boundary matching to avoid false positives like "SOMEWHERE"
-	return whereClausePattern.MatchString(upperSQL)
+// startsModification reports whether tokens start an UPDATE or DELETE
+// statement rather than, say, a column that happens to be named "delete"
+func startsModification(tokens []token) bool {
+	if len(tokens) < 2 {
+		return false
+	}
+	switch {
+	case tokens[0].is("DELETE"):
+		return tokens[1].is("FROM")
+	case tokens[0].is("UPDATE"):
+		return tokens[1].kind == tokenWord || tokens[1].kind == tokenIdent
+	}
+	return false
 }
 
-// removeComments removes SQL comments from the query
-// Note: Nested /* */ comments are not fully supported
-// (matches PostgreSQL default behavior)
-func removeComments(sql string) string {
-	// Remove single-line comments (-- comment)
-	sql = singleLineComment.ReplaceAllString(sql, "")
-	
-	// Remove multi-line comments (/* comment */)
-	sql = multiLineComment.ReplaceAllString(sql, "")
-	
-	return sql
+// hasTopLevelWhere reports whether tokens contain a WHERE keyword outside
+// any parentheses, i.e. a WHERE clause of the statement itself rather than
+// of a subquery
+func hasTopLevelWhere(tokens []token) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("WHERE"):
+			return true
+		}
+	}
+	return false
 }
 
-// removeStringLiterals removes SQL string literals from the query
-// This prevents false positives when WHERE appears inside strings
-// Handles PostgreSQL string escaping: 'can''t' (doubled single quotes)
-func removeStringLiterals(sql string) string {
-	return stringLiteral.ReplaceAllString(sql, "''")
+// hasWhereClause checks if a SQL query has a top-level WHERE clause.
+// Comments, string literals, quoted identifiers and subqueries are ignored.
+func hasWhereClause(sql string) bool {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return false
+	}
+	return hasTopLevelWhere(tokens)
 }
@@ -222,6 +222,18 @@ func TestCheckSafety_CommentsAndWhitespace(t *testing.T) {
 			sql:     "DELETE FROM users WHERE 1=1 -- delete all",
 			wantErr: false,
 		},
+		{
+			name:    "DELETE without WHERE after leading comment",
+			sql:     "/* cleanup */ DELETE FROM users",
+			wantErr: true,
+			errCode: postgres.ErrorCodeUnsafeQuery,
+		},
+		{
+			name:    "UPDATE without WHERE after leading line comment",
+			sql:     "-- reset\nUPDATE users SET name = 'x'",
+			wantErr: true,
+			errCode: postgres.ErrorCodeUnsafeQuery,
+		},
 	}
 
 	for _, tt := range tests {
@@ -375,99 +387,92 @@ func TestHasWhereClause(t *testing.T) {
 	}
 }
 
-func TestRemoveComments(t *testing.T) {
-	tests := []struct {
-		name string
-		sql  string
-		want string
-	}{
-		{
-			name: "single-line comment",
-			sql:  "SELECT * FROM users -- this is a comment",
-			want: "SELECT * FROM users ",
-		},
-		{
-			name: "multi-line comment",
-			sql:  "SELECT * FROM users /* this is a comment */",
-			want: "SELECT * FROM users ",
-		},
-		{
-			name: "multiple single-line comments",
-			sql:  "SELECT * FROM users -- comment 1\nWHERE id = 1 -- comment 2",
-			want: "SELECT * FROM users \nWHERE id = 1 ",
-		},
-		{
-			name: "nested comments",
-			sql:  "SELECT * FROM users /* outer /* inner */ comment */",
-			want: "SELECT * FROM users  comment */",
-		},
-		{
-			name: "no comments",
-			sql:  "SELECT * FROM users WHERE id = 1",
-			want: "SELECT * FROM users WHERE id = 1",
-		},
+// TestCheckSafety_BypassCorpus collects statements that slipped past the
+// earlier prefix-and-regex safety check. Each must be rejected.
+func TestCheckSafety_BypassCorpus(t *testing.T) {
+	corpus := []string{
+		// Data-modifying CTEs
+		"WITH x AS (SELECT 1) DELETE FROM users",
+		"WITH deleted AS (DELETE FROM users RETURNING *) SELECT * FROM deleted",
+		"WITH deleted AS (DELETE FROM users RETURNING *) SELECT * FROM deleted WHERE id = 1",
+		"WITH u AS (UPDATE users SET active = false RETURNING id) INSERT INTO audit SELECT id FROM u",
+		"WITH a AS (SELECT 1), b AS MATERIALIZED (DELETE FROM users RETURNING id) SELECT 1",
+		"WITH outer_cte AS (WITH inner_cte AS (DELETE FROM users RETURNING id) SELECT id FROM inner_cte) SELECT * FROM outer_cte",
+		"WITH recent AS (SELECT id FROM users WHERE created > now()) DELETE FROM users",
+
+		// Multiple statements
+		"DELETE FROM users; SELECT 1 WHERE true",
+		"SELECT 1; UPDATE users SET admin = true",
+		"SELECT 'a;b'; DELETE FROM users",
+
+		// WHERE that doesn't belong to the statement
+		"UPDATE users SET name = (SELECT name FROM admins WHERE id = 1)",
+		"DELETE FROM users USING (SELECT id FROM banned WHERE true) b",
+		"DELETE FROM users RETURNING (SELECT 1 WHERE true)",
+		"UPDATE users SET note = $$ WHERE $$",
+		"UPDATE users SET note = $tag$ it's WHERE $tag$",
+		"UPDATE users SET note = E'\\' WHERE'",
+		`UPDATE users SET "where" = 1`,
+		"UPDATE users SET note = 'x' /* outer /* nested */ WHERE id = 1 */",
+
+		// Leading comments and parentheses
+		"/* maintenance */ DELETE FROM users",
+		"-- nightly reset\nUPDATE users SET visits = 0",
+
+		// Statements run through other commands
+		"EXPLAIN ANALYZE DELETE FROM users",
+		"EXPLAIN (ANALYZE, BUFFERS) UPDATE users SET active = false",
+		"EXPLAIN (FORMAT JSON, ANALYZE true) DELETE FROM users",
+		"EXPLAIN (ANALYZE false, ANALYZE true) DELETE FROM users",
+		"EXPLAIN (ANALYZE off, ANALYZE) UPDATE users SET a = 1",
+		"COPY (DELETE FROM users RETURNING *) TO STDOUT",
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := removeComments(tt.sql)
-			if got != tt.want {
-				t.Errorf("removeComments() = %q, want %q", got, tt.want)
-			}
-		})
+	for _, sql := range corpus {
+		err := CheckSafety(sql)
+		vibeErr, ok := err.(*postgres.VibeError)
+		if !ok {
+			t.Errorf("Expected %q to be rejected, got %v", sql, err)
+			continue
+		}
+		if vibeErr.Code != postgres.ErrorCodeUnsafeQuery {
+			t.Errorf("Expected UNSAFE_QUERY for %q, got %s", sql, vibeErr.Code)
+		}
 	}
 }
 
-func TestRemoveStringLiterals(t *testing.T) {
-	tests := []struct {
-		name string
-		sql  string
-		want string
-	}{
-		{
-			name: "simple string literal",
-			sql:  "SELECT * FROM users WHERE name = 'Alice'",
-			want: "SELECT * FROM users WHERE name = ''",
-		},
-		{
-			name: "multiple string literals",
-			sql:  "SELECT * FROM users WHERE name = 'Alice' AND city = 'NYC'",
-			want: "SELECT * FROM users WHERE name = '' AND city = ''",
-		},
-		{
-			name: "WHERE inside string literal",
-			sql:  "UPDATE users SET description = 'WHERE is my data'",
-			want: "UPDATE users SET description = ''",
-		},
-		{
-			name: "escaped single quote",
-			sql:  "INSERT INTO users (name) VALUES ('can''t')",
-			want: "INSERT INTO users (name) VALUES ('')",
-		},
-		{
-			name: "no string literals",
-			sql:  "SELECT * FROM users WHERE id = 123",
-			want: "SELECT * FROM users WHERE id = 123",
-		},
-		{
-			name: "empty string",
-			sql:  "SELECT * FROM users WHERE name = ''",
-			want: "SELECT * FROM users WHERE name = ''",
-		},
-		{
-			name: "string with SQL keywords",
-			sql:  "UPDATE users SET note = 'DELETE FROM WHERE UPDATE'",
-			want: "UPDATE users SET note = ''",
-		},
+func TestCheckSafety_SafeStatements(t *testing.T) {
+	safe := []string{
+		"WITH deleted AS (DELETE FROM users WHERE id = 1 RETURNING *) SELECT * FROM deleted",
+		"WITH ids AS (SELECT id FROM banned) DELETE FROM users WHERE id IN (SELECT id FROM ids)",
+		"UPDATE users u SET name = a.name FROM admins a WHERE a.id = u.id",
+		"DELETE FROM users u USING banned b WHERE b.id = u.id",
+		"DELETE FROM users WHERE CURRENT OF c",
+		"SELECT * FROM users FOR UPDATE",
+		"SELECT * FROM users FOR NO KEY UPDATE SKIP LOCKED",
+		"INSERT INTO users (id, name) VALUES (1, 'a') ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+		"CREATE TABLE orders (user_id int REFERENCES users ON DELETE CASCADE ON UPDATE CASCADE)",
+		"MERGE INTO users u USING staging s ON u.id = s.id WHEN MATCHED THEN DELETE",
+		"EXPLAIN DELETE FROM users",
+		"EXPLAIN (ANALYZE false) DELETE FROM users",
+		"EXPLAIN (ANALYZE, ANALYZE off) DELETE FROM users",
+		"SELECT coalesce(update, 0) FROM flags",
+		"UPDATE users SET a = 1 WHERE id = 1; DELETE FROM users WHERE id = 2",
+		"COPY (SELECT * FROM users) TO STDOUT",
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := removeStringLiterals(tt.sql)
-			if got != tt.want {
-				t.Errorf("removeStringLiterals() = %q, want %q", got, tt.want)
-			}
-		})
+	for _, sql := range safe {
+		if err := CheckSafety(sql); err != nil {
+			t.Errorf("Expected %q to be safe, got %v", sql, err)
+		}
+	}
+}
+
+func TestCheckSafety_InvalidSQL(t *testing.T) {
+	err := CheckSafety("DELETE FROM users WHERE name = 'unterminated")
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok || vibeErr.Code != postgres.ErrorCodeInvalidSQL {
+		t.Errorf("Expected INVALID_SQL for untokenizable SQL, got %v", err)
 	}
 }
 
@@ -494,11 +499,3 @@ func BenchmarkHasWhereClause(b *testing.B) {
 		_ = hasWhereClause(sql)
 	}
 }
-
-func BenchmarkRemoveComments(b *testing.B) {
-	sql := "SELECT * FROM users -- comment 1\nWHERE id = 1 /* comment 2 */"
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = removeComments(sql)
-	}
-}
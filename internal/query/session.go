@@ -0,0 +1,355 @@
+package query
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+const (
+	// MaxTxSessions is the default maximum number of interactive
+	// transactions and open cursors at once. Each one holds a pool
+	// connection until it ends, so this stays below the pool size to leave
+	// room for regular queries.
+	MaxTxSessions = 2
+)
+
+var (
+	// TxIdleTimeout is how long an interactive transaction or cursor may
+	// sit idle before it is closed and its connection returned to the pool
+	TxIdleTimeout = 30 * time.Second
+)
+
+// txSession is an interactive transaction or a paginated query's cursor,
+// pinned to one pool connection
+type txSession struct {
+	id    string
+	conn  *sql.Conn
+	tx    *sql.Tx
+	timer *time.Timer
+	// owner is the Options.Owner of the request that opened the session
+	owner string
+	// readOnly and role are the policy the session's transaction began with
+	readOnly bool
+	role     string
+
+	// cursor is set for sessions holding a server-side cursor, which can
+	// only be fetched from, not queried or committed directly
+	cursor   bool
+	pageSize int
+
+	// mu serializes statements on the transaction
+	mu       sync.Mutex
+	closed   bool
+	lastUsed time.Time
+}
+
+// txSessions tracks open interactive transactions and cursors by ID
+type txSessions struct {
+	db          *sql.DB
+	limits      Limits
+	idleTimeout time.Duration
+	maxSessions int
+
+	mu       sync.Mutex
+	sessions map[string]*txSession
+	// pending counts sessions whose transaction is still being begun,
+	// which hold a slot without being in sessions yet
+	pending int
+}
+
+func newTxSessions(db *sql.DB, limits Limits) *txSessions {
+	return &txSessions{
+		db:          db,
+		limits:      limits,
+		idleTimeout: TxIdleTimeout,
+		maxSessions: limits.MaxTxSessions,
+		sessions:    make(map[string]*txSession),
+	}
+}
+
+// BeginSession opens an interactive transaction and returns its ID. With
+// opts.ReadOnly the transaction is READ ONLY, and with opts.Role it runs as
+// that role. Only requests with the same opts.Owner may use it.
+func (e *Executor) BeginSession(opts Options) (string, error) {
+	session, err := e.sessions.open(false, opts)
+	if err != nil {
+		return "", err
+	}
+	session.mu.Unlock()
+	return session.id, nil
+}
+
+// ExecuteInSession runs a statement inside an open interactive transaction.
+// A failing statement rolls back and closes the transaction.
+func (e *Executor) ExecuteInSession(id string, sql string, params []interface{}, opts Options) (*ExecutionResult, error) {
+	return e.sessions.execute(id, sql, params, opts)
+}
+
+// CommitSession commits and closes an interactive transaction
+func (e *Executor) CommitSession(id string, opts Options) error {
+	return e.sessions.end(id, opts, true)
+}
+
+// RollbackSession rolls back and closes an interactive transaction
+func (e *Executor) RollbackSession(id string, opts Options) error {
+	return e.sessions.end(id, opts, false)
+}
+
+// Close rolls back every open interactive transaction and cursor
+func (e *Executor) Close() error {
+	e.sessions.closeAll()
+	return nil
+}
+
+// open begins a transaction and registers it as a session. The session is
+// returned locked so it can be set up before other requests can use it.
+func (s *txSessions) open(cursor bool, opts Options) (*txSession, error) {
+	if err := s.reserve(); err != nil {
+		return nil, err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		s.release()
+		return nil, postgres.TranslateError(err)
+	}
+
+	// Waiting for a pool connection happens without s.mu held, so it
+	// doesn't hold up requests for other sessions
+	ctx, cancel := context.WithTimeout(context.Background(), s.limits.QueryTimeout)
+	defer cancel()
+
+	conn, tx, err := beginSession(ctx, s.db, opts)
+	if err != nil {
+		s.release()
+		return nil, s.limits.translateError(err)
+	}
+
+	session := &txSession{
+		id:       id,
+		conn:     conn,
+		tx:       tx,
+		owner:    opts.Owner,
+		readOnly: opts.ReadOnly,
+		role:     opts.Role,
+		cursor:   cursor,
+		lastUsed: time.Now(),
+	}
+	session.mu.Lock()
+	session.timer = time.AfterFunc(s.idleTimeout, func() {
+		s.expire(id)
+	})
+
+	s.mu.Lock()
+	s.pending--
+	s.sessions[id] = session
+	s.mu.Unlock()
+
+	return session, nil
+}
+
+// reserve takes a session slot ahead of beginning its transaction
+func (s *txSessions) reserve() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.sessions)+s.pending >= s.maxSessions {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeServiceUnavailable,
+			"Too many open transactions",
+			fmt.Sprintf("At most %d interactive transactions and cursors may be open at once. Commit or roll back an existing transaction, or read a cursor to its end, first", s.maxSessions),
+		)
+	}
+	s.pending++
+	return nil
+}
+
+// release gives back a slot taken by reserve when the session fails to open
+func (s *txSessions) release() {
+	s.mu.Lock()
+	s.pending--
+	s.mu.Unlock()
+}
+
+// beginSession begins a session's transaction on a connection of its own.
+// ctx bounds waiting for the connection and setting the transaction up,
+// but not the transaction itself, which database/sql would roll back once
+// ctx ended.
+func beginSession(ctx context.Context, db *sql.DB, opts Options) (*sql.Conn, *sql.Tx, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if err := setupTx(ctx, tx, opts); err != nil {
+		_ = tx.Rollback()
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return conn, tx, nil
+}
+
+func (s *txSessions) execute(id string, sql string, params []interface{}, opts Options) (*ExecutionResult, error) {
+	session, err := s.acquire(id, false, opts.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer session.mu.Unlock()
+
+	session.timer.Stop()
+
+	result, err := runQuery(session.tx, s.limits, sql, params, opts)
+	if err != nil {
+		s.close(session, false)
+		log.Printf("[WARN] Transaction %s rolled back after failed statement", id)
+		return nil, err
+	}
+
+	session.lastUsed = time.Now()
+	session.timer.Reset(s.idleTimeout)
+	return result, nil
+}
+
+func (s *txSessions) end(id string, opts Options, commit bool) error {
+	session, err := s.acquire(id, false, opts.Owner)
+	if err != nil {
+		return err
+	}
+	defer session.mu.Unlock()
+
+	return s.close(session, commit)
+}
+
+// expire rolls back a session that exceeded the idle timeout
+func (s *txSessions) expire(id string) {
+	session, err := s.lookup(id)
+	if err != nil {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// The timer may have fired while a statement was running
+	if session.closed || time.Since(session.lastUsed) < s.idleTimeout {
+		return
+	}
+
+	s.close(session, false)
+	if session.cursor {
+		log.Printf("[WARN] Cursor %s closed after %v idle", id, s.idleTimeout)
+	} else {
+		log.Printf("[WARN] Transaction %s rolled back after %v idle", id, s.idleTimeout)
+	}
+}
+
+func (s *txSessions) closeAll() {
+	s.mu.Lock()
+	sessions := make([]*txSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.mu.Unlock()
+
+	for _, session := range sessions {
+		session.mu.Lock()
+		if !session.closed {
+			s.close(session, false)
+		}
+		session.mu.Unlock()
+	}
+}
+
+// close ends a session's transaction and unregisters it. The caller must
+// hold session.mu.
+func (s *txSessions) close(session *txSession, commit bool) error {
+	s.remove(session.id)
+	session.closed = true
+	session.timer.Stop()
+
+	var err error
+	if commit {
+		err = session.tx.Commit()
+	} else {
+		err = session.tx.Rollback()
+	}
+	_ = session.conn.Close()
+	if err != nil {
+		return postgres.TranslateError(err)
+	}
+	return nil
+}
+
+// acquire looks up an open session of the given kind and locks it. A
+// session opened by another owner is reported as not found, so its ID
+// alone reveals nothing.
+func (s *txSessions) acquire(id string, cursor bool, owner string) (*txSession, error) {
+	notFound := func() error {
+		if cursor {
+			return newCursorNotFoundError(id)
+		}
+		return newTransactionNotFoundError(id)
+	}
+
+	session, err := s.lookup(id)
+	if err != nil || session.cursor != cursor {
+		return nil, notFound()
+	}
+	if session.owner != owner {
+		log.Printf("[WARN] Rejected use of %s by a client other than the one that opened it", id)
+		return nil, notFound()
+	}
+
+	session.mu.Lock()
+	if session.closed {
+		session.mu.Unlock()
+		return nil, notFound()
+	}
+	return session, nil
+}
+
+func (s *txSessions) lookup(id string) (*txSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, newTransactionNotFoundError(id)
+	}
+	return session, nil
+}
+
+func (s *txSessions) remove(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate transaction ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newTransactionNotFoundError(id string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		postgres.ErrorCodeTransactionNotFound,
+		"Transaction not found",
+		fmt.Sprintf("Transaction '%s' does not exist, has already ended, or was rolled back after being idle", id),
+	)
+}
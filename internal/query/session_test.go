@@ -0,0 +1,165 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func expectTransactionNotFound(t *testing.T, err error) {
+	t.Helper()
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T (%v)", err, err)
+	}
+	if vibeErr.Code != postgres.ErrorCodeTransactionNotFound {
+		t.Errorf("Expected TRANSACTION_NOT_FOUND, got %s", vibeErr.Code)
+	}
+}
+
+func TestExecutor_Session_UnknownID(t *testing.T) {
+	executor := NewExecutor(nil)
+
+	_, err := executor.ExecuteInSession("missing", "SELECT 1", nil, Options{})
+	expectTransactionNotFound(t, err)
+
+	expectTransactionNotFound(t, executor.CommitSession("missing", Options{}))
+	expectTransactionNotFound(t, executor.RollbackSession("missing", Options{}))
+}
+
+func TestExecutor_Session_MaxSessions(t *testing.T) {
+	executor := NewExecutor(nil)
+	for i := 0; i < MaxTxSessions; i++ {
+		id, _ := newSessionID()
+		executor.sessions.sessions[id] = &txSession{id: id}
+	}
+
+	_, err := executor.BeginSession(Options{})
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T (%v)", err, err)
+	}
+	if vibeErr.Code != postgres.ErrorCodeServiceUnavailable {
+		t.Errorf("Expected SERVICE_UNAVAILABLE, got %s", vibeErr.Code)
+	}
+}
+
+func TestExecutor_Session_Owner(t *testing.T) {
+	executor := NewExecutor(nil)
+	executor.sessions.sessions["tx"] = &txSession{id: "tx", owner: "key1"}
+	executor.sessions.sessions["cur"] = &txSession{id: "cur", owner: "key1", cursor: true}
+
+	for _, owner := range []string{"key2", ""} {
+		other := Options{Owner: owner}
+		_, err := executor.ExecuteInSession("tx", "SELECT 1", nil, other)
+		expectTransactionNotFound(t, err)
+		expectTransactionNotFound(t, executor.CommitSession("tx", other))
+		expectTransactionNotFound(t, executor.RollbackSession("tx", other))
+		_, err = executor.FetchPage("cur", other, 0)
+		expectCursorNotFound(t, err)
+	}
+	if len(executor.sessions.sessions) != 2 {
+		t.Error("Expected sessions to stay open after requests from another owner")
+	}
+}
+
+func TestExecutor_Session_ConfiguredMax(t *testing.T) {
+	executor := NewExecutorWithLimits(nil, Limits{MaxTxSessions: 1})
+	executor.sessions.sessions["tx"] = &txSession{id: "tx"}
+
+	_, err := executor.BeginSession(Options{})
+	if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeServiceUnavailable {
+		t.Errorf("Expected SERVICE_UNAVAILABLE past the configured limit, got %v", err)
+	}
+}
+
+func TestExecutor_Session_PendingCountsTowardMax(t *testing.T) {
+	executor := NewExecutorWithLimits(nil, Limits{MaxTxSessions: 1})
+	executor.sessions.pending = 1
+
+	_, err := executor.BeginSession(Options{})
+	if vibeErr, ok := err.(*postgres.VibeError); !ok || vibeErr.Code != postgres.ErrorCodeServiceUnavailable {
+		t.Errorf("Expected SERVICE_UNAVAILABLE while a session is being opened, got %v", err)
+	}
+}
+
+func TestNewSessionID_Unique(t *testing.T) {
+	a, err := newSessionID()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	b, _ := newSessionID()
+
+	if len(a) != 32 {
+		t.Errorf("Expected 32 hex characters, got %d", len(a))
+	}
+	if a == b {
+		t.Error("Expected unique session IDs")
+	}
+}
+
+func TestExecutor_Session_CommitAndRollback(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, _ = db.Exec("DROP TABLE IF EXISTS test_tx_session")
+	if _, err := db.Exec("CREATE TABLE test_tx_session (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS test_tx_session")
+
+	executor := NewExecutor(db)
+	defer executor.Close()
+
+	committed, err := executor.BeginSession(Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := executor.ExecuteInSession(committed, "INSERT INTO test_tx_session VALUES ($1)", []interface{}{int64(1)}, Options{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := executor.CommitSession(committed, Options{}); err != nil {
+		t.Fatalf("Expected commit to succeed, got: %v", err)
+	}
+
+	rolledBack, err := executor.BeginSession(Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := executor.ExecuteInSession(rolledBack, "INSERT INTO test_tx_session VALUES ($1)", []interface{}{int64(2)}, Options{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := executor.RollbackSession(rolledBack, Options{}); err != nil {
+		t.Fatalf("Expected rollback to succeed, got: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM test_tx_session").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected only the committed row, found %d rows", count)
+	}
+
+	expectTransactionNotFound(t, executor.CommitSession(committed, Options{}))
+}
+
+func TestExecutor_Session_IdleTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	executor.sessions.idleTimeout = 100 * time.Millisecond
+	defer executor.Close()
+
+	id, err := executor.BeginSession(Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	_, err = executor.ExecuteInSession(id, "SELECT 1", nil, Options{})
+	expectTransactionNotFound(t, err)
+}
@@ -0,0 +1,74 @@
+package query
+
+import (
+	"context"
+	"time"
+)
+
+// RowWriter receives the result of a streamed query as it is read
+type RowWriter interface {
+	// WriteColumns is called once, before the first row
+	WriteColumns(columns []Column) error
+	// WriteRow is called for each row with values ordered like the columns.
+	// The slice is reused for the next row and must not be retained.
+	WriteRow(values []interface{}) error
+}
+
+// ExecuteStream runs a query and passes each row to w as soon as it is
+// scanned, so the result is never held in memory and the row limit does
+// not apply. The query timeout still bounds the whole stream. The returned
+// result carries Columns, RowCount and ExecutionTime but no rows.
+//
+// With opts.ReadOnly the stream runs in a READ ONLY transaction.
+//
+// An error from w stops the query and is returned unchanged.
+func (e *Executor) ExecuteStream(sql string, params []interface{}, opts Options, w RowWriter) (*ExecutionResult, error) {
+	return e.run(opts, func(q queryer) (*ExecutionResult, error) {
+		return e.stream(q, sql, params, opts, w)
+	})
+}
+
+func (e *Executor) stream(q queryer, sql string, params []interface{}, opts Options, w RowWriter) (*ExecutionResult, error) {
+	startTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.limits.QueryTimeout)
+	defer cancel()
+
+	rows, err := q.QueryContext(ctx, copyAsQuery(sql), params...)
+	if err != nil {
+		return nil, e.limits.translateError(err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, e.limits.translateError(err)
+	}
+	columns := describeColumns(columnTypes)
+
+	if err := w.WriteColumns(columns); err != nil {
+		return nil, err
+	}
+
+	rowCount := 0
+	values := make([]interface{}, len(columns))
+	for rows.Next() {
+		if err := scanRow(rows, columnTypes, values, opts); err != nil {
+			return nil, e.limits.translateError(err)
+		}
+		if err := w.WriteRow(values); err != nil {
+			return nil, err
+		}
+		rowCount++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, e.limits.translateError(err)
+	}
+
+	return &ExecutionResult{
+		Columns:       columns,
+		RowCount:      rowCount,
+		ExecutionTime: time.Since(startTime),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+type collectingWriter struct {
+	columns []Column
+	rows    [][]interface{}
+	failAt  int
+}
+
+func (c *collectingWriter) WriteColumns(columns []Column) error {
+	c.columns = columns
+	return nil
+}
+
+func (c *collectingWriter) WriteRow(values []interface{}) error {
+	if c.failAt > 0 && len(c.rows) == c.failAt {
+		return errors.New("client went away")
+	}
+	c.rows = append(c.rows, append([]interface{}(nil), values...))
+	return nil
+}
+
+func TestExecutor_ExecuteStream(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// The row limit does not apply to streamed results
+	executor := NewExecutorWithLimits(db, Limits{MaxResultRows: 5})
+	writer := &collectingWriter{}
+
+	result, err := executor.ExecuteStream("SELECT n, n::text AS label FROM generate_series(1, $1::int) AS n", []interface{}{int64(50)}, Options{}, writer)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.RowCount != 50 || len(writer.rows) != 50 {
+		t.Errorf("Expected 50 rows, got %d (writer saw %d)", result.RowCount, len(writer.rows))
+	}
+	if len(writer.columns) != 2 || writer.columns[1].Name != "label" {
+		t.Errorf("Unexpected columns: %+v", writer.columns)
+	}
+	if writer.rows[49][0] != int64(50) || writer.rows[49][1] != "50" {
+		t.Errorf("Unexpected last row: %v", writer.rows[49])
+	}
+	if result.Rows != nil {
+		t.Error("Expected streamed result to hold no rows")
+	}
+}
+
+func TestExecutor_ExecuteStream_WriterError(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	writer := &collectingWriter{failAt: 3}
+
+	_, err := executor.ExecuteStream("SELECT generate_series(1, 100)", nil, Options{}, writer)
+	if err == nil || err.Error() != "client went away" {
+		t.Fatalf("Expected writer error, got: %v", err)
+	}
+	if len(writer.rows) != 3 {
+		t.Errorf("Expected streaming to stop after 3 rows, got %d", len(writer.rows))
+	}
+}
+
+func TestExecutor_ExecuteStream_InvalidQuery(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewExecutor(db)
+	writer := &collectingWriter{}
+
+	if _, err := executor.ExecuteStream("SELECT * FROM missing_table_for_stream", nil, Options{}, writer); err == nil {
+		t.Fatal("Expected error for invalid query")
+	}
+	if writer.columns != nil {
+		t.Error("Expected no output before a query error")
+	}
+}
@@ -0,0 +1,304 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	// tokenWord is an unquoted keyword or identifier
+	tokenWord tokenKind = iota
+	// tokenIdent is a double-quoted identifier
+	tokenIdent
+	// tokenString is a string constant in any quoting style, including
+	// E'', B'', X'', U&'' and dollar quoting
+	tokenString
+	tokenNumber
+	// tokenParam is a positional parameter such as $1
+	tokenParam
+	tokenOperator
+	// tokenPunct is one of ( ) [ ] , ; : .
+	tokenPunct
+)
+
+// token is a lexical element of a SQL string. Whitespace and comments are
+// not returned as tokens.
+type token struct {
+	kind tokenKind
+	text string
+	// pos and end are the byte offsets of the token in the source
+	pos int
+	end int
+}
+
+// is reports whether the token is the given keyword, ignoring case
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// isAny reports whether the token is one of the given keywords
+func (t token) isAny(keywords ...string) bool {
+	for _, keyword := range keywords {
+		if t.is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t token) isPunct(p string) bool {
+	return t.kind == tokenPunct && t.text == p
+}
+
+// tokenize splits sql into tokens following PostgreSQL's lexical rules,
+// skipping whitespace and comments. Block comments nest, as in PostgreSQL.
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		start := i
+
+		switch {
+		case isSpace(c):
+			i++
+			continue
+
+		case c == '-' && peek(sql, i+1) == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '/' && peek(sql, i+1) == '*':
+			end, err := skipBlockComment(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			continue
+
+		case c == '\'':
+			end, err := scanQuoted(sql, i, '\'', false)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: sql[start:i], pos: start, end: i})
+
+		case c == '"':
+			end, err := scanQuoted(sql, i, '"', false)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenIdent, text: sql[start:i], pos: start, end: i})
+
+		case c == '$' && isDigit(peek(sql, i+1)):
+			i++
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenParam, text: sql[start:i], pos: start, end: i})
+
+		case c == '$':
+			end, ok, err := scanDollarQuoted(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("unexpected character '$' at position %d", i+1)
+			}
+			i = end
+			tokens = append(tokens, token{kind: tokenString, text: sql[start:i], pos: start, end: i})
+
+		case isDigit(c) || (c == '.' && isDigit(peek(sql, i+1))):
+			i = scanNumber(sql, i)
+			tokens = append(tokens, token{kind: tokenNumber, text: sql[start:i], pos: start, end: i})
+
+		case isIdentStart(c):
+			end, kind, err := scanWord(sql, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, token{kind: kind, text: sql[start:i], pos: start, end: i})
+
+		case c == ':' && peek(sql, i+1) == ':':
+			i += 2
+			tokens = append(tokens, token{kind: tokenOperator, text: "::", pos: start, end: i})
+
+		case strings.IndexByte("()[],;:.", c) >= 0:
+			i++
+			tokens = append(tokens, token{kind: tokenPunct, text: sql[start:i], pos: start, end: i})
+
+		case isOperatorChar(c):
+			i++
+			// An operator ends where a comment starts
+			for i < len(sql) && isOperatorChar(sql[i]) &&
+				!(sql[i] == '-' && peek(sql, i+1) == '-') &&
+				!(sql[i] == '/' && peek(sql, i+1) == '*') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: sql[start:i], pos: start, end: i})
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// scanWord scans an identifier or keyword starting at i. A string prefix
+// such as E, B, X, N or U& directly followed by a quote makes the whole
+// run a single string or quoted identifier token.
+func scanWord(sql string, i int) (int, tokenKind, error) {
+	start := i
+	for i < len(sql) && isIdentChar(sql[i]) {
+		i++
+	}
+
+	word := sql[start:i]
+	next := peek(sql, i)
+
+	if next == '\'' && len(word) == 1 && strings.ContainsAny(word, "eEbBxXnN") {
+		end, err := scanQuoted(sql, i, '\'', word == "e" || word == "E")
+		return end, tokenString, err
+	}
+
+	if (word == "u" || word == "U") && next == '&' {
+		switch peek(sql, i+1) {
+		case '\'':
+			end, err := scanQuoted(sql, i+1, '\'', false)
+			return end, tokenString, err
+		case '"':
+			end, err := scanQuoted(sql, i+1, '"', false)
+			return end, tokenIdent, err
+		}
+	}
+
+	return i, tokenWord, nil
+}
+
+// scanQuoted scans a quoted string or identifier whose opening quote is at
+// i, returning the offset just past the closing quote. A doubled quote is an
+// escaped quote; with backslashes set, so is a backslash-escaped character.
+func scanQuoted(sql string, i int, quote byte, backslashes bool) (int, error) {
+	start := i
+	i++
+	for i < len(sql) {
+		switch {
+		case backslashes && sql[i] == '\\':
+			i += 2
+		case sql[i] == quote && peek(sql, i+1) == quote:
+			i += 2
+		case sql[i] == quote:
+			return i + 1, nil
+		default:
+			i++
+		}
+	}
+
+	if quote == '"' {
+		return 0, fmt.Errorf("unterminated quoted identifier at position %d", start+1)
+	}
+	return 0, fmt.Errorf("unterminated string literal at position %d", start+1)
+}
+
+// scanDollarQuoted scans a dollar-quoted string ($$...$$ or $tag$...$tag$)
+// starting at i. ok is false when the '$' does not start a dollar quote.
+func scanDollarQuoted(sql string, i int) (end int, ok bool, err error) {
+	j := i + 1
+	if j < len(sql) && isIdentStart(sql[j]) {
+		for j < len(sql) && isIdentChar(sql[j]) && sql[j] != '$' {
+			j++
+		}
+	}
+	if peek(sql, j) != '$' {
+		return 0, false, nil
+	}
+
+	delimiter := sql[i : j+1]
+	closing := strings.Index(sql[j+1:], delimiter)
+	if closing < 0 {
+		return 0, true, fmt.Errorf("unterminated dollar-quoted string at position %d", i+1)
+	}
+	return j + 1 + closing + len(delimiter), true, nil
+}
+
+// skipBlockComment returns the offset just past the block comment starting
+// at i, honoring nested comments
+func skipBlockComment(sql string, i int) (int, error) {
+	start := i
+	depth := 0
+	for i < len(sql) {
+		switch {
+		case sql[i] == '/' && peek(sql, i+1) == '*':
+			depth++
+			i += 2
+		case sql[i] == '*' && peek(sql, i+1) == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, nil
+			}
+		default:
+			i++
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment at position %d", start+1)
+}
+
+// scanNumber scans a numeric constant, including decimals, exponents and
+// the 0x/0o/0b and underscore forms
+func scanNumber(sql string, i int) int {
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case isDigit(c) || c == '_' || isLetter(c):
+			// Exponent sign: 1e-5
+			if (c == 'e' || c == 'E') && (peek(sql, i+1) == '+' || peek(sql, i+1) == '-') && isDigit(peek(sql, i+2)) {
+				i += 2
+			}
+			i++
+		case c == '.' && peek(sql, i+1) != '.':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func peek(sql string, i int) byte {
+	if i < len(sql) {
+		return sql[i]
+	}
+	return 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentStart(c byte) bool {
+	return isLetter(c) || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
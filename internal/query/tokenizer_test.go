@@ -0,0 +1,116 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func tokenTexts(tokens []token) []string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.text
+	}
+	return texts
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+	}{
+		{"simple", "SELECT a, b FROM t", []string{"SELECT", "a", ",", "b", "FROM", "t"}},
+		{"line comment", "-- note\nSELECT 1 -- trailing", []string{"SELECT", "1"}},
+		{"block comment", "/* a */ SELECT /* b */ 1", []string{"SELECT", "1"}},
+		{"nested block comment", "/* outer /* inner */ still comment */ SELECT 1", []string{"SELECT", "1"}},
+		{"string with quote", "SELECT 'it''s; -- not a comment'", []string{"SELECT", "'it''s; -- not a comment'"}},
+		{"escape string", `SELECT E'a\'b', 1`, []string{"SELECT", `E'a\'b'`, ",", "1"}},
+		{"bit and hex strings", "SELECT B'101', X'1F'", []string{"SELECT", "B'101'", ",", "X'1F'"}},
+		{"unicode string", "SELECT U&'d\\0061t'", []string{"SELECT", "U&'d\\0061t'"}},
+		{"quoted identifier", `SELECT "WHERE" FROM "my ""table"""`, []string{"SELECT", `"WHERE"`, "FROM", `"my ""table"""`}},
+		{"dollar quoted", "SELECT $$a ' b; c$$", []string{"SELECT", "$$a ' b; c$$"}},
+		{"tagged dollar quoted", "SELECT $fn$ $$ inner $$ $fn$", []string{"SELECT", "$fn$ $$ inner $$ $fn$"}},
+		{"params", "SELECT $1::int + $23", []string{"SELECT", "$1", "::", "int", "+", "$23"}},
+		{"numbers", "SELECT 1.5, .5, 1e-5, 0x1F, 1_000", []string{"SELECT", "1.5", ",", ".5", ",", "1e-5", ",", "0x1F", ",", "1_000"}},
+		{"array slice", "SELECT a[1:2]", []string{"SELECT", "a", "[", "1", ":", "2", "]"}},
+		{"operators", "SELECT a->>'k', b @> c, d<>e", []string{"SELECT", "a", "->>", "'k'", ",", "b", "@>", "c", ",", "d", "<>", "e"}},
+		{"operator before comment", "SELECT 1+-- c\n2", []string{"SELECT", "1", "+", "2"}},
+		{"identifier with dollar", "SELECT a$b", []string{"SELECT", "a$b"}},
+		{"semicolons", "SELECT 1; SELECT 2;", []string{"SELECT", "1", ";", "SELECT", "2", ";"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.sql)
+			if err != nil {
+				t.Fatalf("tokenize() unexpected error = %v", err)
+			}
+			got := tokenTexts(tokens)
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("tokenize(%q) = %q, expected %q", tt.sql, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTokenize_Kinds(t *testing.T) {
+	tokens, err := tokenize(`SELECT "id", 'x', $$y$$, 1, $1, +, (`)
+	if err != nil {
+		t.Fatalf("tokenize() unexpected error = %v", err)
+	}
+
+	expected := []tokenKind{
+		tokenWord, tokenIdent, tokenPunct, tokenString, tokenPunct, tokenString, tokenPunct,
+		tokenNumber, tokenPunct, tokenParam, tokenPunct, tokenOperator, tokenPunct, tokenPunct,
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d: %q", len(expected), len(tokens), tokenTexts(tokens))
+	}
+	for i, kind := range expected {
+		if tokens[i].kind != kind {
+			t.Errorf("Token %d (%s): expected kind %d, got %d", i, tokens[i].text, kind, tokens[i].kind)
+		}
+	}
+}
+
+func TestTokenize_Positions(t *testing.T) {
+	sql := "  SELECT  'a'"
+	tokens, err := tokenize(sql)
+	if err != nil {
+		t.Fatalf("tokenize() unexpected error = %v", err)
+	}
+	for _, tok := range tokens {
+		if sql[tok.pos:tok.end] != tok.text {
+			t.Errorf("Token %q has positions %d-%d covering %q", tok.text, tok.pos, tok.end, sql[tok.pos:tok.end])
+		}
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		wantErr string
+	}{
+		{"unterminated string", "SELECT 'abc", "unterminated string literal at position 8"},
+		{"unterminated escape string", `SELECT E'abc\'`, "unterminated string literal"},
+		{"unterminated identifier", `SELECT "abc`, "unterminated quoted identifier"},
+		{"unterminated comment", "SELECT 1 /* abc", "unterminated comment"},
+		{"unterminated nested comment", "/* a /* b */ SELECT 1", "unterminated comment"},
+		{"unterminated dollar quote", "SELECT $x$abc", "unterminated dollar-quoted string"},
+		{"stray dollar", "SELECT $ 1", "unexpected character '$'"},
+		{"backslash", `SELECT \d`, "unexpected character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenize(tt.sql)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+const (
+	// MaxTransactionStatements is the maximum number of statements
+	// accepted in a single transaction request
+	MaxTransactionStatements = 100
+)
+
+// Statement is a single SQL statement with its bind parameters
+type Statement struct {
+	SQL    string
+	Params []interface{}
+}
+
+// StatementError reports which statement of a multi-statement request failed
+type StatementError struct {
+	Index int
+	Err   *postgres.VibeError
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d: %v", e.Index, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteTransaction runs statements in order inside a single transaction.
+// The transaction is committed only if every statement succeeds; on the
+// first failure it is rolled back and a *StatementError is returned. With
+// opts.ReadOnly the transaction is READ ONLY.
+func (e *Executor) ExecuteTransaction(statements []Statement, opts Options) ([]*ExecutionResult, error) {
+	tx, err := beginTx(e.db, opts)
+	if err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+
+	results := make([]*ExecutionResult, 0, len(statements))
+	for i, stmt := range statements {
+		result, err := runQuery(tx, e.limits, stmt.SQL, stmt.Params, opts)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, &StatementError{Index: i, Err: postgres.TranslateError(err)}
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, postgres.TranslateError(err)
+	}
+
+	return results, nil
+}
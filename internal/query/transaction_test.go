@@ -0,0 +1,88 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+)
+
+func TestStatementError(t *testing.T) {
+	inner := postgres.NewVibeError(postgres.ErrorCodeInvalidSQL, "Invalid SQL syntax", "")
+	err := &StatementError{Index: 2, Err: inner}
+
+	if !strings.Contains(err.Error(), "statement 2") {
+		t.Errorf("Expected error message to include statement index, got: %s", err.Error())
+	}
+
+	var vibeErr *postgres.VibeError
+	if !errors.As(err, &vibeErr) || vibeErr != inner {
+		t.Error("Expected StatementError to unwrap to its VibeError")
+	}
+}
+
+func TestExecutor_ExecuteTransaction_Commit(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, _ = db.Exec("DROP TABLE IF EXISTS test_tx_accounts")
+	if _, err := db.Exec("CREATE TABLE test_tx_accounts (id INT PRIMARY KEY, balance INT)"); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS test_tx_accounts")
+
+	executor := NewExecutor(db)
+
+	results, err := executor.ExecuteTransaction([]Statement{
+		{SQL: "INSERT INTO test_tx_accounts VALUES ($1, $2)", Params: []interface{}{int64(1), int64(100)}},
+		{SQL: "UPDATE test_tx_accounts SET balance = balance - 30 WHERE id = $1 RETURNING balance", Params: []interface{}{int64(1)}},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	if results[1].Rows[0]["balance"] != int64(70) {
+		t.Errorf("Expected balance 70, got %v", results[1].Rows[0]["balance"])
+	}
+}
+
+func TestExecutor_ExecuteTransaction_Rollback(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, _ = db.Exec("DROP TABLE IF EXISTS test_tx_rollback")
+	if _, err := db.Exec("CREATE TABLE test_tx_rollback (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS test_tx_rollback")
+
+	executor := NewExecutor(db)
+
+	_, err := executor.ExecuteTransaction([]Statement{
+		{SQL: "INSERT INTO test_tx_rollback VALUES (1)"},
+		{SQL: "INSERT INTO test_tx_rollback VALUES (1)"},
+	}, Options{})
+
+	var stmtErr *StatementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("Expected StatementError, got %v", err)
+	}
+
+	if stmtErr.Index != 1 {
+		t.Errorf("Expected failing statement 1, got %d", stmtErr.Index)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM test_tx_rollback").Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected first insert to be rolled back, found %d rows", count)
+	}
+}
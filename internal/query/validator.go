@@ -1,18 +1,24 @@
 package query
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/vibesql/vibe/internal/postgres"
 )
 
 const (
-	// MaxQuerySize is the maximum allowed SQL query length (10KB)
+	// MaxQuerySize is the default maximum SQL query length (10KB)
 	MaxQuerySize = 10 * 1024 // 10KB in bytes
 )
 
-// ValidateQuery validates a SQL query for basic requirements
+// ValidateQuery validates a SQL query against the default limits
 func ValidateQuery(sql string) error {
+	return DefaultLimits().ValidateQuery(sql)
+}
+
+// ValidateQuery validates a SQL query for basic requirements
+func (l Limits) ValidateQuery(sql string) error {
 	// Check for empty SQL
 	trimmed := strings.TrimSpace(sql)
 	if trimmed == "" {
@@ -23,39 +29,87 @@ func ValidateQuery(sql string) error {
 		)
 	}
 
-	// Check query length (10KB limit)
-	if len(sql) > MaxQuerySize {
+	// Check query length
+	if len(sql) > l.MaxQuerySize {
 		return postgres.NewVibeError(
 			postgres.ErrorCodeQueryTooLarge,
 			"Query too large",
-			"SQL query exceeds the maximum allowed size of 10KB",
+			fmt.Sprintf("SQL query exceeds the maximum allowed size of %s", FormatSize(l.MaxQuerySize)),
 		)
 	}
 
-	// Basic SQL syntax validation - check for at least one SQL keyword
-	// Detailed syntax validation is deferred to PostgreSQL engine,
-	// which returns SQLSTATE codes that we map to INVALID_SQL errors
-	upperSQL := strings.ToUpper(trimmed)
-	validKeywords := []string{
-		"SELECT", "INSERT", "UPDATE", "DELETE",
-		"CREATE", "DROP", "ALTER", "TRUNCATE",
+	// Check that each statement is one we recognize and can run. Detailed
+	// syntax validation is deferred to the PostgreSQL engine, which returns
+	// SQLSTATE codes that we map to INVALID_SQL errors. Which statements are
+	// allowed is up to the safety policy.
+	statements, err := ParseStatements(sql)
+	if err != nil {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Invalid SQL syntax",
+			capitalize(err.Error()),
+		)
 	}
 
-	hasValidKeyword := false
-	for _, keyword := range validKeywords {
-		if strings.HasPrefix(upperSQL, keyword) {
-			hasValidKeyword = true
-			break
+	if len(statements) == 0 {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeMissingRequiredField,
+			"Missing required field",
+			"The 'sql' field contains only comments or semicolons",
+		)
+	}
+
+	for _, stmt := range statements {
+		if err := checkSupported(stmt); err != nil {
+			return err
 		}
 	}
 
-	if !hasValidKeyword {
+	return nil
+}
+
+// checkSupported returns an INVALID_SQL error for a statement that is not
+// recognized or that the API cannot run whatever the policy
+func checkSupported(stmt *ParsedStatement) error {
+	if stmt.Class == "" {
 		return postgres.NewVibeError(
 			postgres.ErrorCodeInvalidSQL,
 			"Invalid SQL syntax",
-			"Query must start with a valid SQL keyword (SELECT, INSERT, UPDATE, DELETE, CREATE, DROP)",
+			"Query must start with a valid SQL statement such as SELECT, WITH, INSERT, UPDATE, DELETE, CREATE, DROP or EXPLAIN",
+		)
+	}
+
+	if stmt.Command == "COPY" && stmt.Class != ClassCopy {
+		return postgres.NewVibeError(
+			postgres.ErrorCodeInvalidSQL,
+			"Statement not supported",
+			"Only COPY ... TO STDOUT is supported",
 		)
 	}
 
+	if stmt.Explained != nil {
+		return checkSupported(stmt.Explained)
+	}
 	return nil
 }
+
+// capitalize upper-cases the first letter of an error message for use as
+// an error detail
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// FormatSize formats a byte count as KB or MB when it divides evenly
+func FormatSize(bytes int) string {
+	switch {
+	case bytes >= 1024*1024 && bytes%(1024*1024) == 0:
+		return fmt.Sprintf("%dMB", bytes/(1024*1024))
+	case bytes >= 1024 && bytes%1024 == 0:
+		return fmt.Sprintf("%dKB", bytes/1024)
+	default:
+		return fmt.Sprintf("%d bytes", bytes)
+	}
+}
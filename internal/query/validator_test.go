@@ -271,6 +271,37 @@ func TestValidateQuery_EdgeCases(t *testing.T) {
 	}
 }
 
+func TestLimits_ValidateQuery_CustomSize(t *testing.T) {
+	limits := Limits{MaxQuerySize: 64 * 1024}.WithDefaults()
+
+	if err := limits.ValidateQuery("SELECT " + strings.Repeat("a", MaxQuerySize*2)); err != nil {
+		t.Errorf("Expected 20KB query to pass a 64KB limit, got: %v", err)
+	}
+
+	err := limits.ValidateQuery("SELECT " + strings.Repeat("a", 64*1024))
+	vibeErr, ok := err.(*postgres.VibeError)
+	if !ok {
+		t.Fatalf("Expected VibeError, got %T", err)
+	}
+	if vibeErr.Detail != "SQL query exceeds the maximum allowed size of 64KB" {
+		t.Errorf("Expected detail to report the configured size, got %q", vibeErr.Detail)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := map[int]string{
+		10240:           "10KB",
+		1024 * 1024:     "1MB",
+		1500:            "1500 bytes",
+		3 * 1024 * 1024: "3MB",
+	}
+	for bytes, expected := range testCases {
+		if got := FormatSize(bytes); got != expected {
+			t.Errorf("FormatSize(%d) = %s, expected %s", bytes, got, expected)
+		}
+	}
+}
+
 func BenchmarkValidateQuery_Simple(b *testing.B) {
 	sql := "SELECT * FROM users WHERE id = 1"
 	b.ResetTimer()
@@ -286,3 +317,59 @@ func BenchmarkValidateQuery_Large(b *testing.B) {
 		_ = ValidateQuery(sql)
 	}
 }
+
+func TestValidateQuery_StatementTypes(t *testing.T) {
+	valid := []string{
+		"WITH active AS (SELECT * FROM users WHERE active) SELECT * FROM active",
+		"EXPLAIN SELECT * FROM users",
+		"EXPLAIN (ANALYZE, FORMAT JSON) SELECT 1",
+		"SHOW server_version",
+		"VALUES (1, 'a'), (2, 'b')",
+		"COPY users TO STDOUT WITH (FORMAT csv)",
+		"CREATE INDEX CONCURRENTLY idx_users_name ON users (name)",
+		"-- fetch everyone\nSELECT * FROM users",
+		"/* report */ SELECT count(*) FROM users",
+		"(SELECT 1) UNION ALL (SELECT 2)",
+		"SELECT 1; SELECT 2;",
+	}
+	for _, sql := range valid {
+		if err := ValidateQuery(sql); err != nil {
+			t.Errorf("ValidateQuery(%q) unexpected error = %v", sql, err)
+		}
+	}
+
+	invalid := []struct {
+		sql     string
+		errCode string
+	}{
+		{"nonsense", postgres.ErrorCodeInvalidSQL},
+		{"SELECT 1; nonsense", postgres.ErrorCodeInvalidSQL},
+		{"EXPLAIN nonsense", postgres.ErrorCodeInvalidSQL},
+		{"COPY users FROM '/etc/passwd'", postgres.ErrorCodeInvalidSQL},
+		{"COPY users FROM STDIN", postgres.ErrorCodeInvalidSQL},
+		{"SELECT 'unterminated", postgres.ErrorCodeInvalidSQL},
+		{"-- just a comment", postgres.ErrorCodeMissingRequiredField},
+		{";;", postgres.ErrorCodeMissingRequiredField},
+	}
+	for _, tt := range invalid {
+		err := ValidateQuery(tt.sql)
+		vibeErr, ok := err.(*postgres.VibeError)
+		if !ok {
+			t.Errorf("ValidateQuery(%q): expected VibeError, got %v", tt.sql, err)
+			continue
+		}
+		if vibeErr.Code != tt.errCode {
+			t.Errorf("ValidateQuery(%q): expected %s, got %s (%s)", tt.sql, tt.errCode, vibeErr.Code, vibeErr.Detail)
+		}
+	}
+}
+
+func TestValidateQuery_LeavesClassesToPolicy(t *testing.T) {
+	// Recognized statements pass validation; the safety policy decides
+	// whether they may run
+	for _, sql := range []string{"SET search_path = other", "BEGIN", "VACUUM users", "DO $$ BEGIN PERFORM 1; END $$"} {
+		if err := ValidateQuery(sql); err != nil {
+			t.Errorf("ValidateQuery(%q) unexpected error = %v", sql, err)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Package scram implements SCRAM-SHA-256 (RFC 5802, RFC 7677) as used by
+// PostgreSQL password authentication: verifiers in PostgreSQL's stored
+// format, and both sides of the exchange. Channel binding is not supported.
+package scram
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Mechanism is the SASL mechanism name
+	Mechanism = "SCRAM-SHA-256"
+	// DefaultIterations is the PBKDF2 iteration count PostgreSQL uses
+	DefaultIterations = 4096
+
+	saltSize  = 16
+	nonceSize = 18
+)
+
+// ErrAuthFailed reports a wrong password, detected from the client's proof
+// or the server's signature
+var ErrAuthFailed = errors.New("SCRAM authentication failed")
+
+// Verifier is what a server stores to check a password without keeping it
+type Verifier struct {
+	Iterations int
+	Salt       []byte
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// NewVerifier returns a verifier for password with a random salt
+func NewVerifier(password string) (*Verifier, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return deriveVerifier(password, salt, DefaultIterations), nil
+}
+
+func deriveVerifier(password string, salt []byte, iterations int) *Verifier {
+	salted := saltedPassword(password, salt, iterations)
+	clientKey := hmacSum(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	return &Verifier{
+		Iterations: iterations,
+		Salt:       salt,
+		StoredKey:  storedKey[:],
+		ServerKey:  hmacSum(salted, "Server Key"),
+	}
+}
+
+// String returns the verifier in PostgreSQL's format, as stored in
+// pg_authid.rolpassword:
+// SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+func (v *Verifier) String() string {
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%s$%d:%s$%s:%s", Mechanism, v.Iterations, b64(v.Salt), b64(v.StoredKey), b64(v.ServerKey))
+}
+
+// ParseVerifier parses a verifier in PostgreSQL's format
+func ParseVerifier(s string) (*Verifier, error) {
+	mechanism, rest, ok := strings.Cut(s, "$")
+	if !ok || mechanism != Mechanism {
+		return nil, fmt.Errorf("not a %s verifier", Mechanism)
+	}
+	params, keys, ok := strings.Cut(rest, "$")
+	if !ok {
+		return nil, fmt.Errorf("malformed %s verifier", Mechanism)
+	}
+	iterations, salt, ok1 := strings.Cut(params, ":")
+	storedKey, serverKey, ok2 := strings.Cut(keys, ":")
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("malformed %s verifier", Mechanism)
+	}
+
+	v := &Verifier{}
+	var err error
+	if v.Iterations, err = strconv.Atoi(iterations); err != nil || v.Iterations <= 0 {
+		return nil, fmt.Errorf("malformed %s verifier: invalid iteration count", Mechanism)
+	}
+	for _, field := range []struct {
+		dst  *[]byte
+		text string
+	}{{&v.Salt, salt}, {&v.StoredKey, storedKey}, {&v.ServerKey, serverKey}} {
+		if *field.dst, err = base64.StdEncoding.DecodeString(field.text); err != nil {
+			return nil, fmt.Errorf("malformed %s verifier: %v", Mechanism, err)
+		}
+	}
+	if len(v.StoredKey) != sha256.Size || len(v.ServerKey) != sha256.Size {
+		return nil, fmt.Errorf("malformed %s verifier: wrong key length", Mechanism)
+	}
+	return v, nil
+}
+
+// Server is the server side of one SCRAM exchange
+type Server struct {
+	verifier *Verifier
+	// nonce is the server's part of the nonce, random unless set by tests
+	nonce string
+
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+}
+
+// NewServer starts an exchange checking the password of verifier
+func NewServer(verifier *Verifier) *Server {
+	return &Server{verifier: verifier}
+}
+
+// First takes the client-first-message and returns the server-first-message
+func (s *Server) First(clientFirst []byte) ([]byte, error) {
+	message := string(clientFirst)
+
+	// gs2-header: channel binding flag and optional authorization identity
+	switch {
+	case strings.HasPrefix(message, "n,"), strings.HasPrefix(message, "y,"):
+	case strings.HasPrefix(message, "p="):
+		return nil, fmt.Errorf("SCRAM channel binding is not supported")
+	default:
+		return nil, fmt.Errorf("malformed SCRAM client-first-message")
+	}
+	end := strings.Index(message[2:], ",")
+	if end < 0 {
+		return nil, fmt.Errorf("malformed SCRAM client-first-message")
+	}
+	s.gs2Header = message[:2+end+1]
+	s.clientFirstBare = message[len(s.gs2Header):]
+
+	attrs, err := parseAttributes(s.clientFirstBare)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := attrs["m"]; ok {
+		return nil, fmt.Errorf("unsupported SCRAM extension")
+	}
+	clientNonce := attrs["r"]
+	if clientNonce == "" {
+		return nil, fmt.Errorf("malformed SCRAM client-first-message: missing nonce")
+	}
+
+	if s.nonce == "" {
+		if s.nonce, err = randomNonce(); err != nil {
+			return nil, err
+		}
+	}
+	s.serverFirst = fmt.Sprintf("r=%s%s,s=%s,i=%d", clientNonce, s.nonce,
+		base64.StdEncoding.EncodeToString(s.verifier.Salt), s.verifier.Iterations)
+	return []byte(s.serverFirst), nil
+}
+
+// Final takes the client-final-message and returns the server-final-message,
+// or ErrAuthFailed if the client's proof doesn't match the verifier
+func (s *Server) Final(clientFinal []byte) ([]byte, error) {
+	message := string(clientFinal)
+	i := strings.LastIndex(message, ",p=")
+	if i < 0 {
+		return nil, fmt.Errorf("malformed SCRAM client-final-message: missing proof")
+	}
+	withoutProof := message[:i]
+
+	attrs, err := parseAttributes(message)
+	if err != nil {
+		return nil, err
+	}
+	if attrs["c"] != base64.StdEncoding.EncodeToString([]byte(s.gs2Header)) {
+		return nil, fmt.Errorf("SCRAM channel binding does not match")
+	}
+	serverAttrs, _ := parseAttributes(s.serverFirst)
+	if attrs["r"] != serverAttrs["r"] {
+		return nil, fmt.Errorf("SCRAM nonce does not match")
+	}
+	proof, err := base64.StdEncoding.DecodeString(attrs["p"])
+	if err != nil || len(proof) != sha256.Size {
+		return nil, fmt.Errorf("malformed SCRAM client proof")
+	}
+
+	authMessage := s.clientFirstBare + "," + s.serverFirst + "," + withoutProof
+	clientSignature := hmacSum(s.verifier.StoredKey, authMessage)
+	clientKey := xor(proof, clientSignature)
+	storedKey := sha256.Sum256(clientKey)
+	if !hmac.Equal(storedKey[:], s.verifier.StoredKey) {
+		return nil, ErrAuthFailed
+	}
+
+	serverSignature := hmacSum(s.verifier.ServerKey, authMessage)
+	return []byte("v=" + base64.StdEncoding.EncodeToString(serverSignature)), nil
+}
+
+// Client is the client side of one SCRAM exchange
+type Client struct {
+	password string
+	// nonce is the client's nonce, random unless set by tests
+	nonce string
+
+	clientFirstBare string
+	serverSignature []byte
+}
+
+// NewClient starts an exchange proving password
+func NewClient(password string) *Client {
+	return &Client{password: password}
+}
+
+// First returns the client-first-message. The user name is left empty, as
+// PostgreSQL takes it from the startup message.
+func (c *Client) First() ([]byte, error) {
+	if c.nonce == "" {
+		nonce, err := randomNonce()
+		if err != nil {
+			return nil, err
+		}
+		c.nonce = nonce
+	}
+	c.clientFirstBare = "n=,r=" + c.nonce
+	return []byte("n,," + c.clientFirstBare), nil
+}
+
+// Final takes the server-first-message and returns the client-final-message
+func (c *Client) Final(serverFirst []byte) ([]byte, error) {
+	attrs, err := parseAttributes(string(serverFirst))
+	if err != nil {
+		return nil, err
+	}
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, c.nonce) || len(nonce) == len(c.nonce) {
+		return nil, fmt.Errorf("SCRAM server nonce is invalid")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("SCRAM server salt is invalid")
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return nil, fmt.Errorf("SCRAM iteration count is invalid")
+	}
+
+	salted := saltedPassword(c.password, salt, iterations)
+	clientKey := hmacSum(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+
+	withoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte("n,,")) + ",r=" + nonce
+	authMessage := c.clientFirstBare + "," + string(serverFirst) + "," + withoutProof
+	proof := xor(clientKey, hmacSum(storedKey[:], authMessage))
+	c.serverSignature = hmacSum(hmacSum(salted, "Server Key"), authMessage)
+
+	return []byte(withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+// Verify checks the server-final-message, proving the server knew the
+// password too
+func (c *Client) Verify(serverFinal []byte) error {
+	attrs, err := parseAttributes(string(serverFinal))
+	if err != nil {
+		return err
+	}
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("SCRAM server error: %s", e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil || !hmac.Equal(signature, c.serverSignature) {
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+// parseAttributes parses a comma-separated list of a=value attributes
+func parseAttributes(message string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for _, attr := range strings.Split(message, ",") {
+		key, value, ok := strings.Cut(attr, "=")
+		if !ok || len(key) != 1 {
+			return nil, fmt.Errorf("malformed SCRAM message")
+		}
+		attrs[key] = value
+	}
+	return attrs, nil
+}
+
+// saltedPassword is Hi(password, salt, iterations): PBKDF2 with
+// HMAC-SHA-256, for one block of output
+func saltedPassword(password string, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+
+	result := bytes.Clone(u)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
+
+func hmacSum(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+func randomNonce() (string, error) {
+	b := make([]byte, nonceSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,129 @@
+package scram
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// The exchange from RFC 7677, section 3
+const (
+	rfcPassword    = "pencil"
+	rfcClientNonce = "rOprNGfwEbeRWgbNEkqO"
+	rfcServerNonce = "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0"
+	rfcSalt        = "W22ZaJ0SNY7soEsUEjb6gQ=="
+	rfcClientFirst = "n,,n=user,r=rOprNGfwEbeRWgbNEkqO"
+	rfcServerFirst = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	rfcClientFinal = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	rfcServerFinal = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+)
+
+func rfcVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	salt, err := base64.StdEncoding.DecodeString(rfcSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deriveVerifier(rfcPassword, salt, DefaultIterations)
+}
+
+func TestServer_RFC7677(t *testing.T) {
+	server := NewServer(rfcVerifier(t))
+	server.nonce = rfcServerNonce
+
+	serverFirst, err := server.First([]byte(rfcClientFirst))
+	if err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if string(serverFirst) != rfcServerFirst {
+		t.Errorf("First() = %s, want %s", serverFirst, rfcServerFirst)
+	}
+
+	serverFinal, err := server.Final([]byte(rfcClientFinal))
+	if err != nil {
+		t.Fatalf("Final() error = %v", err)
+	}
+	if string(serverFinal) != rfcServerFinal {
+		t.Errorf("Final() = %s, want %s", serverFinal, rfcServerFinal)
+	}
+}
+
+func TestServer_WrongPassword(t *testing.T) {
+	verifier, err := NewVerifier("right")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(verifier)
+	client := NewClient("wrong")
+
+	clientFirst, _ := client.First()
+	serverFirst, err := server.First(clientFirst)
+	if err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	clientFinal, err := client.Final(serverFirst)
+	if err != nil {
+		t.Fatalf("Client Final() error = %v", err)
+	}
+	if _, err := server.Final(clientFinal); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Final() error = %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestServer_ChannelBinding(t *testing.T) {
+	server := NewServer(rfcVerifier(t))
+	if _, err := server.First([]byte("p=tls-server-end-point,,n=,r=abc")); err == nil {
+		t.Error("Expected channel binding to be rejected")
+	}
+}
+
+func TestClient_Exchange(t *testing.T) {
+	verifier, err := NewVerifier("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(verifier)
+	client := NewClient("s3cret")
+
+	clientFirst, err := client.First()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverFirst, err := server.First(clientFirst)
+	if err != nil {
+		t.Fatalf("Server First() error = %v", err)
+	}
+	clientFinal, err := client.Final(serverFirst)
+	if err != nil {
+		t.Fatalf("Client Final() error = %v", err)
+	}
+	serverFinal, err := server.Final(clientFinal)
+	if err != nil {
+		t.Fatalf("Server Final() error = %v", err)
+	}
+	if err := client.Verify(serverFinal); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := client.Verify([]byte("v=" + base64.StdEncoding.EncodeToString(make([]byte, 32)))); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Verify() of a forged signature = %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestVerifier_String(t *testing.T) {
+	verifier := rfcVerifier(t)
+
+	parsed, err := ParseVerifier(verifier.String())
+	if err != nil {
+		t.Fatalf("ParseVerifier() error = %v", err)
+	}
+	if parsed.String() != verifier.String() {
+		t.Errorf("Round trip = %s, want %s", parsed, verifier)
+	}
+
+	for _, invalid := range []string{"", "md5abc", "SCRAM-SHA-256$4096:c2FsdA==", "SCRAM-SHA-256$x:c2FsdA==$a:b"} {
+		if _, err := ParseVerifier(invalid); err == nil {
+			t.Errorf("ParseVerifier(%q) expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// Authenticator requires an API key on every request once a keys file
+// exists. Each key's scope narrows the server's safety policy for the
+// requests made with it. The keys file is reread when it changes, so keys
+// created or revoked with 'vibe keys' take effect without a restart.
+type Authenticator struct {
+	path   string
+	policy query.Policy
+
+	mu      sync.Mutex
+	store   *KeyStore
+	modTime time.Time
+	size    int64
+}
+
+// NewAuthenticator returns an authenticator for the keys file at path,
+// scoping the given server policy
+func NewAuthenticator(path string, policy query.Policy) *Authenticator {
+	return &Authenticator{path: path, policy: policy}
+}
+
+// Enabled reports whether requests need an API key, that is whether the
+// keys file exists
+func (a *Authenticator) Enabled() (bool, error) {
+	store, err := a.keys()
+	return store != nil, err
+}
+
+// keys returns the current key store, rereading the keys file if it
+// changed, or nil if there is no keys file
+func (a *Authenticator) keys() (*KeyStore, error) {
+	info, err := os.Stat(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.store == nil || !info.ModTime().Equal(a.modTime) || info.Size() != a.size {
+		store, err := LoadKeyStore(a.path)
+		if err != nil {
+			return nil, err
+		}
+		a.store, a.modTime, a.size = store, info.ModTime(), info.Size()
+	}
+	return a.store, nil
+}
+
+// Lookup returns the unrevoked key a PostgreSQL protocol client names as
+// its user (see KeyStore.Lookup), or nil if there is none or no keys file
+func (a *Authenticator) Lookup(user string) (*APIKey, error) {
+	store, err := a.keys()
+	if store == nil || err != nil {
+		return nil, err
+	}
+	return store.Lookup(user), nil
+}
+
+// Verify reports whether secret is the key's secret and the key is still
+// unrevoked
+func (a *Authenticator) Verify(key *APIKey, secret string) (bool, error) {
+	store, err := a.keys()
+	if store == nil || err != nil {
+		return false, err
+	}
+	match := store.Authenticate(secret)
+	return match != nil && match.ID == key.ID, nil
+}
+
+// Policy returns the server's safety policy narrowed to the key's scope,
+// running queries as the key's role if it has one
+func (a *Authenticator) Policy(key *APIKey) query.Policy {
+	policy := ScopePolicy(key.Scope, a.policy)
+	if key.Role != "" {
+		policy.Role = key.Role
+	}
+	return policy
+}
+
+// Wrap returns a handler that authenticates requests before passing them
+// to next. Health checks are always allowed so probes need no key.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHealthPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		store, err := a.keys()
+		if err != nil {
+			WriteError(w, NewInternalError("Failed to load API keys"))
+			log.Printf("[ERROR] Failed to load API keys: %v", err)
+			return
+		}
+		if store == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vibe"`)
+			WriteError(w, NewUnauthorizedError("Send an API key in an 'Authorization: Bearer <key>' header"))
+			log.Printf("[ERROR] Missing API key: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			return
+		}
+
+		key := store.Authenticate(token)
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vibe", error="invalid_token"`)
+			WriteError(w, NewUnauthorizedError("The API key is invalid or has been revoked"))
+			log.Printf("[ERROR] Invalid API key: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			return
+		}
+
+		ctx := WithKeyID(WithPolicy(r.Context(), a.Policy(key)), key.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bearerToken returns the token of an 'Authorization: Bearer' header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
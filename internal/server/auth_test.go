@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// policyRecorder records the policy each request was served with
+type policyRecorder struct {
+	handler *Handler
+	policy  *query.Policy
+}
+
+func (p *policyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	policy := p.handler.policyFor(r)
+	p.policy = &policy
+	w.WriteHeader(http.StatusOK)
+}
+
+func authRequest(handler http.Handler, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticator_NoKeysFile(t *testing.T) {
+	recorder := &policyRecorder{handler: NewHandler(&mockExecutor{})}
+	auth := NewAuthenticator(filepath.Join(t.TempDir(), "keys.json"), query.DefaultPolicy())
+
+	if enabled, err := auth.Enabled(); enabled || err != nil {
+		t.Errorf("Enabled() = %v, %v, want false without a keys file", enabled, err)
+	}
+	if w := authRequest(auth.Wrap(recorder), "/v1/query", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected requests to pass without a keys file, got %d", w.Code)
+	}
+}
+
+func TestAuthenticator_RequiresKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := &KeyStore{}
+	readOnly, readOnlySecret, _ := store.Create("bi", ScopeReadOnly)
+	_, adminSecret, _ := store.Create("ops", ScopeAdmin)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	recorder := &policyRecorder{handler: NewHandler(&mockExecutor{})}
+	auth := NewAuthenticator(path, query.DefaultPolicy())
+	handler := auth.Wrap(recorder)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{"missing key", "/v1/query", "", http.StatusUnauthorized},
+		{"wrong scheme", "/v1/query", "Basic " + readOnlySecret, http.StatusUnauthorized},
+		{"unknown key", "/v1/query", "Bearer vibe_000000000000_0000", http.StatusUnauthorized},
+		{"valid key", "/v1/query", "Bearer " + readOnlySecret, http.StatusOK},
+		{"lower-case scheme", "/v1/export", "bearer " + adminSecret, http.StatusOK},
+		{"health check", "/v1/health/ready", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := authRequest(handler, tt.path, tt.authorization)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+		if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate header", tt.name)
+		}
+	}
+
+	recorder.policy = nil
+	authRequest(handler, "/v1/query", "Bearer "+readOnlySecret)
+	if recorder.policy == nil || !recorder.policy.ReadOnly {
+		t.Errorf("Expected the read-only key's policy, got %+v", recorder.policy)
+	}
+	if recorder.policy.Role != "" {
+		t.Errorf("Expected no role for a key without one, got %q", recorder.policy.Role)
+	}
+
+	// Revoking the key in the file takes effect on the next request
+	store.Revoke(readOnly.ID)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if w := authRequest(handler, "/v1/query", "Bearer "+readOnlySecret); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked key to be rejected, got %d", w.Code)
+	}
+}
+
+func TestAuthenticator_KeyRole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := &KeyStore{}
+	key, secret, _ := store.Create("bi", ScopeReadOnly)
+	key.Role = "analyst"
+	_, otherSecret, _ := store.Create("app", ScopeReadWrite)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	base := query.DefaultPolicy()
+	base.Role = "reporting"
+	recorder := &policyRecorder{handler: NewHandler(&mockExecutor{})}
+	handler := NewAuthenticator(path, base).Wrap(recorder)
+
+	authRequest(handler, "/v1/query", "Bearer "+secret)
+	if recorder.policy == nil || recorder.policy.Role != "analyst" {
+		t.Errorf("Expected the key's role, got %+v", recorder.policy)
+	}
+	authRequest(handler, "/v1/query", "Bearer "+otherSecret)
+	if recorder.policy == nil || recorder.policy.Role != "reporting" {
+		t.Errorf("Expected the server's role for a key without one, got %+v", recorder.policy)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1": true,
+		"::1":       true,
+		"localhost": true,
+		"0.0.0.0":   false,
+		"10.0.0.5":  false,
+	}
+	for host, want := range tests {
+		if got := isLoopback(host); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// HandleBatch runs independent queries in one request, sequentially or in
+// parallel, and reports the outcome of each one
+func (h *Handler) HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/batch endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	var req BatchRequest
+	if vibeErr := decodeJSONBody(r, &req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid request body: %v", vibeErr)
+		return
+	}
+
+	if len(req.Queries) == 0 {
+		WriteError(w, NewMissingFieldError("queries"))
+		log.Printf("[ERROR] Missing required field: queries")
+		return
+	}
+
+	if len(req.Queries) > query.MaxBatchStatements {
+		WriteError(w, NewTooManyStatementsError(len(req.Queries), query.MaxBatchStatements))
+		log.Printf("[ERROR] Too many queries in batch: %d", len(req.Queries))
+		return
+	}
+
+	if !isValidFormat(req.Format) {
+		WriteError(w, NewInvalidFormatError(req.Format))
+		log.Printf("[ERROR] Invalid result format: %s", req.Format)
+		return
+	}
+
+	policy := h.policyFor(r)
+	statements, ok := h.readStatements(w, req.Queries, policy)
+	if !ok {
+		return
+	}
+
+	log.Printf("[INFO] Executing batch of %d queries (parallel: %t, stop on error: %t)", len(statements), req.Parallel, req.StopOnError)
+
+	opts := query.Options{
+		NumericAsString: req.NumericAsString,
+		ReadOnly:        policy.ReadOnly,
+		Role:            policy.Role,
+	}
+	batch := query.BatchOptions{
+		Parallel:    req.Parallel,
+		StopOnError: req.StopOnError,
+	}
+
+	startTime := time.Now()
+	results := h.executor.ExecuteBatch(statements, opts, batch)
+
+	response := &BatchResponse{
+		Results: make([]BatchItemResponse, len(results)),
+	}
+	for i, result := range results {
+		response.Results[i] = newBatchItemResponse(result, req.Format)
+		switch {
+		case result.Skipped:
+			response.Skipped++
+		case result.Err != nil:
+			response.Failed++
+			log.Printf("[ERROR] Batch query %d failed: %v", i, result.Err)
+		default:
+			response.Succeeded++
+		}
+	}
+	response.Success = response.Succeeded == len(results)
+	response.ExecutionTime = float64(time.Since(startTime).Microseconds()) / 1000.0
+
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Batch finished: %d succeeded, %d failed, %d skipped in %.2fms", response.Succeeded, response.Failed, response.Skipped, response.ExecutionTime)
+}
+
+// newBatchItemResponse converts the outcome of one batch query into its
+// response
+func newBatchItemResponse(result query.BatchResult, format string) BatchItemResponse {
+	switch {
+	case result.Skipped:
+		return BatchItemResponse{QueryResponse: &QueryResponse{}, Skipped: true}
+	case result.Err != nil:
+		return BatchItemResponse{QueryResponse: NewErrorResponse(result.Err)}
+	}
+
+	item := BatchItemResponse{QueryResponse: newResultResponse(result.Result)}
+	item.QueryResponse.Rows = nil
+	if format == FormatArray {
+		if len(result.Result.Values) > 0 {
+			item.Rows = result.Result.Values
+		}
+	} else if len(result.Result.Rows) > 0 {
+		item.Rows = result.Result.Rows
+	}
+	return item
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// batchExecutor fails statements containing "missing" and skips those
+// after a failure when asked to stop on error
+type batchExecutor struct {
+	mockExecutor
+	statements []query.Statement
+	opts       query.Options
+	batch      query.BatchOptions
+}
+
+func (e *batchExecutor) ExecuteBatch(statements []query.Statement, opts query.Options, batch query.BatchOptions) []query.BatchResult {
+	e.statements, e.opts, e.batch = statements, opts, batch
+
+	results := make([]query.BatchResult, len(statements))
+	failed := false
+	for i, stmt := range statements {
+		switch {
+		case failed && batch.StopOnError:
+			results[i].Skipped = true
+		case strings.Contains(stmt.SQL, "missing"):
+			results[i].Err = postgres.NewVibeError(postgres.ErrorCodeInvalidSQL, "Invalid SQL syntax", "PostgreSQL error: relation \"missing\" does not exist")
+			failed = true
+		default:
+			results[i].Result = &query.ExecutionResult{
+				Columns:      []query.Column{{Name: "n", Type: "int4", TypeOID: 23}},
+				Rows:         []map[string]interface{}{{"n": int64(i)}},
+				Values:       [][]interface{}{{int64(i)}},
+				RowCount:     1,
+				RowsAffected: 1,
+				CommandTag:   "SELECT 1",
+			}
+		}
+	}
+	return results
+}
+
+func postBatch(t *testing.T, handler *Handler, body string) (*httptest.ResponseRecorder, BatchResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleBatch(w, req)
+
+	var response BatchResponse
+	if err := json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w, response
+}
+
+func TestHandleBatch_Success(t *testing.T) {
+	executor := &batchExecutor{}
+	handler := NewHandler(executor)
+
+	w, response := postBatch(t, handler, `{"queries": [{"sql": "SELECT $1::int AS n", "params": [0]}, {"sql": "SELECT 1 AS n"}], "parallel": true}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !response.Success || response.Succeeded != 2 || response.Failed != 0 {
+		t.Errorf("Expected 2 successful queries, got %+v", response)
+	}
+	if len(executor.statements) != 2 || executor.statements[0].Params[0] != int64(0) {
+		t.Errorf("Expected both queries with bound params, got %+v", executor.statements)
+	}
+	if !executor.batch.Parallel || executor.batch.StopOnError {
+		t.Errorf("Expected parallel without stop on error, got %+v", executor.batch)
+	}
+	if len(response.Results) != 2 || response.Results[1].CommandTag != "SELECT 1" {
+		t.Fatalf("Expected per-query results, got %+v", response.Results)
+	}
+	if rows, ok := response.Results[1].Rows.([]interface{}); !ok || len(rows) != 1 {
+		t.Errorf("Expected one row in the second result, got %v", response.Results[1].Rows)
+	}
+}
+
+func TestHandleBatch_PerQueryErrors(t *testing.T) {
+	handler := NewHandler(&batchExecutor{})
+
+	w, response := postBatch(t, handler, `{"queries": [{"sql": "SELECT 1"}, {"sql": "SELECT * FROM missing"}, {"sql": "SELECT 2"}]}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if response.Success || response.Succeeded != 2 || response.Failed != 1 || response.Skipped != 0 {
+		t.Errorf("Expected 2 succeeded and 1 failed, got %+v", response)
+	}
+	failed := response.Results[1]
+	if failed.Success || failed.Error == nil || failed.Error.Code != ErrorCodeInvalidSQL {
+		t.Errorf("Expected the second query to fail with INVALID_SQL, got %+v", failed.QueryResponse)
+	}
+	if !response.Results[2].Success {
+		t.Error("Expected the third query to run after the failure")
+	}
+}
+
+func TestHandleBatch_StopOnError(t *testing.T) {
+	handler := NewHandler(&batchExecutor{})
+
+	_, response := postBatch(t, handler, `{"queries": [{"sql": "SELECT * FROM missing"}, {"sql": "SELECT 2"}], "stopOnError": true}`)
+
+	if response.Failed != 1 || response.Skipped != 1 {
+		t.Errorf("Expected 1 failed and 1 skipped, got %+v", response)
+	}
+	if !response.Results[1].Skipped || response.Results[1].Success {
+		t.Errorf("Expected the second query to be skipped, got %+v", response.Results[1])
+	}
+}
+
+func TestHandleBatch_ArrayFormat(t *testing.T) {
+	handler := NewHandler(&batchExecutor{})
+
+	w, _ := postBatch(t, handler, `{"queries": [{"sql": "SELECT 1 AS n"}], "format": "array"}`)
+
+	if !strings.Contains(w.Body.String(), `"rows":[[0]]`) {
+		t.Errorf("Expected positional rows, got %s", w.Body.String())
+	}
+}
+
+func TestHandleBatch_RejectsInvalidQuery(t *testing.T) {
+	executor := &batchExecutor{}
+	handler := NewHandler(executor)
+
+	w, response := postBatch(t, handler, `{"queries": [{"sql": "SELECT 1"}, {"sql": "DELETE FROM users"}]}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if response.Error == nil || response.Error.Statement == nil || *response.Error.Statement != 1 {
+		t.Errorf("Expected query 1 to be rejected, got %+v", response.Error)
+	}
+	if executor.statements != nil {
+		t.Error("Executor should not run a batch with a rejected query")
+	}
+}
+
+func TestHandleBatch_InvalidRequests(t *testing.T) {
+	tooMany := make([]string, query.MaxBatchStatements+1)
+	for i := range tooMany {
+		tooMany[i] = `{"sql": "SELECT 1"}`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"no queries", `{"queries": []}`, http.StatusBadRequest},
+		{"too many queries", `{"queries": [` + strings.Join(tooMany, ",") + `]}`, http.StatusRequestEntityTooLarge},
+		{"invalid format", `{"queries": [{"sql": "SELECT 1"}], "format": "csv"}`, http.StatusBadRequest},
+		{"missing sql", `{"queries": [{"params": [1]}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := postBatch(t, NewHandler(&batchExecutor{}), tt.body)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/batch", nil)
+	w := httptest.NewRecorder()
+	NewHandler(&batchExecutor{}).HandleBatch(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected GET to be rejected with 400, got %d", w.Code)
+	}
+}
+
+func TestHandleBatch_PolicyReadOnly(t *testing.T) {
+	executor := &batchExecutor{}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w, response := postBatch(t, handler, `{"queries": [{"sql": "SELECT 1"}, {"sql": "UPDATE users SET name = 'a' WHERE id = 1"}]}`)
+	if w.Code != http.StatusForbidden || response.Error == nil || *response.Error.Statement != 1 {
+		t.Errorf("Expected query 1 to be rejected with 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	postBatch(t, handler, `{"queries": [{"sql": "SELECT 1"}]}`)
+	if !executor.opts.ReadOnly {
+		t.Error("Expected batch queries to run read-only")
+	}
+}
+
+func TestHandleBatch_PolicyRole(t *testing.T) {
+	executor := &batchExecutor{}
+	policy := query.DefaultPolicy()
+	policy.Role = "analyst"
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), policy)
+
+	w, _ := postBatch(t, handler, `{"queries": [{"sql": "RESET ROLE"}]}`)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected RESET ROLE to be rejected with 403, got %d: %s", w.Code, w.Body.String())
+	}
+
+	postBatch(t, handler, `{"queries": [{"sql": "SELECT 1"}]}`)
+	if executor.opts.Role != "analyst" {
+		t.Errorf("Expected batch queries to run as analyst, got %q", executor.opts.Role)
+	}
+}
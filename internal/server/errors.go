@@ -23,6 +23,10 @@ const (
 	ErrorCodeInternalError        = postgres.ErrorCodeInternalError
 	ErrorCodeServiceUnavailable   = postgres.ErrorCodeServiceUnavailable
 	ErrorCodeDatabaseUnavailable  = postgres.ErrorCodeDatabaseUnavailable
+	ErrorCodeTransactionNotFound  = postgres.ErrorCodeTransactionNotFound
+	ErrorCodeCursorNotFound       = postgres.ErrorCodeCursorNotFound
+	ErrorCodeStatementNotAllowed  = postgres.ErrorCodeStatementNotAllowed
+	ErrorCodeUnauthorized         = postgres.ErrorCodeUnauthorized
 )
 
 // GetHTTPStatusCode returns the HTTP status code for a given VibeSQL error code
@@ -50,6 +54,24 @@ func NewInvalidSQLError(message string) *postgres.VibeError {
 	)
 }
 
+// NewInvalidParamsError creates an error for query parameters that cannot be bound
+func NewInvalidParamsError(detail string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		ErrorCodeInvalidSQL,
+		"Invalid query parameters",
+		detail,
+	)
+}
+
+// NewInvalidFormatError creates an error for an unsupported result format
+func NewInvalidFormatError(format string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		ErrorCodeInvalidSQL,
+		"Invalid result format",
+		fmt.Sprintf("Unsupported format '%s'. Use 'object' or 'array'", format),
+	)
+}
+
 // NewUnsafeQueryError creates an error for unsafe queries (UPDATE/DELETE without WHERE)
 func NewUnsafeQueryError(queryType string) *postgres.VibeError {
 	return postgres.NewVibeError(
@@ -77,6 +99,15 @@ func NewQueryTooLargeError(actualSize, maxSize int) *postgres.VibeError {
 	)
 }
 
+// NewTooManyStatementsError creates an error for multi-statement requests exceeding the statement limit
+func NewTooManyStatementsError(actual, max int) *postgres.VibeError {
+	return postgres.NewVibeError(
+		ErrorCodeQueryTooLarge,
+		"Too many statements",
+		fmt.Sprintf("Request contains %d statements, exceeding the maximum of %d", actual, max),
+	)
+}
+
 // NewResultTooLargeError creates an error for result sets exceeding row limit
 func NewResultTooLargeError(actualRows, maxRows int) *postgres.VibeError {
 	return postgres.NewVibeError(
@@ -123,6 +154,15 @@ func NewDatabaseUnavailableError(reason string) *postgres.VibeError {
 	)
 }
 
+// NewUnauthorizedError creates an error for a request without a valid API key
+func NewUnauthorizedError(detail string) *postgres.VibeError {
+	return postgres.NewVibeError(
+		ErrorCodeUnauthorized,
+		"Unauthorized",
+		detail,
+	)
+}
+
 // HTTPErrorCodeMapping maps VibeSQL error codes to HTTP status codes for reference.
 // This map serves as documentation and is used in testing to verify consistency
 // with the postgres package implementation.
@@ -137,6 +177,10 @@ var HTTPErrorCodeMapping = map[string]int{
 	ErrorCodeInternalError:        http.StatusInternalServerError,  // 500
 	ErrorCodeServiceUnavailable:   http.StatusServiceUnavailable,   // 503
 	ErrorCodeDatabaseUnavailable:  http.StatusServiceUnavailable,   // 503
+	ErrorCodeTransactionNotFound:  http.StatusNotFound,             // 404
+	ErrorCodeCursorNotFound:       http.StatusNotFound,             // 404
+	ErrorCodeStatementNotAllowed:  http.StatusForbidden,            // 403
+	ErrorCodeUnauthorized:         http.StatusUnauthorized,         // 401
 }
 
 // ValidateHTTPStatusMapping validates that all error codes have correct HTTP status mappings.
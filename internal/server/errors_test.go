@@ -385,6 +385,10 @@ func TestErrorCodeConstants(t *testing.T) {
 		{"INTERNAL_ERROR", ErrorCodeInternalError, postgres.ErrorCodeInternalError},
 		{"SERVICE_UNAVAILABLE", ErrorCodeServiceUnavailable, postgres.ErrorCodeServiceUnavailable},
 		{"DATABASE_UNAVAILABLE", ErrorCodeDatabaseUnavailable, postgres.ErrorCodeDatabaseUnavailable},
+		{"TRANSACTION_NOT_FOUND", ErrorCodeTransactionNotFound, postgres.ErrorCodeTransactionNotFound},
+		{"CURSOR_NOT_FOUND", ErrorCodeCursorNotFound, postgres.ErrorCodeCursorNotFound},
+		{"STATEMENT_NOT_ALLOWED", ErrorCodeStatementNotAllowed, postgres.ErrorCodeStatementNotAllowed},
+		{"UNAUTHORIZED", ErrorCodeUnauthorized, postgres.ErrorCodeUnauthorized},
 	}
 
 	for _, tt := range tests {
@@ -465,6 +469,9 @@ func TestErrorHelperReturnTypes(t *testing.T) {
 func TestAllHTTPStatusCodesInRange(t *testing.T) {
 	validStatuses := map[int]bool{
 		400: true, // Bad Request
+		401: true, // Unauthorized
+		403: true, // Forbidden
+		404: true, // Not Found
 		408: true, // Request Timeout
 		413: true, // Payload Too Large
 		500: true, // Internal Server Error
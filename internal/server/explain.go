@@ -0,0 +1,121 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// HandleExplain returns the plan of a statement, like /v1/query with
+// "explain": true
+func (h *Handler) HandleExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/explain endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	req, params, ok := h.readQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	h.handleExplain(w, req, params)
+}
+
+// handleExplain runs EXPLAIN (FORMAT JSON) for a validated statement
+func (h *Handler) handleExplain(w http.ResponseWriter, req *QueryRequest, params []interface{}) {
+	if vibeErr := checkPlanMode(req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid EXPLAIN request: %v", vibeErr)
+		return
+	}
+
+	result, err := h.executor.Explain(req.SQL, params, req.options(), req.explainOptions())
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] EXPLAIN failed: %v", err)
+		return
+	}
+
+	response := &ExplainResponse{
+		Success:       true,
+		Plan:          result.Plan,
+		ExecutionTime: float64(result.ExecutionTime.Microseconds()) / 1000.0,
+	}
+	if result.Analyzed {
+		planning := float64(result.PlanningTime.Microseconds()) / 1000.0
+		actual := float64(result.RunTime.Microseconds()) / 1000.0
+		response.PlanningTime = &planning
+		response.ActualTime = &actual
+	}
+
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] EXPLAIN succeeded in %.2fms (analyze: %v)", response.ExecutionTime, req.Analyze)
+}
+
+// handleDryRun runs validated statements in a rolled-back transaction and
+// reports the rows they affected
+func (h *Handler) handleDryRun(w http.ResponseWriter, req *QueryRequest, params []interface{}) {
+	if vibeErr := checkPlanMode(req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid dry run request: %v", vibeErr)
+		return
+	}
+
+	result, err := h.executor.DryRun(req.SQL, params, req.options())
+	if err != nil {
+		var stmtErr *query.StatementError
+		if errors.As(err, &stmtErr) {
+			WriteStatementError(w, stmtErr.Err, stmtErr.Index)
+		} else {
+			WriteError(w, asVibeError(err))
+		}
+		log.Printf("[ERROR] Dry run failed: %v", err)
+		return
+	}
+
+	response := &DryRunResponse{
+		Success:       true,
+		DryRun:        true,
+		Statements:    make([]StatementCount, len(result.Statements)),
+		RowsAffected:  result.RowsAffected,
+		ExecutionTime: float64(result.ExecutionTime.Microseconds()) / 1000.0,
+	}
+	for i, count := range result.Statements {
+		response.Statements[i] = StatementCount{
+			Command:      count.Command,
+			CommandTag:   count.CommandTag,
+			RowsAffected: count.RowsAffected,
+		}
+	}
+
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Dry run succeeded: %d rows affected and rolled back in %.2fms", result.RowsAffected, response.ExecutionTime)
+}
+
+// checkPlanMode rejects explain and dry run requests that also ask for
+// another way of returning results
+func checkPlanMode(req *QueryRequest) *postgres.VibeError {
+	switch {
+	case req.Explain && req.DryRun:
+		return NewInvalidSQLError("'explain' and 'dryRun' cannot be combined")
+	case req.paginated() || req.Truncate || req.Stream:
+		return NewInvalidSQLError("'limit', 'cursor', 'truncate' and 'stream' cannot be combined with 'explain' or 'dryRun'")
+	case req.DryRun && (req.Analyze || req.Buffers):
+		return NewInvalidSQLError("'analyze' and 'buffers' require 'explain'")
+	}
+	return nil
+}
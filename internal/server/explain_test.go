@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+type explainExecutor struct {
+	mockExecutor
+	call    string
+	sql     string
+	explain query.ExplainOptions
+	failAt  int
+}
+
+func (e *explainExecutor) Explain(sql string, params []interface{}, opts query.Options, explain query.ExplainOptions) (*query.ExplainResult, error) {
+	e.call, e.sql, e.explain = "explain", sql, explain
+	result := &query.ExplainResult{
+		Plan: map[string]interface{}{
+			"Node Type": "Seq Scan",
+			"Plans":     []interface{}{map[string]interface{}{"Node Type": "Index Scan"}},
+		},
+		Analyzed: explain.Analyze,
+	}
+	if explain.Analyze {
+		result.PlanningTime = 1500 * time.Microsecond
+		result.RunTime = 2500 * time.Microsecond
+	}
+	return result, nil
+}
+
+func (e *explainExecutor) DryRun(sql string, params []interface{}, opts query.Options) (*query.DryRunResult, error) {
+	e.call, e.sql = "dryRun", sql
+	if e.failAt > 0 {
+		return nil, &query.StatementError{
+			Index: e.failAt,
+			Err:   postgres.NewVibeError(postgres.ErrorCodeInvalidSQL, "Invalid SQL syntax", "PostgreSQL error: relation \"missing\" does not exist"),
+		}
+	}
+	return &query.DryRunResult{
+		Statements:   []query.StatementCount{{Command: "UPDATE", RowsAffected: 42}, {Command: "DELETE", RowsAffected: 3}},
+		RowsAffected: 45,
+	}, nil
+}
+
+func postExplain(handler *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/explain", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleExplain(w, req)
+	return w
+}
+
+func TestHandleExplain(t *testing.T) {
+	executor := &explainExecutor{}
+	w := postExplain(NewHandler(executor), `{"sql": "SELECT * FROM users WHERE id = $1", "params": [1]}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response ExplainResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Success || response.Plan["Node Type"] != "Seq Scan" {
+		t.Errorf("Unexpected plan: %+v", response)
+	}
+	if plans, ok := response.Plan["Plans"].([]interface{}); !ok || len(plans) != 1 {
+		t.Errorf("Expected child plans to be returned, got %v", response.Plan["Plans"])
+	}
+	if response.PlanningTime != nil || response.ActualTime != nil {
+		t.Error("Expected no timings without analyze")
+	}
+	if executor.explain.Analyze || executor.explain.Buffers {
+		t.Errorf("Unexpected explain options: %+v", executor.explain)
+	}
+}
+
+func TestHandleExplain_Analyze(t *testing.T) {
+	executor := &explainExecutor{}
+	w := postExplain(NewHandler(executor), `{"sql": "UPDATE users SET name = 'x' WHERE id = 1", "analyze": true, "buffers": true}`)
+
+	var response ExplainResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !executor.explain.Analyze || !executor.explain.Buffers {
+		t.Errorf("Expected analyze and buffers to be passed, got %+v", executor.explain)
+	}
+	if response.PlanningTime == nil || *response.PlanningTime != 1.5 || response.ActualTime == nil || *response.ActualTime != 2.5 {
+		t.Errorf("Unexpected timings: planning=%v actual=%v", response.PlanningTime, response.ActualTime)
+	}
+}
+
+func TestHandleExplain_SafetyChecked(t *testing.T) {
+	executor := &explainExecutor{}
+	w := postExplain(NewHandler(executor), `{"sql": "DELETE FROM users", "analyze": true}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if executor.call != "" {
+		t.Error("Executor should not run an unsafe statement")
+	}
+}
+
+func TestHandleQuery_Explain(t *testing.T) {
+	executor := &explainExecutor{}
+	w, _ := postQuery(NewHandler(executor), `{"sql": "SELECT 1", "explain": true}`)
+
+	if w.Code != http.StatusOK || executor.call != "explain" {
+		t.Fatalf("Expected the query to be explained, got status %d and call %q", w.Code, executor.call)
+	}
+	if !strings.Contains(w.Body.String(), `"plan"`) {
+		t.Errorf("Expected a plan in the response, got %s", w.Body.String())
+	}
+}
+
+func TestHandleQuery_DryRun(t *testing.T) {
+	executor := &explainExecutor{}
+	w, _ := postQuery(NewHandler(executor), `{"sql": "UPDATE users SET active = false WHERE age > 30; DELETE FROM sessions WHERE expired", "dryRun": true}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response DryRunResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Success || !response.DryRun || response.RowsAffected != 45 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if len(response.Statements) != 2 || response.Statements[0].Command != "UPDATE" || response.Statements[0].RowsAffected != 42 {
+		t.Errorf("Unexpected statement counts: %+v", response.Statements)
+	}
+}
+
+func TestHandleQuery_DryRunStatementError(t *testing.T) {
+	executor := &explainExecutor{failAt: 1}
+	w, response := postQuery(NewHandler(executor), `{"sql": "UPDATE a SET x = 1 WHERE id = 1; UPDATE missing SET x = 1 WHERE id = 1", "dryRun": true}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if response.Error == nil || response.Error.Statement == nil || *response.Error.Statement != 1 {
+		t.Errorf("Expected statement 1 to be reported, got %+v", response.Error)
+	}
+}
+
+func TestHandleQuery_ExplainInvalidCombinations(t *testing.T) {
+	for _, body := range []string{
+		`{"sql": "SELECT 1", "explain": true, "dryRun": true}`,
+		`{"sql": "SELECT 1", "explain": true, "limit": 10}`,
+		`{"sql": "SELECT 1", "explain": true, "stream": true}`,
+		`{"sql": "SELECT 1", "dryRun": true, "truncate": true}`,
+		`{"sql": "SELECT 1", "dryRun": true, "analyze": true}`,
+		`{"sql": "SELECT 1", "analyze": true}`,
+		`{"cursor": "abc", "explain": true}`,
+	} {
+		executor := &explainExecutor{}
+		w, _ := postQuery(NewHandler(executor), body)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+		if executor.call != "" {
+			t.Errorf("%s: should not reach the executor", body)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/vibesql/vibe/internal/export"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// exportFormats maps Accept media types to export formats
+var exportFormats = func() map[string]string {
+	m := make(map[string]string, len(export.ContentTypes))
+	for format, contentType := range export.ContentTypes {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		m[mediaType] = format
+	}
+	return m
+}()
+
+// acceptedExportFormat returns the first export format the client accepts,
+// or "" if none
+func acceptedExportFormat(r *http.Request) string {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if format, ok := exportFormats[mediaType]; err == nil && ok {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+// exportResponse sends the response status and headers with the first
+// byte of the file, so errors before it can still be returned as a regular
+// error response
+type exportResponse struct {
+	w       http.ResponseWriter
+	format  string
+	started bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.w.Header().Set("Content-Type", export.ContentTypes[e.format])
+		e.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "export." + e.format}))
+		e.w.Header().Set("X-Content-Type-Options", "nosniff")
+		e.w.WriteHeader(http.StatusOK)
+		e.started = true
+	}
+	return e.w.Write(p)
+}
+
+// HandleExport serves /v1/export, streaming a query's result as a CSV,
+// NDJSON, Parquet or SQL file. Rows are encoded as they are read, so the
+// row limit does not apply; the query timeout still bounds the export.
+func (h *Handler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/export endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	req := &ExportRequest{}
+	if vibeErr := decodeJSONBody(r, req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid request body: %v", vibeErr)
+		return
+	}
+
+	if req.SQL == "" {
+		WriteError(w, NewMissingFieldError("sql"))
+		log.Printf("[ERROR] Missing required field: sql")
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = acceptedExportFormat(r)
+	}
+	if _, ok := export.ContentTypes[req.Format]; !ok {
+		vibeErr := NewInvalidSQLError(fmt.Sprintf("Unsupported export format '%s'. Use 'csv', 'ndjson', 'parquet' or 'sql'", req.Format))
+		if req.Format == "" {
+			vibeErr = NewInvalidSQLError("Set 'format' to csv, ndjson, parquet or sql, or send a matching Accept header")
+		}
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid export format: %s", req.Format)
+		return
+	}
+
+	if req.Table != "" && req.Format != export.FormatSQL {
+		WriteError(w, NewInvalidSQLError("'table' only applies to sql exports"))
+		log.Printf("[ERROR] Export table given for %s format", req.Format)
+		return
+	}
+
+	params, policy, ok := h.checkQuery(w, r, req.SQL, req.Params)
+	if !ok {
+		return
+	}
+
+	response := &exportResponse{w: w, format: req.Format}
+	writer, err := export.NewWriter(req.Format, response, export.Options{Table: req.Table})
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Export failed: %v", err)
+		return
+	}
+
+	opts := query.Options{
+		NumericAsString: req.NumericAsString,
+		ReadOnly:        policy.ReadOnly,
+		Role:            policy.Role,
+	}
+	result, err := h.executor.ExecuteStream(req.SQL, params, opts, writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil && !response.started {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Export failed: %v", err)
+		return
+	}
+	if err != nil {
+		// The file is incomplete. Abort the response rather than end it
+		// cleanly, so the client cannot mistake it for the whole result.
+		log.Printf("[ERROR] Export failed after the response started: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	log.Printf("[INFO] Query exported as %s: %d rows in %.2fms", req.Format, result.RowCount, float64(result.ExecutionTime.Microseconds())/1000.0)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+func postExport(handler *Handler, body string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/export", strings.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.HandleExport(w, req)
+	return w
+}
+
+func TestHandleExport_CSV(t *testing.T) {
+	executor := &streamExecutor{rows: 2}
+	w := postExport(NewHandler(executor), `{"sql": "SELECT id, name FROM items", "format": "csv"}`, "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Expected CSV content type, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=export.csv` {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+	if got, want := w.Body.String(), "id,name\n1,row\n2,row\n"; got != want {
+		t.Errorf("Expected body %q, got %q", want, got)
+	}
+}
+
+func TestHandleExport_Formats(t *testing.T) {
+	tests := []struct {
+		body   string
+		accept string
+		check  func(body string) bool
+	}{
+		{`{"sql": "SELECT 1", "format": "ndjson"}`, "", func(body string) bool {
+			return strings.HasPrefix(body, `{"id":1,"name":"row"}`+"\n")
+		}},
+		{`{"sql": "SELECT 1"}`, "application/x-ndjson", func(body string) bool {
+			return strings.HasPrefix(body, `{"id":1,"name":"row"}`+"\n")
+		}},
+		{`{"sql": "SELECT 1"}`, "text/html, application/vnd.apache.parquet", func(body string) bool {
+			return strings.HasPrefix(body, "PAR1") && strings.HasSuffix(body, "PAR1")
+		}},
+		{`{"sql": "SELECT 1", "format": "sql", "table": "public.items"}`, "", func(body string) bool {
+			return strings.HasPrefix(body, `INSERT INTO "public"."items" ("id", "name") VALUES (1, 'row');`)
+		}},
+	}
+
+	for _, tt := range tests {
+		w := postExport(NewHandler(&streamExecutor{rows: 3}), tt.body, tt.accept)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", tt.body, w.Code, w.Body.String())
+			continue
+		}
+		if !tt.check(w.Body.String()) {
+			t.Errorf("%s (Accept %q): unexpected body %q", tt.body, tt.accept, w.Body.String())
+		}
+	}
+}
+
+func TestHandleExport_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing sql", `{"format": "csv"}`},
+		{"missing format", `{"sql": "SELECT 1"}`},
+		{"unknown format", `{"sql": "SELECT 1", "format": "xlsx"}`},
+		{"table without sql format", `{"sql": "SELECT 1", "format": "csv", "table": "items"}`},
+		{"invalid body", `{"sql": "SELECT 1", "format": "csv"`},
+	}
+
+	for _, tt := range tests {
+		executor := &streamExecutor{rows: 1}
+		w := postExport(NewHandler(executor), tt.body, "")
+		if w.Code == http.StatusOK {
+			t.Errorf("%s: expected an error, got status 200", tt.name)
+		}
+		if executor.called {
+			t.Errorf("%s: executor should not be called", tt.name)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/export", nil)
+	w := httptest.NewRecorder()
+	NewHandler(&streamExecutor{}).HandleExport(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GET: expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleExport_Policy(t *testing.T) {
+	executor := &streamExecutor{rows: 1}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w := postExport(handler, `{"sql": "DELETE FROM items RETURNING *", "format": "csv"}`, "")
+	if w.Code == http.StatusOK || executor.called {
+		t.Errorf("Expected the read-only policy to reject the export, got status %d", w.Code)
+	}
+}
+
+func TestHandleExport_ErrorBeforeOutput(t *testing.T) {
+	executor := &streamExecutor{failBefore: true}
+	w := postExport(NewHandler(executor), `{"sql": "SELEC 1", "format": "parquet"}`, "")
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", w.Code)
+	}
+	var response QueryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error == nil {
+		t.Fatalf("Expected a JSON error response, got %q", w.Body.String())
+	}
+}
+
+func TestHandleExport_ErrorAfterOutput(t *testing.T) {
+	executor := &streamExecutor{rows: 5000, failAfter: 4000}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/export", strings.NewReader(`{"sql": "SELECT 1", "format": "csv"}`))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Fatalf("Expected the response to be aborted, got %v", recovered)
+		}
+		if !bytes.HasPrefix(w.Body.Bytes(), []byte("id,name\n")) {
+			t.Errorf("Expected the partial file to have been sent")
+		}
+	}()
+	NewHandler(executor).HandleExport(w, req)
+}
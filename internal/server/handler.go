@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -12,11 +14,29 @@ import (
 
 type Handler struct {
 	executor query.QueryExecutor
+	limits   query.Limits
+	policy   query.Policy
 }
 
+// NewHandler creates a handler that validates queries against the default limits
 func NewHandler(executor query.QueryExecutor) *Handler {
+	return NewHandlerWithLimits(executor, query.DefaultLimits())
+}
+
+// NewHandlerWithLimits creates a handler that validates queries against the
+// given limits. They should match the limits the executor enforces.
+func NewHandlerWithLimits(executor query.QueryExecutor, limits query.Limits) *Handler {
+	return NewHandlerWithPolicy(executor, limits, query.DefaultPolicy())
+}
+
+// NewHandlerWithPolicy creates a handler that validates queries against the
+// given limits and enforces the given safety policy, unless a request
+// carries its own policy (see WithPolicy)
+func NewHandlerWithPolicy(executor query.QueryExecutor, limits query.Limits, policy query.Policy) *Handler {
 	return &Handler{
 		executor: executor,
+		limits:   limits.WithDefaults(),
+		policy:   policy,
 	}
 }
 
@@ -28,73 +48,185 @@ func (h *Handler) HandleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	defer r.Body.Close()
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		vibeErr := NewInternalError("Failed to read request body: " + err.Error())
-		WriteError(w, vibeErr)
-		log.Printf("[ERROR] Failed to read request body: %v", err)
+	req, params, ok := h.readQueryRequest(w, r)
+	if !ok {
 		return
 	}
 
-	var req QueryRequest
-	if err := json.Unmarshal(body, &req); err != nil {
-		vibeErr := NewInvalidSQLError("Invalid JSON request body")
-		WriteError(w, vibeErr)
-		log.Printf("[ERROR] Invalid JSON: %v", err)
+	switch {
+	case req.Explain:
+		h.handleExplain(w, req, params)
 		return
-	}
-
-	if req.SQL == "" {
-		vibeErr := NewMissingFieldError("sql")
-		WriteError(w, vibeErr)
-		log.Printf("[ERROR] Missing required field: sql")
+	case req.DryRun:
+		h.handleDryRun(w, req, params)
 		return
-	}
-
-	log.Printf("[INFO] Executing query: %.100s...", req.SQL)
-
-	if err := query.ValidateQuery(req.SQL); err != nil {
-		if vibeErr, ok := err.(*postgres.VibeError); ok {
-			WriteError(w, vibeErr)
-		} else {
-			WriteError(w, NewInternalError(err.Error()))
-		}
-		log.Printf("[ERROR] Query validation failed: %v", err)
+	case req.Analyze || req.Buffers:
+		WriteError(w, NewInvalidSQLError("'analyze' and 'buffers' require 'explain'"))
+		log.Printf("[ERROR] EXPLAIN options requested without explain")
 		return
 	}
 
-	if err := query.CheckSafety(req.SQL); err != nil {
-		if vibeErr, ok := err.(*postgres.VibeError); ok {
-			WriteError(w, vibeErr)
-		} else {
-			WriteError(w, NewInternalError(err.Error()))
-		}
-		log.Printf("[ERROR] Query safety check failed: %v", err)
+	if req.Stream || acceptsNDJSON(r) {
+		h.handleStream(w, req, params)
 		return
 	}
 
-	result, err := h.executor.Execute(req.SQL)
+	var result *query.ExecutionResult
+	var err error
+	switch {
+	case req.Cursor != "":
+		result, err = h.executor.FetchPage(req.Cursor, req.options(), req.Limit)
+	case req.Limit != 0:
+		result, err = h.executor.ExecutePage(req.SQL, params, req.options(), req.Limit)
+	default:
+		result, err = h.executor.ExecuteWithOptions(req.SQL, params, req.options())
+	}
 	if err != nil {
-		if vibeErr, ok := err.(*postgres.VibeError); ok {
-			WriteError(w, vibeErr)
-		} else {
-			WriteError(w, NewInternalError(err.Error()))
-		}
+		WriteError(w, asVibeError(err))
 		log.Printf("[ERROR] Query execution failed: %v", err)
 		return
 	}
 
 	executionTimeMs := float64(result.ExecutionTime.Microseconds()) / 1000.0
 
-	if err := WriteSuccess(w, result.Rows, executionTimeMs); err != nil {
+	if err := WriteResult(w, result, req.Format); err != nil {
 		log.Printf("[ERROR] Failed to write response: %v", err)
 		return
 	}
 
-	log.Printf("[INFO] Query succeeded: %d rows returned in %.2fms", result.RowCount, executionTimeMs)
+	switch {
+	case result.Truncated:
+		log.Printf("[INFO] Query succeeded: result truncated to %d rows in %.2fms", result.RowCount, executionTimeMs)
+	case len(result.Columns) == 0 && result.CommandTag != "":
+		log.Printf("[INFO] Query succeeded: %s in %.2fms", result.CommandTag, executionTimeMs)
+	case result.NextCursor != "":
+		log.Printf("[INFO] Query succeeded: page of %d rows returned in %.2fms, more rows in cursor %s", result.RowCount, executionTimeMs, result.NextCursor)
+	default:
+		log.Printf("[INFO] Query succeeded: %d rows returned in %.2fms", result.RowCount, executionTimeMs)
+	}
 }
 
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/v1/query", h.HandleQuery)
+	mux.HandleFunc("/v1/explain", h.HandleExplain)
+	mux.HandleFunc("/v1/transaction", h.HandleTransaction)
+	mux.HandleFunc("/v1/batch", h.HandleBatch)
+	mux.HandleFunc(importPathPrefix, h.HandleImport)
+	mux.HandleFunc("/v1/export", h.HandleExport)
+	mux.HandleFunc("/v1/tx", h.HandleTxBegin)
+	mux.HandleFunc(txPathPrefix, h.HandleTxAction)
+}
+
+// readQueryRequest decodes, validates and safety-checks a QueryRequest,
+// writing an error response and returning ok=false if it is rejected
+func (h *Handler) readQueryRequest(w http.ResponseWriter, r *http.Request) (req *QueryRequest, params []interface{}, ok bool) {
+	req = &QueryRequest{}
+	if vibeErr := decodeJSONBody(r, req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid request body: %v", vibeErr)
+		return nil, nil, false
+	}
+
+	if req.SQL == "" && req.Cursor == "" {
+		vibeErr := NewMissingFieldError("sql")
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Missing required field: sql")
+		return nil, nil, false
+	}
+
+	if !isValidFormat(req.Format) {
+		vibeErr := NewInvalidFormatError(req.Format)
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid result format: %s", req.Format)
+		return nil, nil, false
+	}
+
+	req.owner = keyIDFor(r)
+
+	// A cursor continues a query that was validated when it was opened. Its
+	// rows may only be read under the same policy.
+	if req.Cursor != "" {
+		policy := h.policyFor(r)
+		req.readOnly = policy.ReadOnly
+		req.role = policy.Role
+		return req, nil, true
+	}
+
+	params, policy, ok := h.checkQuery(w, r, req.SQL, req.Params)
+	if !ok {
+		return nil, nil, false
+	}
+	req.readOnly = policy.ReadOnly
+	req.role = policy.Role
+
+	return req, params, true
+}
+
+// checkQuery validates and safety-checks a query and binds its params,
+// writing an error response and returning ok=false if it is rejected. The
+// policy it was checked against decides whether the query runs READ ONLY
+// and as which role.
+func (h *Handler) checkQuery(w http.ResponseWriter, r *http.Request, sql string, rawParams []interface{}) (params []interface{}, policy query.Policy, ok bool) {
+	log.Printf("[INFO] Executing query: %.100s...", sql)
+
+	if err := h.limits.ValidateQuery(sql); err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Query validation failed: %v", err)
+		return nil, policy, false
+	}
+
+	policy = h.policyFor(r)
+	if err := policy.Check(sql); err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Query safety check failed (%s policy): %v", policy.Name(), err)
+		return nil, policy, false
+	}
+
+	params, err := query.BindParams(rawParams)
+	if err != nil {
+		WriteError(w, NewInvalidParamsError(err.Error()))
+		log.Printf("[ERROR] Invalid query params: %v", err)
+		return nil, policy, false
+	}
+	if err := query.CheckParams(sql, params); err != nil {
+		WriteError(w, NewInvalidParamsError(err.Error()))
+		log.Printf("[ERROR] Invalid query params: %v", err)
+		return nil, policy, false
+	}
+
+	return params, policy, true
+}
+
+// decodeJSONBody reads the request body into v, decoding JSON numbers as
+// json.Number so query parameters keep their precision
+func decodeJSONBody(r *http.Request, v interface{}) *postgres.VibeError {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var vibeErr *postgres.VibeError
+		if errors.As(err, &vibeErr) {
+			return vibeErr
+		}
+		return NewInternalError("Failed to read request body: " + err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return NewInvalidSQLError("Invalid JSON request body")
+	}
+	return nil
+}
+
+// asVibeError returns err as a VibeError, wrapping unknown errors as internal errors
+func asVibeError(err error) *postgres.VibeError {
+	var vibeErr *postgres.VibeError
+	if errors.As(err, &vibeErr) {
+		return vibeErr
+	}
+	return NewInternalError(err.Error())
+}
+
+func isValidFormat(format string) bool {
+	return format == "" || format == FormatObject || format == FormatArray
 }
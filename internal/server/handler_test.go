@@ -706,3 +706,404 @@ func TestHandleQuery_Concurrency(t *testing.T) {
 		t.Errorf("Expected %d successful requests, got %d", numRequests, successCount)
 	}
 }
+
+type recordingExecutor struct {
+	mockExecutor
+	sql    string
+	params []interface{}
+	opts   query.Options
+}
+
+func (r *recordingExecutor) ExecuteWithOptions(sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	r.sql = sql
+	r.params = params
+	r.opts = opts
+	return &query.ExecutionResult{}, nil
+}
+
+func TestHandleQuery_Params(t *testing.T) {
+	executor := &recordingExecutor{}
+	handler := NewHandler(executor)
+
+	body := `{"sql": "SELECT * FROM users WHERE id = $1 AND name = $2 AND data @> $3", "params": [7, "O'Brien", {"active": true}]}`
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(executor.params) != 3 {
+		t.Fatalf("Expected 3 params, got %d", len(executor.params))
+	}
+
+	if executor.params[0] != int64(7) {
+		t.Errorf("Expected $1 = int64(7), got %v (%T)", executor.params[0], executor.params[0])
+	}
+
+	if executor.params[1] != "O'Brien" {
+		t.Errorf("Expected $2 = O'Brien, got %v", executor.params[1])
+	}
+
+	if executor.params[2] != `{"active":true}` {
+		t.Errorf("Expected $3 = JSON text, got %v", executor.params[2])
+	}
+}
+
+func TestHandleQuery_NoParams(t *testing.T) {
+	executor := &recordingExecutor{}
+	handler := NewHandler(executor)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "SELECT 1"}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	if len(executor.params) != 0 {
+		t.Errorf("Expected no params, got %v", executor.params)
+	}
+}
+
+func TestHandleQuery_ParamCountMismatch(t *testing.T) {
+	executor := &recordingExecutor{}
+	handler := NewHandler(executor)
+
+	for _, body := range []string{
+		`{"sql": "SELECT * FROM users WHERE id = $1 AND name = $2", "params": [7]}`,
+		`{"sql": "SELECT * FROM users WHERE id = $1"}`,
+		`{"sql": "SELECT 1", "params": [7]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.HandleQuery(w, req)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid query parameters") {
+			t.Errorf("%s: expected 400 Invalid query parameters, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	if executor.sql != "" {
+		t.Errorf("Expected mismatched queries not to run, got %q", executor.sql)
+	}
+}
+
+func TestHandleQuery_NumericAsString(t *testing.T) {
+	executor := &recordingExecutor{}
+	handler := NewHandler(executor)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "SELECT 1.5::numeric", "numericAsString": true}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	if !executor.opts.NumericAsString {
+		t.Error("Expected NumericAsString option to be passed to executor")
+	}
+}
+
+type fixedResultExecutor struct {
+	mockExecutor
+	result *query.ExecutionResult
+}
+
+func (f *fixedResultExecutor) ExecuteWithOptions(sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	return f.result, nil
+}
+
+func newDuplicateColumnsExecutor() *fixedResultExecutor {
+	return &fixedResultExecutor{
+		result: &query.ExecutionResult{
+			Columns: []query.Column{
+				{Name: "id", Type: "int4", TypeOID: 23},
+				{Name: "id", Type: "text", TypeOID: 25},
+			},
+			Rows:     []map[string]interface{}{{"id": "b"}},
+			Values:   [][]interface{}{{int64(1), "b"}},
+			RowCount: 1,
+		},
+	}
+}
+
+func TestHandleQuery_ColumnMetadata(t *testing.T) {
+	handler := NewHandler(newDuplicateColumnsExecutor())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "SELECT a.id, b.id FROM a, b"}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(response.Columns) != 2 {
+		t.Fatalf("Expected 2 columns, got %d", len(response.Columns))
+	}
+
+	if response.Columns[0].Type != "int4" || response.Columns[0].TypeOID != 23 {
+		t.Errorf("Expected first column int4 (23), got %s (%d)", response.Columns[0].Type, response.Columns[0].TypeOID)
+	}
+
+	if len(response.Rows) != 1 {
+		t.Errorf("Expected 1 object row, got %d", len(response.Rows))
+	}
+}
+
+func TestHandleQuery_ArrayFormat(t *testing.T) {
+	handler := NewHandler(newDuplicateColumnsExecutor())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "SELECT a.id, b.id FROM a, b", "format": "array"}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Success  bool            `json:"success"`
+		Columns  []ColumnInfo    `json:"columns"`
+		Rows     [][]interface{} `json:"rows"`
+		RowCount int             `json:"rowCount"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if !response.Success {
+		t.Fatal("Expected success=true")
+	}
+
+	if response.RowCount != 1 {
+		t.Errorf("Expected rowCount=1, got %d", response.RowCount)
+	}
+
+	if len(response.Rows) != 1 || len(response.Rows[0]) != 2 {
+		t.Fatalf("Expected one row with 2 positional values, got %v", response.Rows)
+	}
+
+	if response.Rows[0][0] != float64(1) || response.Rows[0][1] != "b" {
+		t.Errorf("Expected [1, \"b\"], got %v", response.Rows[0])
+	}
+}
+
+func TestHandleQuery_InvalidFormat(t *testing.T) {
+	handler := NewHandler(newDuplicateColumnsExecutor())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "SELECT 1", "format": "csv"}`))
+	w := httptest.NewRecorder()
+
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleQuery_QuerySizeLimit(t *testing.T) {
+	largeSQL := "SELECT '" + strings.Repeat("a", 20*1024) + "'"
+	body, _ := json.Marshal(map[string]string{"sql": largeSQL})
+
+	testCases := []struct {
+		name         string
+		limits       query.Limits
+		expectedCode int
+	}{
+		{"default 10KB limit", query.DefaultLimits(), http.StatusRequestEntityTooLarge},
+		{"64KB limit", query.Limits{MaxQuerySize: 64 * 1024}, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := NewHandlerWithLimits(&recordingExecutor{}, tc.limits)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/query", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.HandleQuery(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+type pagingExecutor struct {
+	mockExecutor
+	call   string
+	sql    string
+	cursor string
+	limit  int
+	opts   query.Options
+}
+
+func (p *pagingExecutor) ExecuteWithOptions(sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	p.call, p.sql, p.opts = "execute", sql, opts
+	return &query.ExecutionResult{
+		Rows:      []map[string]interface{}{{"n": int64(1)}},
+		Values:    [][]interface{}{{int64(1)}},
+		RowCount:  1,
+		Truncated: opts.Truncate,
+	}, nil
+}
+
+func (p *pagingExecutor) ExecutePage(sql string, params []interface{}, opts query.Options, limit int) (*query.ExecutionResult, error) {
+	p.call, p.sql, p.limit = "page", sql, limit
+	return &query.ExecutionResult{
+		Rows:       []map[string]interface{}{{"n": int64(1)}},
+		Values:     [][]interface{}{{int64(1)}},
+		RowCount:   1,
+		NextCursor: "c1",
+	}, nil
+}
+
+func (p *pagingExecutor) FetchPage(cursor string, opts query.Options, limit int) (*query.ExecutionResult, error) {
+	p.call, p.cursor, p.limit, p.opts = "fetch", cursor, limit, opts
+	if cursor != "c1" {
+		return nil, postgres.NewVibeError(postgres.ErrorCodeCursorNotFound, "Cursor not found", "")
+	}
+	return &query.ExecutionResult{}, nil
+}
+
+func postQuery(handler *Handler, body string) (*httptest.ResponseRecorder, QueryResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.HandleQuery(w, req)
+
+	var response QueryResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestHandleQuery_Truncate(t *testing.T) {
+	executor := &pagingExecutor{}
+	w, response := postQuery(NewHandler(executor), `{"sql": "SELECT * FROM events", "truncate": true}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !executor.opts.Truncate {
+		t.Error("Expected Truncate option to be passed to executor")
+	}
+	if !response.Truncated {
+		t.Error("Expected truncated=true in response")
+	}
+}
+
+func TestHandleQuery_Pagination(t *testing.T) {
+	executor := &pagingExecutor{}
+	handler := NewHandler(executor)
+
+	w, response := postQuery(handler, `{"sql": "SELECT * FROM events", "limit": 100}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if executor.call != "page" || executor.limit != 100 {
+		t.Errorf("Expected ExecutePage with limit 100, got %s with limit %d", executor.call, executor.limit)
+	}
+	if response.NextCursor != "c1" {
+		t.Errorf("Expected nextCursor c1, got %q", response.NextCursor)
+	}
+
+	w, response = postQuery(handler, `{"cursor": "c1"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for cursor without sql, got %d: %s", w.Code, w.Body.String())
+	}
+	if executor.call != "fetch" || executor.cursor != "c1" || executor.limit != 0 {
+		t.Errorf("Expected FetchPage(c1, 0), got %s(%s, %d)", executor.call, executor.cursor, executor.limit)
+	}
+	if response.NextCursor != "" {
+		t.Errorf("Expected no nextCursor on last page, got %q", response.NextCursor)
+	}
+
+	w, response = postQuery(handler, `{"cursor": "gone"}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown cursor, got %d", w.Code)
+	}
+	if response.Error == nil || response.Error.Code != ErrorCodeCursorNotFound {
+		t.Errorf("Expected CURSOR_NOT_FOUND, got %+v", response.Error)
+	}
+}
+
+func TestHandleQuery_CursorKeyAndPolicy(t *testing.T) {
+	executor := &pagingExecutor{}
+	handler := NewHandler(executor)
+
+	policy := readOnlyPolicy(t)
+	policy.Role = "analyst"
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"cursor": "c1"}`))
+	req = req.WithContext(WithKeyID(WithPolicy(req.Context(), policy), "key1"))
+	w := httptest.NewRecorder()
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if executor.opts.Owner != "key1" || !executor.opts.ReadOnly || executor.opts.Role != "analyst" {
+		t.Errorf("Expected the fetch to carry the key and its policy, got %+v", executor.opts)
+	}
+}
+
+func TestHandleQuery_PaginationValidatesSQL(t *testing.T) {
+	executor := &pagingExecutor{}
+	w, _ := postQuery(NewHandler(executor), `{"sql": "DELETE FROM events", "limit": 10}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if executor.call != "" {
+		t.Errorf("Unsafe query should not reach the executor, got %s", executor.call)
+	}
+}
+
+type commandExecutor struct {
+	mockExecutor
+}
+
+func (e *commandExecutor) ExecuteWithOptions(sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	return &query.ExecutionResult{RowsAffected: 3, CommandTag: "UPDATE 3"}, nil
+}
+
+func TestHandleQuery_RowsAffected(t *testing.T) {
+	w, response := postQuery(NewHandler(&commandExecutor{}), `{"sql": "UPDATE users SET active = false WHERE id > 1"}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if response.RowsAffected == nil || *response.RowsAffected != 3 {
+		t.Errorf("Expected rowsAffected 3, got %v", response.RowsAffected)
+	}
+	if response.CommandTag != "UPDATE 3" {
+		t.Errorf("Expected commandTag UPDATE 3, got %q", response.CommandTag)
+	}
+
+	// Without a command tag the row count is unknown and left out
+	w, _ = postQuery(NewHandler(&pagingExecutor{}), `{"sql": "SELECT * FROM events", "limit": 100}`)
+	if strings.Contains(w.Body.String(), "rowsAffected") {
+		t.Errorf("Expected no rowsAffected for a page, got %s", w.Body.String())
+	}
+}
+
+func TestNewStatementResult_RowsAffected(t *testing.T) {
+	sr := NewStatementResult(&query.ExecutionResult{CommandTag: "DELETE 0"}, "")
+	if sr.RowsAffected == nil || *sr.RowsAffected != 0 || sr.CommandTag != "DELETE 0" {
+		t.Errorf("Expected rowsAffected 0 and commandTag DELETE 0, got %v, %q", sr.RowsAffected, sr.CommandTag)
+	}
+}
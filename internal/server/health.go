@@ -0,0 +1,180 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/version"
+)
+
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+	HealthStatusAlive     = "alive"
+	HealthStatusReady     = "ready"
+	HealthStatusNotReady  = "not_ready"
+
+	healthCheckOK = "ok"
+)
+
+// DatabaseProbe reports the state of the database behind the server
+type DatabaseProbe interface {
+	IsRunning() bool
+	Ping() error
+	ServerVersion() (string, error)
+}
+
+// HealthResponse is returned by the health endpoints
+type HealthResponse struct {
+	Status        string            `json:"status"`
+	Version       string            `json:"version,omitempty"`
+	Database      string            `json:"database,omitempty"`
+	UptimeSeconds int64             `json:"uptime_seconds"`
+	Checks        map[string]string `json:"checks,omitempty"`
+}
+
+// SetDatabaseProbe sets the probe used by the health endpoints. Without a
+// probe only the HTTP server itself is checked.
+func (s *Server) SetDatabaseProbe(probe DatabaseProbe) {
+	s.probe = probe
+}
+
+// isHealthPath reports whether path is a health endpoint. Health checks
+// need no API key or signature and may use the reserved connection slot.
+func isHealthPath(path string) bool {
+	return path == "/v1/health" || strings.HasPrefix(path, "/v1/health/")
+}
+
+func (s *Server) registerHealthRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/health", s.HandleHealth)
+	mux.HandleFunc("/v1/health/live", s.HandleLive)
+	mux.HandleFunc("/v1/health/ready", s.HandleReady)
+}
+
+// HandleHealth reports overall status, version, database version and uptime.
+// It returns 503 when the server is shutting down or the database is down.
+func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowHealthMethod(w, r) {
+		return
+	}
+
+	checks, healthy := s.runChecks()
+
+	response := &HealthResponse{
+		Status:        HealthStatusHealthy,
+		Version:       version.Get().Short(),
+		UptimeSeconds: s.uptimeSeconds(),
+		Checks:        checks,
+	}
+	if s.probe != nil && checks["database"] == healthCheckOK {
+		if v, err := s.probe.ServerVersion(); err == nil {
+			response.Database = "postgresql-" + v
+		}
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		response.Status = HealthStatusUnhealthy
+		status = http.StatusServiceUnavailable
+		log.Printf("[WARN] Health check failed: %v", checks)
+	}
+
+	writeHealth(w, status, response)
+}
+
+// HandleLive reports that the HTTP server is serving requests. It doesn't
+// check the database, so supervisors only restart the process when the
+// server itself is stuck.
+func (s *Server) HandleLive(w http.ResponseWriter, r *http.Request) {
+	if !allowHealthMethod(w, r) {
+		return
+	}
+
+	writeHealth(w, http.StatusOK, &HealthResponse{
+		Status:        HealthStatusAlive,
+		UptimeSeconds: s.uptimeSeconds(),
+	})
+}
+
+// HandleReady reports whether the server can execute queries
+func (s *Server) HandleReady(w http.ResponseWriter, r *http.Request) {
+	if !allowHealthMethod(w, r) {
+		return
+	}
+
+	checks, healthy := s.runChecks()
+
+	response := &HealthResponse{
+		Status:        HealthStatusReady,
+		UptimeSeconds: s.uptimeSeconds(),
+		Checks:        checks,
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		response.Status = HealthStatusNotReady
+		status = http.StatusServiceUnavailable
+	}
+
+	writeHealth(w, status, response)
+}
+
+// runChecks checks the HTTP server, the PostgreSQL process and database
+// connectivity, returning "ok" or a failure description for each
+func (s *Server) runChecks() (map[string]string, bool) {
+	checks := make(map[string]string, 3)
+	healthy := true
+
+	if s.IsReady() {
+		checks["server"] = healthCheckOK
+	} else {
+		checks["server"] = "not accepting requests"
+		healthy = false
+	}
+
+	if s.probe == nil {
+		return checks, healthy
+	}
+
+	if !s.probe.IsRunning() {
+		checks["postgres"] = "process not running"
+		checks["database"] = "unavailable"
+		return checks, false
+	}
+	checks["postgres"] = healthCheckOK
+
+	if err := s.probe.Ping(); err != nil {
+		checks["database"] = err.Error()
+		healthy = false
+	} else {
+		checks["database"] = healthCheckOK
+	}
+
+	return checks, healthy
+}
+
+func (s *Server) uptimeSeconds() int64 {
+	if s.startedAt.IsZero() {
+		return 0
+	}
+	return int64(time.Since(s.startedAt).Seconds())
+}
+
+func allowHealthMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	WriteError(w, NewInvalidSQLError("Only GET method is supported for health endpoints"))
+	log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+	return false
+}
+
+func writeHealth(w http.ResponseWriter, status int, response *HealthResponse) {
+	// Health results must never be served from a cache
+	w.Header().Set("Cache-Control", "no-store")
+	if err := WriteJSON(w, status, response); err != nil {
+		log.Printf("[ERROR] Failed to write health response: %v", err)
+	}
+}
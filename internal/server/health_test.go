@@ -0,0 +1,182 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vibesql/vibe/internal/version"
+)
+
+type mockProbe struct {
+	running bool
+	pingErr error
+}
+
+func (p *mockProbe) IsRunning() bool {
+	return p.running
+}
+
+func (p *mockProbe) Ping() error {
+	return p.pingErr
+}
+
+func (p *mockProbe) ServerVersion() (string, error) {
+	return "16.1", nil
+}
+
+func newHealthTestServer(probe DatabaseProbe) *Server {
+	server := newTestServer()
+	server.SetDatabaseProbe(probe)
+	server.startedAt = time.Now().Add(-90 * time.Second)
+	server.ready.Store(true)
+	return server
+}
+
+func serveHealth(t *testing.T, handler http.HandlerFunc, method string) (int, *HealthResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	var resp HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w.Code, &resp
+}
+
+func TestHandleHealth_Healthy(t *testing.T) {
+	server := newHealthTestServer(&mockProbe{running: true})
+
+	status, resp := serveHealth(t, server.HandleHealth, http.MethodGet)
+
+	if status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if resp.Status != HealthStatusHealthy {
+		t.Errorf("Expected status %q, got %q", HealthStatusHealthy, resp.Status)
+	}
+	if resp.Version != version.Get().Short() {
+		t.Errorf("Expected version %q, got %q", version.Get().Short(), resp.Version)
+	}
+	if resp.Database != "postgresql-16.1" {
+		t.Errorf("Expected database 'postgresql-16.1', got %q", resp.Database)
+	}
+	if resp.UptimeSeconds < 90 {
+		t.Errorf("Expected uptime of at least 90s, got %d", resp.UptimeSeconds)
+	}
+	for _, name := range []string{"server", "postgres", "database"} {
+		if resp.Checks[name] != "ok" {
+			t.Errorf("Expected check %q to be ok, got %q", name, resp.Checks[name])
+		}
+	}
+}
+
+func TestHandleHealth_PostgresStopped(t *testing.T) {
+	server := newHealthTestServer(&mockProbe{running: false})
+
+	status, resp := serveHealth(t, server.HandleHealth, http.MethodGet)
+
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", status)
+	}
+	if resp.Status != HealthStatusUnhealthy {
+		t.Errorf("Expected status %q, got %q", HealthStatusUnhealthy, resp.Status)
+	}
+	if resp.Checks["server"] != "ok" {
+		t.Errorf("Expected server check to be ok, got %q", resp.Checks["server"])
+	}
+	if resp.Checks["postgres"] == "ok" {
+		t.Error("Expected postgres check to fail")
+	}
+	if resp.Database != "" {
+		t.Errorf("Expected no database version, got %q", resp.Database)
+	}
+}
+
+func TestHandleHealth_PingFails(t *testing.T) {
+	server := newHealthTestServer(&mockProbe{running: true, pingErr: errors.New("connection refused")})
+
+	status, resp := serveHealth(t, server.HandleHealth, http.MethodGet)
+
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", status)
+	}
+	if resp.Checks["database"] != "connection refused" {
+		t.Errorf("Expected database check 'connection refused', got %q", resp.Checks["database"])
+	}
+}
+
+func TestHandleHealth_MethodNotAllowed(t *testing.T) {
+	server := newHealthTestServer(&mockProbe{running: true})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	server.HandleHealth(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHandleLive_IgnoresDatabase(t *testing.T) {
+	server := newHealthTestServer(&mockProbe{running: false})
+
+	status, resp := serveHealth(t, server.HandleLive, http.MethodGet)
+
+	if status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if resp.Status != HealthStatusAlive {
+		t.Errorf("Expected status %q, got %q", HealthStatusAlive, resp.Status)
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	testCases := []struct {
+		name           string
+		probe          *mockProbe
+		ready          bool
+		expectedCode   int
+		expectedStatus string
+	}{
+		{"ready", &mockProbe{running: true}, true, http.StatusOK, HealthStatusReady},
+		{"postgres stopped", &mockProbe{running: false}, true, http.StatusServiceUnavailable, HealthStatusNotReady},
+		{"ping fails", &mockProbe{running: true, pingErr: errors.New("timeout")}, true, http.StatusServiceUnavailable, HealthStatusNotReady},
+		{"shutting down", &mockProbe{running: true}, false, http.StatusServiceUnavailable, HealthStatusNotReady},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newHealthTestServer(tc.probe)
+			server.ready.Store(tc.ready)
+
+			status, resp := serveHealth(t, server.HandleReady, http.MethodGet)
+
+			if status != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, status)
+			}
+			if resp.Status != tc.expectedStatus {
+				t.Errorf("Expected status %q, got %q", tc.expectedStatus, resp.Status)
+			}
+		})
+	}
+}
+
+func TestHandleHealth_WithoutProbe(t *testing.T) {
+	server := newTestServer()
+	server.ready.Store(true)
+
+	status, resp := serveHealth(t, server.HandleHealth, http.MethodGet)
+
+	if status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+	if _, ok := resp.Checks["database"]; ok {
+		t.Error("Expected no database check without a probe")
+	}
+}
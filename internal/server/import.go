@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+const importPathPrefix = "/v1/import/"
+
+// importFormats maps request media types to import formats
+var importFormats = map[string]string{
+	"text/csv":           query.ImportCSV,
+	ContentTypeNDJSON:    query.ImportNDJSON,
+	"application/ndjson": query.ImportNDJSON,
+	"application/jsonl":  query.ImportNDJSON,
+	"application/json":   query.ImportJSON,
+}
+
+// HandleImport serves /v1/import/{table}, loading the CSV, NDJSON or JSON
+// array request body into the table with COPY FROM STDIN
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/import endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+	defer r.Body.Close()
+
+	opts, vibeErr := importOptions(r)
+	if vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid import request: %v", vibeErr)
+		return
+	}
+
+	policy := h.policyFor(r)
+	if err := policy.CheckImport(opts.Create != ""); err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Import rejected (%s policy): %v", policy.Name(), err)
+		return
+	}
+
+	opts.Role = policy.Role
+	extendImportDeadlines(w, h.limits.ImportTimeout)
+
+	log.Printf("[INFO] Importing %s data into %s", opts.Format, opts.Table)
+
+	result, err := h.executor.Import(r.Body, opts)
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Import into %s failed: %v", opts.Table, err)
+		return
+	}
+
+	response := &ImportResponse{
+		Success:       true,
+		Table:         result.Table,
+		Columns:       result.Columns,
+		Created:       result.Created,
+		RowsLoaded:    result.RowsLoaded,
+		RowsRejected:  result.RowsRejected,
+		ExecutionTime: float64(result.ExecutionTime.Microseconds()) / 1000.0,
+	}
+	for _, rejection := range result.Rejections {
+		response.Rejections = append(response.Rejections, ImportRejection{Row: rejection.Row, Reason: rejection.Reason})
+	}
+
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Import into %s succeeded: %d rows loaded, %d rejected in %.2fms", opts.Table, result.RowsLoaded, result.RowsRejected, response.ExecutionTime)
+}
+
+// extendImportDeadlines lets an import take the import timeout to upload
+// and load its data, which the server's read and write timeouts would cut
+// short. Writers that are not connections, such as test recorders, are
+// left alone.
+func extendImportDeadlines(w http.ResponseWriter, timeout time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(timeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		return
+	}
+	rc.SetWriteDeadline(deadline.Add(WriteTimeout))
+}
+
+// importOptions reads the import options from the request path, query
+// string and Content-Type
+func importOptions(r *http.Request) (query.ImportOptions, *postgres.VibeError) {
+	opts := query.ImportOptions{
+		Table: strings.TrimPrefix(r.URL.Path, importPathPrefix),
+	}
+	if opts.Table == "" || strings.Contains(opts.Table, "/") {
+		return opts, NewInvalidSQLError("Expected /v1/import/{table}")
+	}
+
+	params := r.URL.Query()
+
+	opts.Format = params.Get("format")
+	if opts.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		opts.Format = importFormats[mediaType]
+	}
+	switch opts.Format {
+	case query.ImportCSV, query.ImportNDJSON, query.ImportJSON:
+	case "":
+		return opts, NewInvalidSQLError("Set 'format' to csv, ndjson or json, or send a matching Content-Type")
+	default:
+		return opts, NewInvalidSQLError(fmt.Sprintf("Unsupported import format '%s'. Use 'csv', 'ndjson' or 'json'", opts.Format))
+	}
+
+	if columns := params.Get("columns"); columns != "" {
+		opts.Columns = splitList(columns)
+	}
+
+	if mapping := params.Get("map"); mapping != "" {
+		opts.Mapping = make(map[string]string)
+		for _, pair := range splitList(mapping) {
+			source, target, ok := strings.Cut(pair, ":")
+			source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+			if !ok || source == "" || target == "" {
+				return opts, NewInvalidSQLError(fmt.Sprintf("Invalid field mapping '%s'. Use 'field:column'", pair))
+			}
+			opts.Mapping[source] = target
+		}
+	}
+
+	if header := params.Get("header"); header != "" {
+		hasHeader, err := strconv.ParseBool(header)
+		if err != nil {
+			return opts, NewInvalidSQLError(fmt.Sprintf("Invalid header value '%s'. Use 'true' or 'false'", header))
+		}
+		opts.NoHeader = !hasHeader
+	}
+
+	opts.Create = params.Get("create")
+	switch opts.Create {
+	case "", query.CreateInfer, query.CreateJSONB:
+	default:
+		return opts, NewInvalidSQLError(fmt.Sprintf("Unsupported create mode '%s'. Use 'infer' or 'jsonb'", opts.Create))
+	}
+
+	return opts, nil
+}
+
+// splitList splits a comma-separated list, trimming spaces
+func splitList(list string) []string {
+	items := strings.Split(list, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// importExecutor records the options and data of an import
+type importExecutor struct {
+	mockExecutor
+	opts   *query.ImportOptions
+	data   string
+	result query.ImportResult
+}
+
+func (e *importExecutor) Import(r io.Reader, opts query.ImportOptions) (*query.ImportResult, error) {
+	e.opts = &opts
+	data, _ := io.ReadAll(r)
+	e.data = string(data)
+	result := e.result
+	return &result, nil
+}
+
+func postImport(t *testing.T, handler *Handler, target, contentType, body string) (*httptest.ResponseRecorder, ImportResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	handler.HandleImport(w, req)
+
+	var response ImportResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w, response
+}
+
+func TestHandleImport_Success(t *testing.T) {
+	executor := &importExecutor{result: query.ImportResult{
+		Table:        "public.users",
+		Columns:      []string{"id", "full_name"},
+		Created:      true,
+		RowsLoaded:   2,
+		RowsRejected: 1,
+		Rejections:   []query.Rejection{{Row: 3, Reason: "Wrong number of fields"}},
+	}}
+	handler := NewHandler(executor)
+
+	body := "id,name\n1,Alice\n2,Bob\n3\n"
+	w, response := postImport(t, handler, "/v1/import/public.users?map=name:full_name&create=infer", "text/csv; charset=utf-8", body)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	want := query.ImportOptions{
+		Table:   "public.users",
+		Format:  query.ImportCSV,
+		Mapping: map[string]string{"name": "full_name"},
+		Create:  query.CreateInfer,
+	}
+	if !reflect.DeepEqual(*executor.opts, want) {
+		t.Errorf("Expected options %+v, got %+v", want, *executor.opts)
+	}
+	if executor.data != body {
+		t.Errorf("Expected the body to reach the executor, got %q", executor.data)
+	}
+	if !response.Success || !response.Created || response.RowsLoaded != 2 || response.RowsRejected != 1 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if len(response.Rejections) != 1 || response.Rejections[0].Row != 3 {
+		t.Errorf("Expected the rejection to be reported, got %+v", response.Rejections)
+	}
+}
+
+func TestHandleImport_OutlastsReadTimeout(t *testing.T) {
+	executor := &importExecutor{}
+	handler := NewHandlerWithLimits(executor, query.Limits{ImportTimeout: 5 * time.Second})
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler.HandleImport))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	body, upload := io.Pipe()
+	go func() {
+		upload.Write([]byte("id\n1\n"))
+		time.Sleep(300 * time.Millisecond)
+		upload.Write([]byte("2\n"))
+		upload.Close()
+	}()
+
+	resp, err := http.Post(server.URL+"/v1/import/events", "text/csv", body)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || executor.data != "id\n1\n2\n" {
+		t.Errorf("Expected the slow upload to be read in full, got %d and %q", resp.StatusCode, executor.data)
+	}
+}
+
+func TestHandleImport_Options(t *testing.T) {
+	tests := []struct {
+		target      string
+		contentType string
+		want        query.ImportOptions
+	}{
+		{"/v1/import/events", "application/x-ndjson", query.ImportOptions{Table: "events", Format: query.ImportNDJSON}},
+		{"/v1/import/events", "application/json", query.ImportOptions{Table: "events", Format: query.ImportJSON}},
+		{"/v1/import/events?format=csv&header=false&columns=id,%20name", "application/octet-stream", query.ImportOptions{
+			Table: "events", Format: query.ImportCSV, NoHeader: true, Columns: []string{"id", "name"},
+		}},
+		{"/v1/import/events?format=json&create=jsonb", "", query.ImportOptions{Table: "events", Format: query.ImportJSON, Create: query.CreateJSONB}},
+	}
+
+	for _, tt := range tests {
+		executor := &importExecutor{}
+		w, _ := postImport(t, NewHandler(executor), tt.target, tt.contentType, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d: %s", tt.target, w.Code, w.Body.String())
+			continue
+		}
+		if !reflect.DeepEqual(*executor.opts, tt.want) {
+			t.Errorf("%s: expected options %+v, got %+v", tt.target, tt.want, *executor.opts)
+		}
+	}
+}
+
+func TestHandleImport_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		target      string
+		contentType string
+	}{
+		{"/v1/import/", "text/csv"},
+		{"/v1/import/a/b", "text/csv"},
+		{"/v1/import/events", "application/octet-stream"},
+		{"/v1/import/events?format=xml", "text/csv"},
+		{"/v1/import/events?map=name", "text/csv"},
+		{"/v1/import/events?header=maybe", "text/csv"},
+		{"/v1/import/events?create=always", "text/csv"},
+	}
+
+	for _, tt := range tests {
+		executor := &importExecutor{}
+		w, response := postImport(t, NewHandler(executor), tt.target, tt.contentType, "")
+		if w.Code != http.StatusBadRequest || response.Error == nil {
+			t.Errorf("%s: expected status 400 with an error, got %d: %s", tt.target, w.Code, w.Body.String())
+		}
+		if executor.opts != nil {
+			t.Errorf("%s: executor should not run a rejected import", tt.target)
+		}
+	}
+}
+
+func TestHandleImport_Policy(t *testing.T) {
+	executor := &importExecutor{}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w, response := postImport(t, handler, "/v1/import/events", "text/csv", "id\n1\n")
+	if w.Code != http.StatusForbidden || response.Error == nil || response.Error.Code != ErrorCodeStatementNotAllowed {
+		t.Errorf("Expected the readonly profile to reject imports, got %d: %s", w.Code, w.Body.String())
+	}
+
+	noDDL, _ := query.PolicyProfile(query.ProfileNoDDL)
+	handler = NewHandlerWithPolicy(executor, query.DefaultLimits(), noDDL)
+
+	if w, _ := postImport(t, handler, "/v1/import/events?create=infer", "text/csv", "id\n1\n"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected the no-ddl profile to reject table creation, got %d", w.Code)
+	}
+	if w, _ := postImport(t, handler, "/v1/import/events", "text/csv", "id\n1\n"); w.Code != http.StatusOK {
+		t.Errorf("Expected the no-ddl profile to allow imports into existing tables, got %d", w.Code)
+	}
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+	"github.com/vibesql/vibe/internal/scram"
+)
+
+// API key scopes, from least to most privileged
+const (
+	// ScopeReadOnly allows statements that read data, run in READ ONLY
+	// transactions
+	ScopeReadOnly = "read-only"
+	// ScopeReadWrite allows reading and writing rows, but not DDL,
+	// TRUNCATE, privilege or maintenance statements
+	ScopeReadWrite = "read-write"
+	// ScopeAdmin allows whatever the server's safety policy allows
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every API key scope
+var Scopes = []string{ScopeReadOnly, ScopeReadWrite, ScopeAdmin}
+
+// apiKeyPrefix starts every API key, so keys are recognizable in config
+// files and secret scanners
+const apiKeyPrefix = "vibe_"
+
+// APIKey is a stored API key. Only a hash of the secret is kept.
+type APIKey struct {
+	// ID identifies the key and is the part of it shown by 'vibe keys list'
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	Hash  string `json:"hash"`
+	// SCRAM is the key's SCRAM-SHA-256 verifier, for PostgreSQL protocol
+	// clients that log in with the key as their password
+	SCRAM string `json:"scram,omitempty"`
+	// Role is the PostgreSQL role the key's queries run as, in place of the
+	// server's postgres.role. Empty keeps the server's role.
+	Role      string     `json:"role,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Revoked reports whether the key has been revoked
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// KeyStore is the set of API keys saved in a keys file
+type KeyStore struct {
+	Keys []*APIKey `json:"keys"`
+}
+
+// LoadKeyStore reads a keys file. A missing file is an empty store.
+func LoadKeyStore(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &KeyStore{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	store := &KeyStore{}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid keys file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save writes the store to a keys file readable only by its owner,
+// creating its directory if needed: the default keys file is in the data
+// directory, which may not exist before the first start. The file is
+// replaced atomically so a running server never reads it half written.
+func (s *KeyStore) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0600)
+}
+
+// Create adds a key with the given name and scope and returns it with the
+// secret key, which is not stored and cannot be recovered
+func (s *KeyStore) Create(name, scope string) (*APIKey, string, error) {
+	if !validScope(scope) {
+		return nil, "", fmt.Errorf("unknown scope %q (expected one of %s)", scope, strings.Join(Scopes, ", "))
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	key := apiKeyPrefix + id + "_" + secret
+	verifier, err := scram.NewVerifier(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey := &APIKey{
+		ID:        id,
+		Name:      name,
+		Scope:     scope,
+		Hash:      hashKey(key),
+		SCRAM:     verifier.String(),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.Keys = append(s.Keys, apiKey)
+	return apiKey, key, nil
+}
+
+// Revoke revokes the key with the given ID
+func (s *KeyStore) Revoke(id string) (*APIKey, error) {
+	for _, key := range s.Keys {
+		if key.ID != id {
+			continue
+		}
+		if key.Revoked() {
+			return nil, fmt.Errorf("key %s is already revoked", id)
+		}
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key with ID %s", id)
+}
+
+// Authenticate returns the unrevoked key matching a secret key, or nil
+func (s *KeyStore) Authenticate(key string) *APIKey {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return nil
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil
+	}
+
+	hash := hashKey(key)
+	for _, apiKey := range s.Keys {
+		if apiKey.ID == id && !apiKey.Revoked() && subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hash)) == 1 {
+			return apiKey
+		}
+	}
+	return nil
+}
+
+// Lookup returns the unrevoked key a PostgreSQL protocol client names as
+// its user: the key's ID, or its name if no other unrevoked key has it.
+// It returns nil if there is no such key.
+func (s *KeyStore) Lookup(user string) *APIKey {
+	var named *APIKey
+	for _, apiKey := range s.Keys {
+		if apiKey.Revoked() {
+			continue
+		}
+		if apiKey.ID == user {
+			return apiKey
+		}
+		if apiKey.Name == user {
+			if named != nil {
+				return nil
+			}
+			named = apiKey
+		}
+	}
+	return named
+}
+
+// Roles returns the PostgreSQL roles named by unrevoked keys, each once
+func (s *KeyStore) Roles() []string {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, apiKey := range s.Keys {
+		if apiKey.Revoked() || apiKey.Role == "" || seen[apiKey.Role] {
+			continue
+		}
+		seen[apiKey.Role] = true
+		roles = append(roles, apiKey.Role)
+	}
+	return roles
+}
+
+// ScopePolicy returns the safety policy for requests made with a key of
+// the given scope. Scopes only narrow the server's policy: a read-write key
+// cannot run DDL the policy denies, and an admin key has exactly the
+// server's policy. Unknown scopes are treated as read-only.
+func ScopePolicy(scope string, policy query.Policy) query.Policy {
+	switch scope {
+	case ScopeAdmin:
+		return policy
+	case ScopeReadWrite:
+		policy = policy.Deny(query.ClassDDL, query.ClassTruncate, query.ClassPrivilege, query.ClassMaintenance)
+	default:
+		for _, class := range query.StatementClasses {
+			switch class {
+			case query.ClassQuery, query.ClassExplain, query.ClassShow, query.ClassCopy:
+			default:
+				policy = policy.Deny(class)
+			}
+		}
+		policy.ReadOnly = true
+	}
+	policy.Profile = policy.Name() + "/" + scope
+	return policy
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hashKey returns the stored form of a key. Keys are long random strings,
+// so a fast hash is enough to make a leaked keys file useless.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over
+// path, so readers never see a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+	"github.com/vibesql/vibe/internal/scram"
+)
+
+func TestKeyStore_CreateAuthenticateRevoke(t *testing.T) {
+	store := &KeyStore{}
+
+	key, secret, err := store.Create("ci", ScopeReadWrite)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, apiKeyPrefix+key.ID+"_") {
+		t.Errorf("Expected the key to start with its ID, got %q", secret)
+	}
+	if strings.Contains(key.Hash, secret) {
+		t.Error("The secret key must not be stored")
+	}
+
+	if got := store.Authenticate(secret); got != key {
+		t.Errorf("Authenticate() = %v, want the created key", got)
+	}
+	for _, wrong := range []string{"", "vibe_", secret + "x", apiKeyPrefix + key.ID + "_00", "Bearer " + secret} {
+		if store.Authenticate(wrong) != nil {
+			t.Errorf("Authenticate(%q) should fail", wrong)
+		}
+	}
+
+	if _, err := store.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if store.Authenticate(secret) != nil {
+		t.Error("A revoked key should not authenticate")
+	}
+	if _, err := store.Revoke(key.ID); err == nil {
+		t.Error("Expected an error revoking a key twice")
+	}
+	if _, err := store.Revoke("missing"); err == nil {
+		t.Error("Expected an error revoking an unknown key")
+	}
+
+	if _, _, err := store.Create("root", "superuser"); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+}
+
+func TestKeyStore_Lookup(t *testing.T) {
+	store := &KeyStore{}
+	ci, _, _ := store.Create("ci", ScopeReadWrite)
+	first, _, _ := store.Create("bi", ScopeReadOnly)
+	store.Create("bi", ScopeReadOnly)
+	old, _, _ := store.Create("old", ScopeAdmin)
+	store.Revoke(old.ID)
+
+	tests := []struct {
+		user string
+		want *APIKey
+	}{
+		{"ci", ci},
+		{ci.ID, ci},
+		{first.ID, first},
+		{"bi", nil},
+		{"old", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := store.Lookup(tt.user); got != tt.want {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+
+	if _, err := scram.ParseVerifier(ci.SCRAM); err != nil {
+		t.Errorf("Expected a SCRAM verifier for new keys, got %q: %v", ci.SCRAM, err)
+	}
+}
+
+func TestKeyStore_Roles(t *testing.T) {
+	store := &KeyStore{}
+	for _, role := range []string{"analyst", "", "writer", "analyst", "retired"} {
+		key, _, _ := store.Create("k", ScopeReadWrite)
+		key.Role = role
+		if role == "retired" {
+			store.Revoke(key.ID)
+		}
+	}
+
+	if got := strings.Join(store.Roles(), ","); got != "analyst,writer" {
+		t.Errorf("Roles() = %q, want analyst,writer", got)
+	}
+}
+
+func TestKeyStore_SaveLoad(t *testing.T) {
+	// The data directory holding the keys file may not exist yet
+	path := filepath.Join(t.TempDir(), "vibe-data", "keys.json")
+
+	store, err := LoadKeyStore(path)
+	if err != nil || len(store.Keys) != 0 {
+		t.Fatalf("LoadKeyStore() of a missing file = %v, %v, want an empty store", store, err)
+	}
+
+	_, secret, _ := store.Create("ci", ScopeAdmin)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected the keys file to be 0600, got %o", mode)
+	}
+
+	loaded, err := LoadKeyStore(path)
+	if err != nil {
+		t.Fatalf("LoadKeyStore() error = %v", err)
+	}
+	if key := loaded.Authenticate(secret); key == nil || key.Name != "ci" || key.Scope != ScopeAdmin {
+		t.Errorf("Expected the saved key to authenticate, got %v", key)
+	}
+
+	os.WriteFile(path, []byte("{"), 0600)
+	if _, err := LoadKeyStore(path); err == nil {
+		t.Error("Expected an error for a malformed keys file")
+	}
+}
+
+func TestScopePolicy(t *testing.T) {
+	base := query.DefaultPolicy()
+
+	tests := []struct {
+		scope   string
+		sql     string
+		allowed bool
+	}{
+		{ScopeReadOnly, "SELECT * FROM users", true},
+		{ScopeReadOnly, "INSERT INTO users (name) VALUES ('a')", false},
+		{ScopeReadOnly, "CREATE TABLE t (id int)", false},
+		{"unknown", "INSERT INTO users (name) VALUES ('a')", false},
+		{ScopeReadWrite, "INSERT INTO users (name) VALUES ('a')", true},
+		{ScopeReadWrite, "DELETE FROM users WHERE id = 1", true},
+		{ScopeReadWrite, "CREATE TABLE t (id int)", false},
+		{ScopeReadWrite, "TRUNCATE users", false},
+		{ScopeAdmin, "CREATE TABLE t (id int)", true},
+		// Scopes never widen the server's policy
+		{ScopeAdmin, "VACUUM users", false},
+		{ScopeAdmin, "DELETE FROM users", false},
+	}
+
+	for _, tt := range tests {
+		err := ScopePolicy(tt.scope, base).Check(tt.sql)
+		if (err == nil) != tt.allowed {
+			t.Errorf("ScopePolicy(%s).Check(%q) = %v, want allowed=%v", tt.scope, tt.sql, err, tt.allowed)
+		}
+	}
+
+	if !ScopePolicy(ScopeReadOnly, base).ReadOnly {
+		t.Error("Expected read-only keys to run in READ ONLY transactions")
+	}
+	if name := ScopePolicy(ScopeReadWrite, base).Name(); name != "default/read-write" {
+		t.Errorf("Expected policy name default/read-write, got %q", name)
+	}
+}
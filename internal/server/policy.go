@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+type (
+	policyKey struct{}
+	keyIDKey  struct{}
+)
+
+// WithPolicy returns a copy of ctx carrying a safety policy that replaces
+// the handler's own for requests made with it, such as the policy attached
+// to an API key
+func WithPolicy(ctx context.Context, policy query.Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// policyFor returns the safety policy that applies to r
+func (h *Handler) policyFor(r *http.Request) query.Policy {
+	if policy, ok := r.Context().Value(policyKey{}).(query.Policy); ok {
+		return policy
+	}
+	return h.policy
+}
+
+// WithKeyID returns a copy of ctx recording the ID of the API key a request
+// was made with. Interactive transactions and cursors may only be used with
+// the key that opened them.
+func WithKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, keyIDKey{}, id)
+}
+
+// keyIDFor returns the ID of the API key r was made with, empty when the
+// server doesn't require keys
+func keyIDFor(r *http.Request) string {
+	id, _ := r.Context().Value(keyIDKey{}).(string)
+	return id
+}
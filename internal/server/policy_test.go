@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// beginExecutor records the options an interactive transaction was opened with
+type beginExecutor struct {
+	mockExecutor
+	opts query.Options
+}
+
+func (e *beginExecutor) BeginSession(opts query.Options) (string, error) {
+	e.opts = opts
+	return "tx1", nil
+}
+
+func readOnlyPolicy(t *testing.T) query.Policy {
+	t.Helper()
+	policy, err := query.PolicyProfile(query.ProfileReadOnly)
+	if err != nil {
+		t.Fatalf("Failed to load readonly profile: %v", err)
+	}
+	return policy
+}
+
+func TestHandleQuery_PolicyRejects(t *testing.T) {
+	handler := NewHandlerWithPolicy(&pagingExecutor{}, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w, response := postQuery(handler, `{"sql": "DROP TABLE users"}`)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	if response.Error == nil || response.Error.Code != ErrorCodeStatementNotAllowed {
+		t.Errorf("Expected STATEMENT_NOT_ALLOWED, got %+v", response.Error)
+	}
+}
+
+func TestHandleQuery_PolicyReadOnly(t *testing.T) {
+	executor := &pagingExecutor{}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w, _ := postQuery(handler, `{"sql": "SELECT * FROM users"}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !executor.opts.ReadOnly {
+		t.Error("Expected the query to run read-only")
+	}
+
+	executor = &pagingExecutor{}
+	postQuery(NewHandler(executor), `{"sql": "SELECT * FROM users"}`)
+	if executor.opts.ReadOnly {
+		t.Error("Expected the default policy not to run queries read-only")
+	}
+}
+
+func TestHandleQuery_PolicyFromContext(t *testing.T) {
+	executor := &pagingExecutor{}
+	handler := NewHandler(executor)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(`{"sql": "DELETE FROM users WHERE id = 1"}`))
+	req = req.WithContext(WithPolicy(req.Context(), readOnlyPolicy(t)))
+	w := httptest.NewRecorder()
+	handler.HandleQuery(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected the request's policy to reject the DELETE, got status %d", w.Code)
+	}
+	if executor.call != "" {
+		t.Error("Executor should not run a rejected query")
+	}
+}
+
+func TestHandleTransaction_PolicyReadOnly(t *testing.T) {
+	executor := &txExecutor{failAt: -1}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	w, response := postTransaction(t, handler, `{"statements": [{"sql": "SELECT 1"}, {"sql": "UPDATE accounts SET balance = 0 WHERE id = 1"}]}`)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+	if response.Error == nil || response.Error.Statement == nil || *response.Error.Statement != 1 {
+		t.Errorf("Expected statement 1 to be rejected, got %+v", response.Error)
+	}
+}
+
+func TestHandleTxBegin_PolicyReadOnly(t *testing.T) {
+	executor := &beginExecutor{}
+	handler := NewHandlerWithPolicy(executor, query.DefaultLimits(), readOnlyPolicy(t))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tx", nil)
+	w := httptest.NewRecorder()
+	handler.HandleTxBegin(w, req)
+
+	var response TxResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Success {
+		t.Fatalf("Expected transaction to start, got %+v", response)
+	}
+	if !executor.opts.ReadOnly {
+		t.Error("Expected the transaction to be opened read-only")
+	}
+}
@@ -5,27 +5,242 @@ import (
 	"net/http"
 
 	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// Row formats accepted in QueryRequest.Format
+const (
+	FormatObject = "object"
+	FormatArray  = "array"
 )
 
 // QueryRequest represents an incoming SQL query request
 type QueryRequest struct {
-	SQL string `json:"sql"`
+	SQL             string        `json:"sql"`
+	Params          []interface{} `json:"params,omitempty"`
+	NumericAsString bool          `json:"numericAsString,omitempty"`
+	Format          string        `json:"format,omitempty"`
+	// Truncate returns the first rows up to the row limit instead of failing
+	Truncate bool `json:"truncate,omitempty"`
+	// Limit pages through the result via a server-side cursor
+	Limit int `json:"limit,omitempty"`
+	// Cursor continues a paginated query; SQL and Params are ignored
+	Cursor string `json:"cursor,omitempty"`
+	// Stream sends the result as NDJSON, one row per line
+	Stream bool `json:"stream,omitempty"`
+	// Explain returns the statement's plan instead of its result
+	Explain bool `json:"explain,omitempty"`
+	// Analyze and Buffers add the EXPLAIN options of the same name
+	Analyze bool `json:"analyze,omitempty"`
+	Buffers bool `json:"buffers,omitempty"`
+	// DryRun runs the statements in a transaction that is rolled back and
+	// returns the number of rows they affected
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// readOnly is set by the handler when the safety policy is read-only
+	readOnly bool
+	// role is set by the handler to the role the safety policy runs
+	// queries as
+	role string
+	// owner is set by the handler to the API key the request was made with
+	owner string
+}
+
+// options returns the executor options for the request
+func (r *QueryRequest) options() query.Options {
+	return query.Options{
+		NumericAsString: r.NumericAsString,
+		Truncate:        r.Truncate,
+		ReadOnly:        r.readOnly,
+		Role:            r.role,
+		Owner:           r.owner,
+	}
+}
+
+// explainOptions returns the EXPLAIN options requested by the client
+func (r *QueryRequest) explainOptions() query.ExplainOptions {
+	return query.ExplainOptions{
+		Analyze: r.Analyze,
+		Buffers: r.Buffers,
+	}
+}
+
+// paginated reports whether the request opens or continues a cursor
+func (r *QueryRequest) paginated() bool {
+	return r.Limit != 0 || r.Cursor != ""
+}
+
+// StatementRequest is a single statement within a multi-statement request
+type StatementRequest struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params,omitempty"`
+}
+
+// TransactionRequest represents statements to run atomically in one transaction
+type TransactionRequest struct {
+	Statements      []StatementRequest `json:"statements"`
+	NumericAsString bool               `json:"numericAsString,omitempty"`
+	Format          string             `json:"format,omitempty"`
+}
+
+// BatchRequest represents independent queries run in one request, each
+// outside any transaction
+type BatchRequest struct {
+	Queries         []StatementRequest `json:"queries"`
+	Parallel        bool               `json:"parallel,omitempty"`
+	StopOnError     bool               `json:"stopOnError,omitempty"`
+	NumericAsString bool               `json:"numericAsString,omitempty"`
+	Format          string             `json:"format,omitempty"`
+}
+
+// ExportRequest represents a query whose result is downloaded as a file
+type ExportRequest struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params,omitempty"`
+	// Format is csv, ndjson, parquet or sql; when empty it is negotiated
+	// from the Accept header
+	Format string `json:"format,omitempty"`
+	// Table is the table the INSERT statements of a sql export target
+	Table           string `json:"table,omitempty"`
+	NumericAsString bool   `json:"numericAsString,omitempty"`
 }
 
 // QueryResponse represents a query response (success or error)
 type QueryResponse struct {
 	Success       bool                     `json:"success"`
+	Columns       []ColumnInfo             `json:"columns,omitempty"`
 	Rows          []map[string]interface{} `json:"rows,omitempty"`
 	RowCount      int                      `json:"rowCount,omitempty"`
+	RowsAffected  *int64                   `json:"rowsAffected,omitempty"`
+	CommandTag    string                   `json:"commandTag,omitempty"`
+	Truncated     bool                     `json:"truncated,omitempty"`
+	NextCursor    string                   `json:"nextCursor,omitempty"`
 	ExecutionTime float64                  `json:"executionTime,omitempty"`
 	Error         *ErrorDetail             `json:"error,omitempty"`
 }
 
+// ArrayQueryResponse is a successful QueryResponse whose rows are
+// positional arrays ordered like Columns
+type ArrayQueryResponse struct {
+	*QueryResponse
+	Rows [][]interface{} `json:"rows,omitempty"`
+}
+
+// StatementResult is the result of one statement in a multi-statement response
+type StatementResult struct {
+	Columns      []ColumnInfo `json:"columns,omitempty"`
+	Rows         interface{}  `json:"rows,omitempty"`
+	RowCount     int          `json:"rowCount"`
+	RowsAffected *int64       `json:"rowsAffected,omitempty"`
+	CommandTag   string       `json:"commandTag,omitempty"`
+}
+
+// TransactionResponse represents the response to a transaction request
+type TransactionResponse struct {
+	Success       bool              `json:"success"`
+	Results       []StatementResult `json:"results,omitempty"`
+	ExecutionTime float64           `json:"executionTime,omitempty"`
+	Error         *ErrorDetail      `json:"error,omitempty"`
+}
+
+// BatchResponse represents the results of a batch request. Success is
+// set when every query succeeded.
+type BatchResponse struct {
+	Success       bool                `json:"success"`
+	Results       []BatchItemResponse `json:"results,omitempty"`
+	Succeeded     int                 `json:"succeeded"`
+	Failed        int                 `json:"failed"`
+	Skipped       int                 `json:"skipped"`
+	ExecutionTime float64             `json:"executionTime,omitempty"`
+	Error         *ErrorDetail        `json:"error,omitempty"`
+}
+
+// BatchItemResponse is the result of one query of a batch, shaped like a
+// QueryResponse. Rows are objects or positional arrays according to the
+// batch's format.
+type BatchItemResponse struct {
+	*QueryResponse
+	Rows interface{} `json:"rows,omitempty"`
+	// Skipped is set for queries not run because an earlier one failed
+	// with stopOnError
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// ExplainResponse represents the plan of a statement
+type ExplainResponse struct {
+	Success bool `json:"success"`
+	// Plan is the root node of PostgreSQL's plan tree, with child nodes
+	// under "Plans"
+	Plan map[string]interface{} `json:"plan,omitempty"`
+	// PlanningTime and ActualTime are PostgreSQL's planning and execution
+	// times in milliseconds, reported with analyze
+	PlanningTime  *float64     `json:"planningTime,omitempty"`
+	ActualTime    *float64     `json:"actualTime,omitempty"`
+	ExecutionTime float64      `json:"executionTime,omitempty"`
+	Error         *ErrorDetail `json:"error,omitempty"`
+}
+
+// DryRunResponse reports the rows a dry run would have changed
+type DryRunResponse struct {
+	Success       bool             `json:"success"`
+	DryRun        bool             `json:"dryRun"`
+	Statements    []StatementCount `json:"statements,omitempty"`
+	RowsAffected  int64            `json:"rowsAffected"`
+	ExecutionTime float64          `json:"executionTime,omitempty"`
+	Error         *ErrorDetail     `json:"error,omitempty"`
+}
+
+// StatementCount is the number of rows one statement of a dry run affected
+type StatementCount struct {
+	Command      string `json:"command"`
+	CommandTag   string `json:"commandTag"`
+	RowsAffected int64  `json:"rowsAffected"`
+}
+
+// ImportResponse summarizes a bulk import
+type ImportResponse struct {
+	Success      bool     `json:"success"`
+	Table        string   `json:"table,omitempty"`
+	Columns      []string `json:"columns,omitempty"`
+	Created      bool     `json:"created,omitempty"`
+	RowsLoaded   int64    `json:"rowsLoaded"`
+	RowsRejected int64    `json:"rowsRejected"`
+	// Rejections describes the first rejected records
+	Rejections    []ImportRejection `json:"rejections,omitempty"`
+	ExecutionTime float64           `json:"executionTime,omitempty"`
+	Error         *ErrorDetail      `json:"error,omitempty"`
+}
+
+// ImportRejection describes a record skipped by an import
+type ImportRejection struct {
+	Row    int64  `json:"row"`
+	Reason string `json:"reason"`
+}
+
+// TxResponse represents the response to opening or ending an interactive transaction
+type TxResponse struct {
+	Success       bool   `json:"success"`
+	TransactionID string `json:"transactionId"`
+	// IdleTimeout is the number of idle seconds after which the
+	// transaction is rolled back automatically
+	IdleTimeout float64 `json:"idleTimeout,omitempty"`
+}
+
+// ColumnInfo describes a result column
+type ColumnInfo struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	TypeOID uint32 `json:"typeOid"`
+}
+
 // ErrorDetail represents error information in the response
 type ErrorDetail struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Detail  string `json:"detail,omitempty"`
+	// Statement is the zero-based index of the failing statement in
+	// multi-statement requests
+	Statement *int `json:"statement,omitempty"`
 }
 
 // NewSuccessResponse creates a successful query response
@@ -43,6 +258,66 @@ func NewSuccessResponse(rows []map[string]interface{}, executionTime float64) *Q
 	}
 }
 
+// NewColumnInfos converts executor column metadata into response columns
+func NewColumnInfos(columns []query.Column) []ColumnInfo {
+	if columns == nil {
+		return nil
+	}
+
+	infos := make([]ColumnInfo, len(columns))
+	for i, col := range columns {
+		infos[i] = ColumnInfo{
+			Name:    col.Name,
+			Type:    col.Type,
+			TypeOID: col.TypeOID,
+		}
+	}
+	return infos
+}
+
+// NewStatementResult converts an execution result into a StatementResult,
+// encoding rows as objects or positional arrays according to format
+func NewStatementResult(result *query.ExecutionResult, format string) StatementResult {
+	sr := StatementResult{
+		Columns:      NewColumnInfos(result.Columns),
+		RowCount:     result.RowCount,
+		RowsAffected: rowsAffected(result),
+		CommandTag:   result.CommandTag,
+	}
+	if format == FormatArray {
+		if len(result.Values) > 0 {
+			sr.Rows = result.Values
+		}
+	} else if len(result.Rows) > 0 {
+		sr.Rows = result.Rows
+	}
+	return sr
+}
+
+// newResultResponse converts an execution result into a successful
+// QueryResponse with object rows
+func newResultResponse(result *query.ExecutionResult) *QueryResponse {
+	executionTime := float64(result.ExecutionTime.Microseconds()) / 1000.0
+
+	response := NewSuccessResponse(result.Rows, executionTime)
+	response.Columns = NewColumnInfos(result.Columns)
+	response.RowsAffected = rowsAffected(result)
+	response.CommandTag = result.CommandTag
+	response.Truncated = result.Truncated
+	response.NextCursor = result.NextCursor
+	return response
+}
+
+// rowsAffected returns a result's affected row count, or nil when its
+// command tag is unknown
+func rowsAffected(result *query.ExecutionResult) *int64 {
+	if result.CommandTag == "" {
+		return nil
+	}
+	n := result.RowsAffected
+	return &n
+}
+
 // NewErrorResponse creates an error response from a VibeError
 func NewErrorResponse(err *postgres.VibeError) *QueryResponse {
 	if err == nil {
@@ -65,8 +340,8 @@ func NewErrorResponse(err *postgres.VibeError) *QueryResponse {
 	}
 }
 
-// WriteJSON writes a QueryResponse as JSON to the HTTP response writer
-func WriteJSON(w http.ResponseWriter, statusCode int, response *QueryResponse) error {
+// WriteJSON writes a response as JSON to the HTTP response writer
+func WriteJSON(w http.ResponseWriter, statusCode int, response interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
@@ -80,6 +355,21 @@ func WriteSuccess(w http.ResponseWriter, rows []map[string]interface{}, executio
 	return WriteJSON(w, http.StatusOK, response)
 }
 
+// WriteResult writes an execution result with column metadata and 200 OK
+// status, encoding rows as objects or positional arrays according to format
+func WriteResult(w http.ResponseWriter, result *query.ExecutionResult, format string) error {
+	response := newResultResponse(result)
+	if format == FormatArray {
+		response.Rows = nil
+		return WriteJSON(w, http.StatusOK, &ArrayQueryResponse{
+			QueryResponse: response,
+			Rows:          result.Values,
+		})
+	}
+
+	return WriteJSON(w, http.StatusOK, response)
+}
+
 // WriteError writes an error response with appropriate HTTP status code
 func WriteError(w http.ResponseWriter, err *postgres.VibeError) error {
 	response := NewErrorResponse(err)
@@ -87,3 +377,12 @@ func WriteError(w http.ResponseWriter, err *postgres.VibeError) error {
 	statusCode := postgres.GetHTTPStatusCode(response.Error.Code)
 	return WriteJSON(w, statusCode, response)
 }
+
+// WriteStatementError writes an error response identifying the failing
+// statement of a multi-statement request
+func WriteStatementError(w http.ResponseWriter, err *postgres.VibeError, index int) error {
+	response := NewErrorResponse(err)
+	response.Error.Statement = &index
+	statusCode := postgres.GetHTTPStatusCode(response.Error.Code)
+	return WriteJSON(w, statusCode, response)
+}
@@ -2,12 +2,14 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -24,6 +26,8 @@ const (
 	ShutdownTimeout = 30 * time.Second
 	IdleTimeout     = 30 * time.Second
 	ReadHeaderTimeout = 5 * time.Second
+	// DefaultSocketMode lets only the server's user connect to the socket
+	DefaultSocketMode os.FileMode = 0600
 )
 
 // GetBindHost returns the host to bind to.
@@ -35,63 +39,283 @@ func GetBindHost() string {
 	return DefaultHost
 }
 
+// Options configures the HTTP server. Zero values use the defaults.
+type Options struct {
+	Host           string
+	Port           int
+	MaxConnections int
+	// Limits are the query limits validated by the handler. When unset,
+	// the executor's own limits are used if it reports them.
+	Limits query.Limits
+	// Policy is the safety policy enforced on every query. The zero value
+	// is the default profile.
+	Policy query.Policy
+	// KeysFile is the API keys file. Once it exists, every request except
+	// health checks needs one of its keys. Empty disables authentication.
+	KeysFile string
+	// AllowUnauthenticated starts the server on a non-loopback host even
+	// when requests need neither a key nor a signature
+	AllowUnauthenticated bool
+	// HMACSecret, when set, requires every request except health checks
+	// to be signed with it
+	HMACSecret string
+	// HMACWindow is how far a signed request's timestamp may be from the
+	// server's clock. Zero uses DefaultSignatureWindow.
+	HMACWindow time.Duration
+	// TLS configures HTTPS on the TCP listener. The zero value serves
+	// plain HTTP.
+	TLS TLSOptions
+	// SocketPath, when set, also serves the API on a Unix domain socket
+	SocketPath string
+	// SocketMode is the socket file's permissions. Zero uses
+	// DefaultSocketMode.
+	SocketMode os.FileMode
+	// DisableTCP serves the API only on the socket
+	DisableTCP bool
+}
+
+// limitsReporter is implemented by executors that enforce their own limits
+type limitsReporter interface {
+	Limits() query.Limits
+}
+
 type Server struct {
-	host       string
-	port       int
+	host           string
+	port           int
+	maxConnections int
+	writeTimeout   time.Duration
+	// allowNoAuth is Options.AllowUnauthenticated
+	allowNoAuth    bool
 	httpServer *http.Server
 	listener   net.Listener
 	handler    *Handler
+	auth       *Authenticator
+	signer     *Signer
+	tls        TLSOptions
+	certs      *certificateReloader
+	socketPath string
+	socketMode os.FileMode
+	disableTCP bool
+	socket     net.Listener
 	ready      atomic.Bool
+	probe      DatabaseProbe
+	startedAt  time.Time
 }
 
 func NewServer(executor query.QueryExecutor) *Server {
-	handler := NewHandler(executor)
+	return NewServerWithOptions(executor, Options{})
+}
+
+// NewServerWithOptions creates a server using the given options
+func NewServerWithOptions(executor query.QueryExecutor, opts Options) *Server {
+	if opts.Host == "" {
+		opts.Host = GetBindHost()
+	}
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	if opts.MaxConnections == 0 {
+		opts.MaxConnections = MaxConnections
+	}
+	if opts.SocketMode == 0 {
+		opts.SocketMode = DefaultSocketMode
+	}
+
+	if opts.Limits == (query.Limits{}) {
+		if reporter, ok := executor.(limitsReporter); ok {
+			opts.Limits = reporter.Limits()
+		}
+	}
+
+	handler := NewHandlerWithPolicy(executor, opts.Limits, opts.Policy)
 
 	server := &Server{
-		host:    GetBindHost(),
-		port:    DefaultPort,
-		handler: handler,
+		host:           opts.Host,
+		port:           opts.Port,
+		maxConnections: opts.MaxConnections,
+		writeTimeout:   writeTimeoutFor(handler.limits.QueryTimeout),
+		handler:        handler,
+		tls:            opts.TLS,
+		socketPath:     opts.SocketPath,
+		socketMode:     opts.SocketMode,
+		disableTCP:     opts.DisableTCP,
+		allowNoAuth:    opts.AllowUnauthenticated,
+	}
+	if opts.KeysFile != "" {
+		server.auth = NewAuthenticator(opts.KeysFile, handler.policy)
+	}
+	if opts.HMACSecret != "" {
+		server.signer = NewSigner(opts.HMACSecret, opts.HMACWindow)
+		server.signer.importTimeout = handler.limits.ImportTimeout
 	}
 	server.ready.Store(false)
 	return server
 }
 
+// writeTimeoutFor returns the HTTP write timeout, extended past the query
+// timeout so slow queries can still send their response or timeout error
+func writeTimeoutFor(queryTimeout time.Duration) time.Duration {
+	if minimum := queryTimeout + WriteTimeout/2; minimum > WriteTimeout {
+		return minimum
+	}
+	return WriteTimeout
+}
+
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	s.handler.RegisterRoutes(mux)
+	s.registerHealthRoutes(mux)
 
-	addr := fmt.Sprintf("%s:%d", s.host, s.port)
-	
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to bind to %s: %w", addr, err)
+	var handler http.Handler = mux
+	keys := false
+	if s.auth != nil {
+		enabled, err := s.auth.Enabled()
+		if err != nil {
+			return err
+		}
+		if enabled {
+			log.Printf("[INFO] API key authentication enabled (keys file: %s)", s.auth.path)
+		}
+		keys = enabled
+		// Keys created while the server runs take effect immediately
+		handler = s.auth.Wrap(mux)
+	}
+	if !keys && s.signer == nil && !s.disableTCP && !isLoopback(s.host) {
+		if !s.allowNoAuth {
+			return fmt.Errorf("refusing to listen on %s without API keys: anyone who could reach it would have full database access. Create a key with 'vibe keys create', or set auth.allow_unauthenticated to serve it anyway", s.host)
+		}
+		log.Printf("[WARN] Listening on %s without API keys: anyone who can reach it has full database access", s.host)
+	}
+	if s.signer != nil {
+		log.Printf("[INFO] HMAC request signing required (window: %s)", s.signer.window)
+		handler = s.signer.Wrap(handler)
 	}
-	s.listener = listener
 
-	limitListener := &limitedListener{
-		Listener:       listener,
-		maxConnections: MaxConnections,
-		semaphore:      make(chan struct{}, MaxConnections),
+	var tlsConfig *tls.Config
+	if s.tls.Enabled() {
+		certs, err := s.loadCertificates()
+		if err != nil {
+			return err
+		}
+		s.certs = certs
+		tlsConfig = certs.serverConfig("h2", "http/1.1")
 	}
 
+	if !s.disableTCP {
+		addr := fmt.Sprintf("%s:%d", s.host, s.port)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to bind to %s: %w", addr, err)
+		}
+		s.listener = listener
+	}
+	if s.socketPath != "" {
+		socket, err := listenUnix(s.socketPath, s.socketMode)
+		if err != nil {
+			if s.listener != nil {
+				s.listener.Close()
+			}
+			return err
+		}
+		s.socket = socket
+	}
+
+	// Both listeners draw on one pool of connections, plus one slot kept
+	// for health checks
+	semaphore := make(chan struct{}, s.maxConnections)
+	reserve := make(chan struct{}, 1)
+
 	s.httpServer = &http.Server{
-		Handler:           mux,
+		Handler:           reservedForHealth(handler, s.maxConnections),
+		ConnContext:       withConn,
 		ReadTimeout:       ReadTimeout,
-		WriteTimeout:      WriteTimeout,
+		WriteTimeout:      s.writeTimeout,
 		IdleTimeout:       IdleTimeout,
 		ReadHeaderTimeout: ReadHeaderTimeout,
+		TLSConfig:         tlsConfig,
 	}
 
+	s.startedAt = time.Now()
 	s.ready.Store(true)
-	log.Printf("[INFO] HTTP server listening on %s (max connections: %d)", addr, MaxConnections)
+	if s.listener != nil {
+		log.Printf("[INFO] HTTP server listening on %s (max connections: %d)", s.URL(), s.maxConnections)
+		s.serve(&limitedListener{Listener: s.listener, maxConnections: s.maxConnections, semaphore: semaphore, reserve: reserve}, tlsConfig != nil)
+	}
+	if s.socket != nil {
+		log.Printf("[INFO] HTTP server listening on unix socket %s (mode %04o, max connections: %d)", s.socketPath, s.socketMode, s.maxConnections)
+		s.serve(&limitedListener{Listener: s.socket, maxConnections: s.maxConnections, semaphore: semaphore, reserve: reserve}, false)
+	}
+
+	return nil
+}
 
+// serve serves HTTP on listener in the background, over TLS if useTLS is
+// set
+func (s *Server) serve(listener net.Listener, useTLS bool) {
 	go func() {
-		if err := s.httpServer.Serve(limitListener); err != nil && err != http.ErrServerClosed {
+		var err error
+		if useTLS {
+			// The certificates come from the server's TLSConfig
+			err = s.httpServer.ServeTLS(listener, "", "")
+		} else {
+			err = s.httpServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Printf("[ERROR] HTTP server error: %v", err)
 		}
 	}()
+}
 
-	return nil
+// listenUnix listens on a Unix domain socket at path with the given
+// permissions, replacing a socket file left behind by a server that
+// didn't shut down cleanly
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to listen on %s: socket is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// loadCertificates loads the configured certificate, generating the
+// self-signed one first if no certificate file is set
+func (s *Server) loadCertificates() (*certificateReloader, error) {
+	opts := s.tls
+	if opts.CertFile == "" {
+		certFile, keyFile, err := EnsureSelfSignedCertificate(opts.SelfSignedDir, selfSignedHosts(s.host))
+		if err != nil {
+			return nil, err
+		}
+		opts.CertFile, opts.KeyFile = certFile, keyFile
+	}
+
+	certs, err := newCertificateReloader(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ClientCAFile != "" {
+		log.Printf("[INFO] TLS enabled (certificate: %s), client certificates required (CAs: %s)", opts.CertFile, opts.ClientCAFile)
+	} else {
+		log.Printf("[INFO] TLS enabled (certificate: %s)", opts.CertFile)
+	}
+	return certs, nil
 }
 
 func (s *Server) Stop() error {
@@ -114,6 +338,17 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// TLSConfig returns a TLS configuration serving the server's certificate
+// for the given application protocols, so other listeners such as the
+// PostgreSQL proxy can use it too. It is nil before Start and when TLS is
+// off.
+func (s *Server) TLSConfig(nextProtos ...string) *tls.Config {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.serverConfig(nextProtos...)
+}
+
 func (s *Server) IsReady() bool {
 	return s.ready.Load()
 }
@@ -125,6 +360,20 @@ func (s *Server) Addr() string {
 	return fmt.Sprintf("%s:%d", s.host, s.port)
 }
 
+// SocketPath returns the Unix domain socket the server listens on, empty
+// if none
+func (s *Server) SocketPath() string {
+	return s.socketPath
+}
+
+// URL returns the base URL of the TCP listener, https when TLS is enabled
+func (s *Server) URL() string {
+	if s.tls.Enabled() {
+		return "https://" + s.Addr()
+	}
+	return "http://" + s.Addr()
+}
+
 func (s *Server) WaitForShutdown() {
 	if !s.IsReady() {
 		log.Printf("[WARN] WaitForShutdown called but server not started")
@@ -141,31 +390,108 @@ func (s *Server) WaitForShutdown() {
 	}
 }
 
+// limitedListener caps the connections served at once. Listeners sharing
+// a semaphore share the cap.
 type limitedListener struct {
 	net.Listener
 	maxConnections int
 	semaphore      chan struct{}
+	// reserve, when set, holds slots beyond the cap that only serve health
+	// checks, so probes get an answer while every slot is busy
+	reserve chan struct{}
+
+	mu     sync.Mutex
+	closed chan struct{}
 }
 
+// Accept waits for a connection, then for a free slot. Taking the slot
+// only once a connection arrives keeps an idle listener from holding
+// slots that another listener sharing the semaphore needs.
 func (l *limitedListener) Accept() (net.Conn, error) {
-	l.semaphore <- struct{}{}
-
 	conn, err := l.Listener.Accept()
 	if err != nil {
-		<-l.semaphore
 		return nil, err
 	}
 
-	return &limitedConn{
-		Conn:      conn,
-		semaphore: l.semaphore,
-	}, nil
+	// Prefer a regular slot, falling back to the reserve only when every
+	// regular slot is taken
+	select {
+	case l.semaphore <- struct{}{}:
+		return &limitedConn{Conn: conn, semaphore: l.semaphore}, nil
+	default:
+	}
+
+	select {
+	case l.semaphore <- struct{}{}:
+		return &limitedConn{Conn: conn, semaphore: l.semaphore}, nil
+	case l.reserve <- struct{}{}:
+		return &limitedConn{Conn: conn, semaphore: l.reserve, reserved: true}, nil
+	case <-l.done():
+		conn.Close()
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the listener and releases an Accept waiting for a slot
+func (l *limitedListener) Close() error {
+	done := l.done()
+	l.mu.Lock()
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+	l.mu.Unlock()
+	return l.Listener.Close()
+}
+
+// done returns a channel that is closed when the listener is closed
+func (l *limitedListener) done() chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed == nil {
+		l.closed = make(chan struct{})
+	}
+	return l.closed
 }
 
 type limitedConn struct {
 	net.Conn
 	semaphore chan struct{}
 	released  bool
+	// reserved is set for connections holding a reserve slot
+	reserved bool
+}
+
+type connKey struct{}
+
+// withConn records the connection a request arrived on in its context
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// reservedForHealth only serves health checks on connections holding a
+// reserve slot, answering other requests with 503. Those connections are
+// closed after each response, freeing the slot for the next probe.
+func reservedForHealth(next http.Handler, maxConnections int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _ := r.Context().Value(connKey{}).(*limitedConn)
+		if conn == nil || !conn.reserved {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Connection", "close")
+		if !isHealthPath(r.URL.Path) {
+			WriteError(w, NewServiceUnavailableError(fmt.Sprintf("All %d connections are in use. Retry once a request finishes", maxConnections)))
+			log.Printf("[WARN] Rejected %s %s from %s: all %d connections are in use", r.Method, r.URL.Path, r.RemoteAddr, maxConnections)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (c *limitedConn) Close() error {
@@ -2,9 +2,14 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -15,7 +20,7 @@ import (
 
 type mockExecutor struct{}
 
-func (m *mockExecutor) Execute(sql string) (*query.ExecutionResult, error) {
+func (m *mockExecutor) ExecuteWithOptions(sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
 	return &query.ExecutionResult{
 		Rows:          []map[string]interface{}{{"result": "ok"}},
 		RowCount:      1,
@@ -23,6 +28,81 @@ func (m *mockExecutor) Execute(sql string) (*query.ExecutionResult, error) {
 	}, nil
 }
 
+func (m *mockExecutor) ExecuteTransaction(statements []query.Statement, opts query.Options) ([]*query.ExecutionResult, error) {
+	results := make([]*query.ExecutionResult, len(statements))
+	for i := range statements {
+		results[i], _ = m.ExecuteWithOptions(statements[i].SQL, statements[i].Params, opts)
+	}
+	return results, nil
+}
+
+func (m *mockExecutor) ExecuteStream(sql string, params []interface{}, opts query.Options, w query.RowWriter) (*query.ExecutionResult, error) {
+	result, _ := m.ExecuteWithOptions(sql, params, opts)
+	if err := w.WriteColumns([]query.Column{{Name: "result", Type: "text", TypeOID: 25}}); err != nil {
+		return nil, err
+	}
+	if err := w.WriteRow([]interface{}{"ok"}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *mockExecutor) ExecuteBatch(statements []query.Statement, opts query.Options, batch query.BatchOptions) []query.BatchResult {
+	results := make([]query.BatchResult, len(statements))
+	for i := range statements {
+		results[i].Result, _ = m.ExecuteWithOptions(statements[i].SQL, statements[i].Params, opts)
+	}
+	return results
+}
+
+func (m *mockExecutor) Import(r io.Reader, opts query.ImportOptions) (*query.ImportResult, error) {
+	return &query.ImportResult{
+		Table:         opts.Table,
+		Columns:       opts.Columns,
+		ExecutionTime: time.Millisecond,
+	}, nil
+}
+
+func (m *mockExecutor) Explain(sql string, params []interface{}, opts query.Options, explain query.ExplainOptions) (*query.ExplainResult, error) {
+	return &query.ExplainResult{
+		Plan:          map[string]interface{}{"Node Type": "Result"},
+		Analyzed:      explain.Analyze,
+		ExecutionTime: time.Millisecond,
+	}, nil
+}
+
+func (m *mockExecutor) DryRun(sql string, params []interface{}, opts query.Options) (*query.DryRunResult, error) {
+	return &query.DryRunResult{
+		Statements:    []query.StatementCount{{Command: "UPDATE", RowsAffected: 1}},
+		RowsAffected:  1,
+		ExecutionTime: time.Millisecond,
+	}, nil
+}
+
+func (m *mockExecutor) ExecutePage(sql string, params []interface{}, opts query.Options, limit int) (*query.ExecutionResult, error) {
+	return m.ExecuteWithOptions(sql, params, opts)
+}
+
+func (m *mockExecutor) FetchPage(cursor string, opts query.Options, limit int) (*query.ExecutionResult, error) {
+	return m.ExecuteWithOptions("", nil, opts)
+}
+
+func (m *mockExecutor) BeginSession(opts query.Options) (string, error) {
+	return "mock-tx", nil
+}
+
+func (m *mockExecutor) ExecuteInSession(id string, sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	return m.ExecuteWithOptions(sql, params, opts)
+}
+
+func (m *mockExecutor) CommitSession(id string, opts query.Options) error {
+	return nil
+}
+
+func (m *mockExecutor) RollbackSession(id string, opts query.Options) error {
+	return nil
+}
+
 func newTestServer() *Server {
 	executor := &mockExecutor{}
 	return NewServer(executor)
@@ -416,6 +496,73 @@ func TestLimitedListener_Functionality(t *testing.T) {
 	}
 }
 
+func TestLimitedListener_Reserve(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+	defer listener.Close()
+
+	limitListener := &limitedListener{
+		Listener:       listener,
+		maxConnections: 1,
+		semaphore:      make(chan struct{}, 1),
+		reserve:        make(chan struct{}, 1),
+	}
+
+	var accepted []*limitedConn
+	for i := 0; i < 2; i++ {
+		client, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial %d failed: %v", i, err)
+		}
+		defer client.Close()
+
+		conn, err := limitListener.Accept()
+		if err != nil {
+			t.Fatalf("Accept %d failed: %v", i, err)
+		}
+		defer conn.Close()
+		accepted = append(accepted, conn.(*limitedConn))
+	}
+
+	if accepted[0].reserved || !accepted[1].reserved {
+		t.Errorf("Expected only the connection beyond the cap to be reserved, got %v and %v", accepted[0].reserved, accepted[1].reserved)
+	}
+}
+
+func TestReservedForHealth(t *testing.T) {
+	var served []string
+	handler := reservedForHealth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.URL.Path)
+	}), 2)
+
+	for _, tt := range []struct {
+		path     string
+		reserved bool
+		want     int
+	}{
+		{"/v1/query", false, http.StatusOK},
+		{"/v1/query", true, http.StatusServiceUnavailable},
+		{"/v1/health/ready", true, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), connKey{}, &limitedConn{reserved: tt.reserved}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s (reserved: %v): expected status %d, got %d", tt.path, tt.reserved, tt.want, w.Code)
+		}
+		if tt.reserved && w.Header().Get("Connection") != "close" {
+			t.Errorf("%s: expected reserved connections to close after the response", tt.path)
+		}
+	}
+	if strings.Join(served, ",") != "/v1/query,/v1/health/ready" {
+		t.Errorf("Served %v", served)
+	}
+}
+
 func TestLimitedConn_Close(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -454,6 +601,171 @@ func TestLimitedConn_Close(t *testing.T) {
 	}
 }
 
+type limitedExecutor struct {
+	mockExecutor
+	limits query.Limits
+}
+
+func (e *limitedExecutor) Limits() query.Limits {
+	return e.limits
+}
+
+func TestNewServerWithOptions(t *testing.T) {
+	executor := &limitedExecutor{limits: query.Limits{QueryTimeout: time.Minute, MaxResultRows: 50000, MaxQuerySize: 64 * 1024, MaxTxSessions: 3, ImportTimeout: time.Hour}}
+
+	server := NewServerWithOptions(executor, Options{Host: "127.0.0.2", Port: 6000, MaxConnections: 8})
+
+	if server.host != "127.0.0.2" || server.port != 6000 || server.maxConnections != 8 {
+		t.Errorf("Unexpected address settings: %s:%d max=%d", server.host, server.port, server.maxConnections)
+	}
+	if server.handler.limits != executor.limits {
+		t.Errorf("Expected handler to use the executor limits %+v, got %+v", executor.limits, server.handler.limits)
+	}
+	if server.writeTimeout <= time.Minute {
+		t.Errorf("Expected write timeout beyond the 1m query timeout, got %v", server.writeTimeout)
+	}
+}
+
+func TestServer_Start_RefusesUnauthenticatedNetworkHost(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "vibe-keys.json")
+
+	server := NewServerWithOptions(&mockExecutor{}, Options{Host: "0.0.0.0", Port: 6003, KeysFile: keysFile})
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Fatal("Expected a non-loopback host without API keys to be refused")
+	} else if !strings.Contains(err.Error(), "auth.allow_unauthenticated") {
+		t.Errorf("Expected the error to name the opt-out setting, got: %v", err)
+	}
+
+	for name, opts := range map[string]Options{
+		"opted out": {Port: 6003, AllowUnauthenticated: true},
+		"signed":    {Port: 6004, HMACSecret: "s3cret"},
+	} {
+		opts.Host, opts.KeysFile = "0.0.0.0", keysFile
+		server := NewServerWithOptions(&mockExecutor{}, opts)
+		if err := server.Start(); err != nil {
+			t.Errorf("%s: expected the server to start, got: %v", name, err)
+			continue
+		}
+		server.Stop()
+	}
+}
+
+func TestNewServer_Defaults(t *testing.T) {
+	server := newTestServer()
+
+	if server.port != DefaultPort || server.maxConnections != MaxConnections {
+		t.Errorf("Expected default port and connections, got %d and %d", server.port, server.maxConnections)
+	}
+	if server.handler.limits != query.DefaultLimits() {
+		t.Errorf("Expected default limits, got %+v", server.handler.limits)
+	}
+	if server.writeTimeout != WriteTimeout {
+		t.Errorf("Expected write timeout %v, got %v", WriteTimeout, server.writeTimeout)
+	}
+}
+
+// unixClient returns an HTTP client that connects to the socket at path
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		},
+		Timeout: 5 * time.Second,
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, too short for t.TempDir
+	dir, err := os.MkdirTemp("", "vibe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vibe.sock")
+
+	// A socket file left behind by a crashed server is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	server := NewServerWithOptions(&mockExecutor{}, Options{SocketPath: path, DisableTCP: true})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected a socket file: %v", err)
+	}
+	if info.Mode().Perm() != DefaultSocketMode {
+		t.Errorf("Expected socket mode %v, got %v", DefaultSocketMode, info.Mode().Perm())
+	}
+	if server.listener != nil {
+		t.Error("Expected no TCP listener")
+	}
+
+	resp, err := unixClient(path).Post("http://vibe/v1/query", "application/json", strings.NewReader(`{"sql": "SELECT 1"}`))
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// A second server can't take over a socket in use
+	second := NewServerWithOptions(&mockExecutor{}, Options{SocketPath: path, DisableTCP: true})
+	if err := second.Start(); err == nil {
+		second.Stop()
+		t.Error("Expected an error for a socket in use")
+	}
+
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Failed to stop server: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket file to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestServer_UnixSocketAndTCP(t *testing.T) {
+	dir, err := os.MkdirTemp("", "vibe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vibe.sock")
+
+	server := NewServerWithOptions(&mockExecutor{}, Options{Port: 6002, SocketPath: path, SocketMode: 0660})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %v, %v", info, err)
+	}
+
+	for name, client := range map[string]*http.Client{"tcp": http.DefaultClient, "unix": unixClient(path)} {
+		resp, err := client.Get("http://" + server.Addr() + "/v1/health")
+		if err != nil {
+			t.Errorf("%s: request failed: %v", name, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected status 200, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
 func TestServer_Constants(t *testing.T) {
 	tests := []struct {
 		name     string
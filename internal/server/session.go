@@ -0,0 +1,150 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+const txPathPrefix = "/v1/tx/"
+
+// HandleTxBegin opens an interactive transaction and returns its ID.
+// The transaction is rolled back automatically after query.TxIdleTimeout
+// without activity.
+func (h *Handler) HandleTxBegin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/tx endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	policy := h.policyFor(r)
+	id, err := h.executor.BeginSession(query.Options{ReadOnly: policy.ReadOnly, Role: policy.Role, Owner: keyIDFor(r)})
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Failed to begin transaction: %v", err)
+		return
+	}
+
+	response := &TxResponse{
+		Success:       true,
+		TransactionID: id,
+		IdleTimeout:   query.TxIdleTimeout.Seconds(),
+	}
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Transaction %s started", id)
+}
+
+// HandleTxAction serves /v1/tx/{id}/query, /v1/tx/{id}/commit and
+// /v1/tx/{id}/rollback
+func (h *Handler) HandleTxAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/tx endpoints")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	id, action, ok := parseTxPath(r.URL.Path)
+	if !ok {
+		WriteError(w, NewInvalidSQLError("Expected /v1/tx/{id}/query, /v1/tx/{id}/commit or /v1/tx/{id}/rollback"))
+		log.Printf("[ERROR] Invalid transaction path: %s", r.URL.Path)
+		return
+	}
+
+	switch action {
+	case "query":
+		h.handleTxQuery(w, r, id)
+	case "commit":
+		h.handleTxEnd(w, r, id, true)
+	case "rollback":
+		h.handleTxEnd(w, r, id, false)
+	}
+}
+
+func (h *Handler) handleTxQuery(w http.ResponseWriter, r *http.Request, id string) {
+	req, params, ok := h.readQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.paginated() {
+		WriteError(w, NewInvalidSQLError("'limit' and 'cursor' are not supported inside interactive transactions. Use LIMIT and OFFSET instead"))
+		log.Printf("[ERROR] Pagination requested inside transaction %s", id)
+		return
+	}
+
+	if req.Explain || req.DryRun {
+		WriteError(w, NewInvalidSQLError("'explain' and 'dryRun' are not supported inside interactive transactions. Use /v1/explain or /v1/query instead"))
+		log.Printf("[ERROR] Explain or dry run requested inside transaction %s", id)
+		return
+	}
+
+	result, err := h.executor.ExecuteInSession(id, req.SQL, params, req.options())
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Transaction %s query failed: %v", id, err)
+		return
+	}
+
+	executionTimeMs := float64(result.ExecutionTime.Microseconds()) / 1000.0
+
+	if err := WriteResult(w, result, req.Format); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Transaction %s query succeeded: %d rows returned in %.2fms", id, result.RowCount, executionTimeMs)
+}
+
+func (h *Handler) handleTxEnd(w http.ResponseWriter, r *http.Request, id string, commit bool) {
+	opts := query.Options{Owner: keyIDFor(r)}
+
+	var err error
+	action := "committed"
+	if commit {
+		err = h.executor.CommitSession(id, opts)
+	} else {
+		action = "rolled back"
+		err = h.executor.RollbackSession(id, opts)
+	}
+
+	if err != nil {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Transaction %s could not be %s: %v", id, action, err)
+		return
+	}
+
+	response := &TxResponse{
+		Success:       true,
+		TransactionID: id,
+	}
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Transaction %s %s", id, action)
+}
+
+// parseTxPath splits /v1/tx/{id}/{action} into its ID and action
+func parseTxPath(path string) (id string, action string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(path, txPathPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+
+	switch parts[1] {
+	case "query", "commit", "rollback":
+		return parts[0], parts[1], true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+type sessionExecutor struct {
+	mockExecutor
+	open      map[string]bool
+	lastQuery string
+	ended     string
+	// owners records the Owner option of each call
+	owners []string
+}
+
+func newSessionExecutor() *sessionExecutor {
+	return &sessionExecutor{open: map[string]bool{}}
+}
+
+func (e *sessionExecutor) BeginSession(opts query.Options) (string, error) {
+	e.owners = append(e.owners, opts.Owner)
+	e.open["tx1"] = true
+	return "tx1", nil
+}
+
+func (e *sessionExecutor) ExecuteInSession(id string, sql string, params []interface{}, opts query.Options) (*query.ExecutionResult, error) {
+	e.owners = append(e.owners, opts.Owner)
+	if !e.open[id] {
+		return nil, postgres.NewVibeError(postgres.ErrorCodeTransactionNotFound, "Transaction not found", "")
+	}
+	e.lastQuery = sql
+	return &query.ExecutionResult{
+		Rows:     []map[string]interface{}{{"id": int64(1)}},
+		Values:   [][]interface{}{{int64(1)}},
+		RowCount: 1,
+	}, nil
+}
+
+func (e *sessionExecutor) CommitSession(id string, opts query.Options) error {
+	e.owners = append(e.owners, opts.Owner)
+	return e.end(id, "commit")
+}
+
+func (e *sessionExecutor) RollbackSession(id string, opts query.Options) error {
+	return e.end(id, "rollback")
+}
+
+func (e *sessionExecutor) end(id string, action string) error {
+	if !e.open[id] {
+		return postgres.NewVibeError(postgres.ErrorCodeTransactionNotFound, "Transaction not found", "")
+	}
+	delete(e.open, id)
+	e.ended = action
+	return nil
+}
+
+func serveTx(handler *Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleTx_Lifecycle(t *testing.T) {
+	executor := newSessionExecutor()
+	handler := NewHandler(executor)
+
+	w := serveTx(handler, http.MethodPost, "/v1/tx", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on begin, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var begin TxResponse
+	if err := json.NewDecoder(w.Body).Decode(&begin); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if begin.TransactionID != "tx1" {
+		t.Fatalf("Expected transactionId tx1, got %q", begin.TransactionID)
+	}
+	if begin.IdleTimeout != query.TxIdleTimeout.Seconds() {
+		t.Errorf("Expected idleTimeout %v, got %v", query.TxIdleTimeout.Seconds(), begin.IdleTimeout)
+	}
+
+	w = serveTx(handler, http.MethodPost, "/v1/tx/tx1/query", `{"sql": "SELECT id FROM accounts WHERE id = $1 FOR UPDATE", "params": [1]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on query, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(executor.lastQuery, "SELECT id FROM accounts") {
+		t.Errorf("Expected query to run in session, got %q", executor.lastQuery)
+	}
+
+	w = serveTx(handler, http.MethodPost, "/v1/tx/tx1/commit", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on commit, got %d: %s", w.Code, w.Body.String())
+	}
+	if executor.ended != "commit" {
+		t.Errorf("Expected commit, got %q", executor.ended)
+	}
+
+	w = serveTx(handler, http.MethodPost, "/v1/tx/tx1/rollback", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after transaction ended, got %d", w.Code)
+	}
+}
+
+func TestHandleTx_KeyOwnsTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := &KeyStore{}
+	key, secret, _ := store.Create("app", ScopeReadWrite)
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	executor := newSessionExecutor()
+	mux := http.NewServeMux()
+	NewHandler(executor).RegisterRoutes(mux)
+	handler := NewAuthenticator(path, query.DefaultPolicy()).Wrap(mux)
+
+	for _, path := range []string{"/v1/tx", "/v1/tx/tx1/query", "/v1/tx/tx1/commit"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"sql": "SELECT 1"}`))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+	for i, owner := range executor.owners {
+		if owner != key.ID {
+			t.Errorf("Call %d: expected the key %s as owner, got %q", i, key.ID, owner)
+		}
+	}
+}
+
+func TestHandleTx_UnsafeQueryRejected(t *testing.T) {
+	executor := newSessionExecutor()
+	executor.open["tx1"] = true
+	handler := NewHandler(executor)
+
+	w := serveTx(handler, http.MethodPost, "/v1/tx/tx1/query", `{"sql": "DELETE FROM accounts"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	if executor.lastQuery != "" {
+		t.Error("Unsafe query should not reach the executor")
+	}
+}
+
+func TestHandleTx_InvalidPath(t *testing.T) {
+	handler := NewHandler(newSessionExecutor())
+
+	for _, path := range []string{"/v1/tx/tx1", "/v1/tx/tx1/explode", "/v1/tx/tx1/query/extra"} {
+		w := serveTx(handler, http.MethodPost, path, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestHandleTx_MethodNotAllowed(t *testing.T) {
+	handler := NewHandler(newSessionExecutor())
+
+	for _, path := range []string{"/v1/tx", "/v1/tx/tx1/commit"} {
+		w := serveTx(handler, http.MethodGet, path, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestParseTxPath(t *testing.T) {
+	id, action, ok := parseTxPath("/v1/tx/abc123/rollback")
+	if !ok || id != "abc123" || action != "rollback" {
+		t.Errorf("parseTxPath() = %q, %q, %v", id, action, ok)
+	}
+
+	if _, _, ok := parseTxPath("/v1/tx//query"); ok {
+		t.Error("parseTxPath() should reject an empty transaction ID")
+	}
+}
+
+func TestHandleTx_PaginationRejected(t *testing.T) {
+	executor := newSessionExecutor()
+	executor.open["tx1"] = true
+	handler := NewHandler(executor)
+
+	for _, body := range []string{
+		`{"sql": "SELECT * FROM accounts", "limit": 10}`,
+		`{"sql": "SELECT * FROM accounts", "cursor": "c1"}`,
+	} {
+		w := serveTx(handler, http.MethodPost, "/v1/tx/tx1/query", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+	if executor.lastQuery != "" {
+		t.Error("Paginated query should not reach the executor")
+	}
+}
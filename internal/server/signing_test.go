@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signingMux returns the handler's routes plus a health check
+func signingMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	NewHandler(&mockExecutor{}).RegisterRoutes(mux)
+	mux.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// signedRequest returns a POST request to path with body, signed by signer
+func signedRequest(signer *Signer, path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	signer.SignRequest(req, []byte(body))
+	return req
+}
+
+func TestSigner_Wrap(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	signer := NewSigner("s3cret", time.Minute)
+	signer.now = func() time.Time { return now }
+
+	other := NewSigner("other", time.Minute)
+	other.now = signer.now
+
+	stale := NewSigner("s3cret", time.Minute)
+	stale.now = func() time.Time { return now.Add(-2 * time.Minute) }
+
+	handler := signer.Wrap(signingMux())
+	body := `{"sql": "SELECT 1"}`
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{"signed", func() *http.Request { return signedRequest(signer, "/v1/query", body) }, http.StatusOK},
+		{"unsigned", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(body))
+		}, http.StatusUnauthorized},
+		{"wrong secret", func() *http.Request { return signedRequest(other, "/v1/query", body) }, http.StatusUnauthorized},
+		{"outside window", func() *http.Request { return signedRequest(stale, "/v1/query", body) }, http.StatusUnauthorized},
+		{"different path", func() *http.Request {
+			req := signedRequest(signer, "/v1/query", body)
+			req.URL.Path = "/v1/batch"
+			return req
+		}, http.StatusUnauthorized},
+		{"tampered body", func() *http.Request {
+			req := signedRequest(signer, "/v1/query", body)
+			req.Body = io.NopCloser(strings.NewReader(`{"sql": "DROP TABLE users"}`))
+			return req
+		}, http.StatusUnauthorized},
+		{"health check", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, tt.req())
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSigner_Wrap_Replay(t *testing.T) {
+	signer := NewSigner("s3cret", 0)
+	handler := signer.Wrap(signingMux())
+
+	req := signedRequest(signer, "/v1/query", `{"sql": "SELECT 1"}`)
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{"sql": "SELECT 1"}`))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, replay)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a replayed request to be rejected, got %d", w.Code)
+	}
+
+	var response QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.Code != ErrorCodeUnauthorized || !strings.Contains(response.Error.Detail, "already been used") {
+		t.Errorf("Expected an UNAUTHORIZED replay error, got %+v", response.Error)
+	}
+}
+
+func TestSigner_Wrap_ChecksBodyBeforeDispatch(t *testing.T) {
+	signer := NewSigner("s3cret", 0)
+
+	for _, size := range []int{64, signedBodyMemory + 1024} {
+		body := "id\n" + strings.Repeat("1\n", size/2)
+		tampered := strings.Replace(body, "1", "2", 1)
+
+		for _, tt := range []struct {
+			name string
+			sent string
+			want int
+		}{
+			{"matching", body, http.StatusOK},
+			{"tampered", tampered, http.StatusUnauthorized},
+		} {
+			executor := &importExecutor{}
+			mux := http.NewServeMux()
+			NewHandler(executor).RegisterRoutes(mux)
+
+			req := signedRequest(signer, "/v1/import/events", body)
+			req.Header.Set("Content-Type", "text/csv")
+			req.Body = io.NopCloser(strings.NewReader(tt.sent))
+			w := httptest.NewRecorder()
+			signer.Wrap(mux).ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("%d bytes, %s: expected status %d, got %d: %s", size, tt.name, tt.want, w.Code, w.Body.String())
+			}
+			if tt.want == http.StatusOK && executor.data != body {
+				t.Errorf("%d bytes, %s: expected the import to read the whole body, got %d bytes", size, tt.name, len(executor.data))
+			}
+			if tt.want != http.StatusOK && executor.opts != nil {
+				t.Errorf("%d bytes, %s: expected the import not to run", size, tt.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// ContentTypeNDJSON is the media type of streamed query results
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// streamFlushRows is how many rows are buffered before flushing a stream
+// to the client
+const streamFlushRows = 100
+
+// StreamHeader is the first line of a streamed result
+type StreamHeader struct {
+	Columns []ColumnInfo `json:"columns"`
+}
+
+// StreamSummary reports the outcome of a streamed result. It is sent as
+// the last line, wrapped in StreamTrailer, and is the only place an error
+// that occurs after the first row can be reported.
+type StreamSummary struct {
+	Success       bool         `json:"success"`
+	RowCount      int          `json:"rowCount"`
+	ExecutionTime float64      `json:"executionTime"`
+	Error         *ErrorDetail `json:"error,omitempty"`
+}
+
+// StreamTrailer is the last line of a streamed result
+type StreamTrailer struct {
+	Summary StreamSummary `json:"summary"`
+}
+
+// acceptsNDJSON reports whether the client asked for an NDJSON response
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == ContentTypeNDJSON {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ndjsonWriter writes rows to an HTTP response as NDJSON. The status and
+// headers are sent with the column header line, so errors before it can
+// still be returned as a regular error response.
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	format  string
+	columns []query.Column
+
+	started bool
+	rows    int
+}
+
+func newNDJSONWriter(w http.ResponseWriter, format string) *ndjsonWriter {
+	return &ndjsonWriter{
+		w:       w,
+		encoder: json.NewEncoder(w),
+		format:  format,
+	}
+}
+
+func (s *ndjsonWriter) WriteColumns(columns []query.Column) error {
+	s.w.Header().Set("Content-Type", ContentTypeNDJSON)
+	s.w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+	s.columns = columns
+
+	if err := s.encoder.Encode(StreamHeader{Columns: NewColumnInfos(columns)}); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+func (s *ndjsonWriter) WriteRow(values []interface{}) error {
+	var err error
+	if s.format == FormatArray {
+		err = s.encoder.Encode(values)
+	} else {
+		row := make(map[string]interface{}, len(values))
+		for i, col := range s.columns {
+			row[col.Name] = values[i]
+		}
+		err = s.encoder.Encode(row)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%streamFlushRows == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// finish writes the summary line
+func (s *ndjsonWriter) finish(executionTime time.Duration, err error) error {
+	summary := StreamSummary{
+		Success:       err == nil,
+		RowCount:      s.rows,
+		ExecutionTime: float64(executionTime.Microseconds()) / 1000.0,
+	}
+	if err != nil {
+		summary.Error = NewErrorResponse(asVibeError(err)).Error
+	}
+
+	if encErr := s.encoder.Encode(StreamTrailer{Summary: summary}); encErr != nil {
+		return encErr
+	}
+	s.flush()
+	return nil
+}
+
+func (s *ndjsonWriter) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// handleStream runs a validated query and streams its rows as NDJSON
+func (h *Handler) handleStream(w http.ResponseWriter, req *QueryRequest, params []interface{}) {
+	if req.paginated() || req.Truncate {
+		WriteError(w, NewInvalidSQLError("'limit', 'cursor' and 'truncate' cannot be combined with streaming"))
+		log.Printf("[ERROR] Pagination or truncation requested for a streamed query")
+		return
+	}
+
+	startTime := time.Now()
+	stream := newNDJSONWriter(w, req.Format)
+
+	result, err := h.executor.ExecuteStream(req.SQL, params, req.options(), stream)
+	if err != nil && !stream.started {
+		WriteError(w, asVibeError(err))
+		log.Printf("[ERROR] Query execution failed: %v", err)
+		return
+	}
+
+	executionTime := time.Since(startTime)
+	if result != nil {
+		executionTime = result.ExecutionTime
+	}
+
+	if finishErr := stream.finish(executionTime, err); finishErr != nil {
+		log.Printf("[ERROR] Failed to write stream summary: %v", finishErr)
+		return
+	}
+
+	if err != nil {
+		log.Printf("[ERROR] Query stream failed after %d rows: %v", stream.rows, err)
+		return
+	}
+	log.Printf("[INFO] Query streamed: %d rows in %.2fms", stream.rows, float64(executionTime.Microseconds())/1000.0)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+type streamExecutor struct {
+	mockExecutor
+	rows int
+	// failAfter returns a mid-stream error after this many rows when set
+	failAfter int
+	// failBefore returns an error before any output when set
+	failBefore bool
+	called     bool
+}
+
+func (e *streamExecutor) ExecuteStream(sql string, params []interface{}, opts query.Options, w query.RowWriter) (*query.ExecutionResult, error) {
+	e.called = true
+	if e.failBefore {
+		return nil, postgres.NewVibeError(postgres.ErrorCodeInvalidSQL, "Invalid SQL syntax", "syntax error")
+	}
+
+	columns := []query.Column{{Name: "id", Type: "int8", TypeOID: 20}, {Name: "name", Type: "text", TypeOID: 25}}
+	if err := w.WriteColumns(columns); err != nil {
+		return nil, err
+	}
+	for i := 0; i < e.rows; i++ {
+		if e.failAfter > 0 && i == e.failAfter {
+			return nil, postgres.NewVibeError(postgres.ErrorCodeQueryTimeout, "Query timeout", "")
+		}
+		if err := w.WriteRow([]interface{}{int64(i + 1), "row"}); err != nil {
+			return nil, err
+		}
+	}
+	return &query.ExecutionResult{Columns: columns, RowCount: e.rows}, nil
+}
+
+func postStream(handler *Handler, body string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/query", strings.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.HandleQuery(w, req)
+	return w
+}
+
+func readLines(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func decodeSummary(t *testing.T, line string) StreamSummary {
+	t.Helper()
+	var trailer StreamTrailer
+	if err := json.Unmarshal([]byte(line), &trailer); err != nil {
+		t.Fatalf("Failed to decode summary %q: %v", line, err)
+	}
+	return trailer.Summary
+}
+
+func TestHandleQuery_Stream(t *testing.T) {
+	executor := &streamExecutor{rows: 250}
+	w := postStream(NewHandler(executor), `{"sql": "SELECT id, name FROM items", "stream": true}`, "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeNDJSON {
+		t.Errorf("Expected Content-Type %s, got %s", ContentTypeNDJSON, ct)
+	}
+
+	lines := readLines(t, w)
+	if len(lines) != 252 {
+		t.Fatalf("Expected header, 250 rows and summary, got %d lines", len(lines))
+	}
+
+	var header StreamHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("Failed to decode header: %v", err)
+	}
+	if len(header.Columns) != 2 || header.Columns[0].Name != "id" {
+		t.Errorf("Unexpected columns: %+v", header.Columns)
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil {
+		t.Fatalf("Failed to decode row: %v", err)
+	}
+	if row["id"] != float64(1) || row["name"] != "row" {
+		t.Errorf("Unexpected first row: %v", row)
+	}
+
+	summary := decodeSummary(t, lines[251])
+	if !summary.Success || summary.RowCount != 250 || summary.Error != nil {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
+
+func TestHandleQuery_StreamAcceptHeader(t *testing.T) {
+	testCases := []struct {
+		accept string
+		stream bool
+	}{
+		{"application/x-ndjson", true},
+		{"application/json, application/x-ndjson;q=0.9", true},
+		{"application/json", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		executor := &streamExecutor{rows: 1}
+		postStream(NewHandler(executor), `{"sql": "SELECT 1"}`, tc.accept)
+		if executor.called != tc.stream {
+			t.Errorf("Accept %q: expected stream=%v, got %v", tc.accept, tc.stream, executor.called)
+		}
+	}
+}
+
+func TestHandleQuery_StreamArrayFormat(t *testing.T) {
+	executor := &streamExecutor{rows: 1}
+	w := postStream(NewHandler(executor), `{"sql": "SELECT id, name FROM items", "format": "array"}`, ContentTypeNDJSON)
+
+	lines := readLines(t, w)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if lines[1] != `[1,"row"]` {
+		t.Errorf("Expected positional row, got %s", lines[1])
+	}
+}
+
+func TestHandleQuery_StreamMidStreamError(t *testing.T) {
+	executor := &streamExecutor{rows: 10, failAfter: 3}
+	w := postStream(NewHandler(executor), `{"sql": "SELECT id, name FROM items", "stream": true}`, "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 once streaming started, got %d", w.Code)
+	}
+
+	lines := readLines(t, w)
+	if len(lines) != 5 {
+		t.Fatalf("Expected header, 3 rows and summary, got %d lines", len(lines))
+	}
+
+	summary := decodeSummary(t, lines[4])
+	if summary.Success || summary.RowCount != 3 {
+		t.Errorf("Expected failed summary after 3 rows, got %+v", summary)
+	}
+	if summary.Error == nil || summary.Error.Code != ErrorCodeQueryTimeout {
+		t.Errorf("Expected QUERY_TIMEOUT in summary, got %+v", summary.Error)
+	}
+}
+
+func TestHandleQuery_StreamErrorBeforeRows(t *testing.T) {
+	executor := &streamExecutor{failBefore: true}
+	w := postStream(NewHandler(executor), `{"sql": "SELECT id FROM items", "stream": true}`, "")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	var response QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Success || response.Error == nil || response.Error.Code != ErrorCodeInvalidSQL {
+		t.Errorf("Expected INVALID_SQL error response, got %+v", response)
+	}
+}
+
+func TestHandleQuery_StreamRejectsPagination(t *testing.T) {
+	for _, body := range []string{
+		`{"sql": "SELECT 1", "stream": true, "limit": 10}`,
+		`{"sql": "SELECT 1", "stream": true, "truncate": true}`,
+	} {
+		executor := &streamExecutor{rows: 1}
+		w := postStream(NewHandler(executor), body, "")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+		if executor.called {
+			t.Errorf("%s: should not reach the executor", body)
+		}
+	}
+}
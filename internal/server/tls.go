@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// SelfSignedCertFile and SelfSignedKeyFile are the names of the
+	// generated certificate and key in TLSOptions.SelfSignedDir
+	SelfSignedCertFile = "server.crt"
+	SelfSignedKeyFile  = "server.key"
+
+	// selfSignedValidity is how long a generated certificate is valid
+	selfSignedValidity = 365 * 24 * time.Hour
+	// selfSignedRenewBefore is how long before expiry a generated
+	// certificate is replaced
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// TLSOptions configures HTTPS. The zero value serves plain HTTP.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM certificate chain and private key
+	CertFile string
+	KeyFile  string
+	// SelfSignedDir, used when CertFile is empty, is where a self-signed
+	// certificate is generated on first start and kept afterwards
+	SelfSignedDir string
+	// ClientCAFile, when set, requires every client to present a
+	// certificate signed by one of the PEM CAs it contains
+	ClientCAFile string
+}
+
+// Enabled reports whether the options turn on TLS
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.SelfSignedDir != ""
+}
+
+// fileStamp identifies a version of a file by its modification time and
+// size, the way the Authenticator detects keys file changes
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// certificateReloader serves the certificate, key and client CAs from
+// their files, rereading them when they change so a renewed certificate
+// takes effect on the next handshake without a restart. If the new files
+// can't be loaded, the previous ones stay in use.
+type certificateReloader struct {
+	opts TLSOptions
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    [3]fileStamp
+	failed    [3]fileStamp
+}
+
+// newCertificateReloader loads the files named by opts, which must name a
+// certificate and key
+func newCertificateReloader(opts TLSOptions) (*certificateReloader, error) {
+	c := &certificateReloader{opts: opts}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload rereads the files if any of them changed since the last load
+func (c *certificateReloader) reload() error {
+	paths := [3]string{c.opts.CertFile, c.opts.KeyFile, c.opts.ClientCAFile}
+	var stamps [3]fileStamp
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		stamp, err := statFile(path)
+		if err != nil {
+			return err
+		}
+		stamps[i] = stamp
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cert != nil && (stamps == c.stamps || stamps == c.failed) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.opts.CertFile, c.opts.KeyFile)
+	if err != nil {
+		c.failed = stamps
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.opts.ClientCAFile)
+		if err != nil {
+			c.failed = stamps
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			c.failed = stamps
+			return fmt.Errorf("no PEM certificates found in client CA file %s", c.opts.ClientCAFile)
+		}
+	}
+
+	if c.cert != nil {
+		log.Printf("[INFO] Reloaded TLS certificate from %s", c.opts.CertFile)
+	}
+	c.cert, c.clientCAs, c.stamps = &cert, clientCAs, stamps
+	return nil
+}
+
+// config returns the TLS configuration for one handshake, reloading
+// changed files first
+func (c *certificateReloader) config() *tls.Config {
+	if err := c.reload(); err != nil {
+		log.Printf("[WARN] %v; still using the previous certificate", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.cert},
+	}
+	if c.clientCAs != nil {
+		config.ClientCAs = c.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// serverConfig returns a TLS configuration for the given application
+// protocols, whose certificates are picked per handshake
+func (c *certificateReloader) serverConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := c.config()
+			config.NextProtos = nextProtos
+			return config, nil
+		},
+		// Never used for handshakes; tells http.Server certificates exist
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &c.config().Certificates[0], nil
+		},
+	}
+}
+
+// EnsureSelfSignedCertificate returns the certificate and key files in
+// dir, generating a self-signed certificate for hosts when there is none,
+// the existing one expires within 30 days, or it is a CA certificate as
+// generated by earlier versions
+func EnsureSelfSignedCertificate(dir string, hosts []string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, SelfSignedCertFile)
+	keyFile = filepath.Join(dir, SelfSignedKeyFile)
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && !leaf.IsCA && time.Until(leaf.NotAfter) > selfSignedRenewBefore {
+			return certFile, keyFile, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("[WARN] Replacing unreadable self-signed certificate in %s: %v", dir, err)
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(hosts, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create TLS directory: %w", err)
+	}
+	// The key is written first so the certificate never pairs with a stale key
+	if err := writeFileAtomic(keyFile, keyPEM, 0600); err != nil {
+		return "", "", err
+	}
+	if err := writeFileAtomic(certFile, certPEM, 0644); err != nil {
+		return "", "", err
+	}
+
+	block, _ := pem.Decode(certPEM)
+	fingerprint := sha256.Sum256(block.Bytes)
+	log.Printf("[INFO] Generated self-signed TLS certificate %s (SHA-256 fingerprint %s)", certFile, hex.EncodeToString(fingerprint[:]))
+	return certFile, keyFile, nil
+}
+
+// generateSelfSigned returns a PEM certificate and ECDSA P-256 key valid
+// for the given host names and IP addresses. It is a leaf certificate, so
+// a client that trusts it can't be made to trust others signed with its
+// key.
+func generateSelfSigned(hosts []string, now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate TLS key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate certificate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"VibeSQL"}, CommonName: "vibe self-signed"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create TLS certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode TLS key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// selfSignedHosts returns the names a self-signed certificate for the
+// bind host should cover: the loopback names, the machine's host name,
+// and the bind address, or every interface address when binding to all
+// of them
+func selfSignedHosts(bindHost string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil {
+		hosts = append(hosts, name)
+	}
+
+	ip := net.ParseIP(bindHost)
+	switch {
+	case ip != nil && ip.IsUnspecified():
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			break
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	case !isLoopback(bindHost):
+		hosts = append(hosts, bindHost)
+	}
+	return hosts
+}
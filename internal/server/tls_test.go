@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSigned writes a new self-signed certificate and key for
+// localhost to certFile and keyFile
+func writeSelfSigned(t *testing.T, certFile, keyFile string) []byte {
+	t.Helper()
+	certPEM, keyPEM, err := generateSelfSigned([]string{"localhost", "127.0.0.1"}, time.Now())
+	if err != nil {
+		t.Fatalf("generateSelfSigned() error = %v", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPEM
+}
+
+func TestGenerateSelfSigned_Leaf(t *testing.T) {
+	certPEM, _, err := generateSelfSigned([]string{"localhost"}, time.Now())
+	if err != nil {
+		t.Fatalf("generateSelfSigned() error = %v", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cert.IsCA || !cert.BasicConstraintsValid {
+		t.Errorf("Expected a leaf certificate, got IsCA=%v BasicConstraintsValid=%v", cert.IsCA, cert.BasicConstraintsValid)
+	}
+	if cert.KeyUsage&x509.KeyUsageCertSign != 0 {
+		t.Error("Expected no certificate signing key usage")
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("Expected only server authentication, got %v", cert.ExtKeyUsage)
+	}
+}
+
+func TestEnsureSelfSignedCertificate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+
+	certFile, keyFile, err := EnsureSelfSignedCertificate(dir, []string{"localhost", "127.0.0.1", "devbox"})
+	if err != nil {
+		t.Fatalf("EnsureSelfSignedCertificate() error = %v", err)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("Expected a key file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected key file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Generated certificate doesn't load: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "devbox"} {
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("Expected the certificate to cover %s: %v", host, err)
+		}
+	}
+
+	first, _ := os.ReadFile(certFile)
+	if _, _, err := EnsureSelfSignedCertificate(dir, nil); err != nil {
+		t.Fatalf("EnsureSelfSignedCertificate() error = %v", err)
+	}
+	second, _ := os.ReadFile(certFile)
+	if !bytes.Equal(first, second) {
+		t.Error("Expected the existing certificate to be kept")
+	}
+}
+
+func TestCertificateReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSigned(t, certFile, keyFile)
+
+	certs, err := newCertificateReloader(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("newCertificateReloader() error = %v", err)
+	}
+	before := certs.config().Certificates[0].Certificate[0]
+
+	writeSelfSigned(t, certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	after := certs.config().Certificates[0].Certificate[0]
+	if bytes.Equal(before, after) {
+		t.Error("Expected the renewed certificate to be served")
+	}
+
+	// A broken certificate keeps the previous one in use
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if current := certs.config().Certificates[0].Certificate[0]; !bytes.Equal(current, after) {
+		t.Error("Expected the previous certificate after a failed reload")
+	}
+}
+
+func TestCertificateReloader_ClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeSelfSigned(t, certFile, keyFile)
+
+	if _, err := newCertificateReloader(TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}); err == nil {
+		t.Error("Expected an error for a client CA file without certificates")
+	}
+
+	certs, err := newCertificateReloader(TLSOptions{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile})
+	if err != nil {
+		t.Fatalf("newCertificateReloader() error = %v", err)
+	}
+	if config := certs.config(); config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Errorf("Expected client certificates to be required, got %v", config.ClientAuth)
+	}
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := writeSelfSigned(t, certFile, keyFile)
+
+	server := NewServerWithOptions(&mockExecutor{}, Options{
+		Port: 6001,
+		TLS:  TLSOptions{CertFile: certFile, KeyFile: keyFile},
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	if got, want := server.URL(), "https://"+server.Addr(); got != want {
+		t.Errorf("URL() = %s, want %s", got, want)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+		Timeout:   5 * time.Second,
+	}
+
+	resp, err := client.Get(server.URL() + "/v1/health")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// Plain HTTP gets the TLS server's canned 400 response
+	resp, err = http.Get("http://" + server.Addr() + "/v1/health")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected plain HTTP to be refused, got %d", resp.StatusCode)
+		}
+	}
+}
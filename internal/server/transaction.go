@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vibesql/vibe/internal/query"
+)
+
+// HandleTransaction runs an ordered list of statements in a single
+// transaction, rolling back all of them if any statement fails
+func (h *Handler) HandleTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		err := NewInvalidSQLError("Only POST method is supported for /v1/transaction endpoint")
+		WriteError(w, err)
+		log.Printf("[ERROR] Method not allowed: %s %s", r.Method, r.URL.Path)
+		return
+	}
+
+	var req TransactionRequest
+	if vibeErr := decodeJSONBody(r, &req); vibeErr != nil {
+		WriteError(w, vibeErr)
+		log.Printf("[ERROR] Invalid request body: %v", vibeErr)
+		return
+	}
+
+	if len(req.Statements) == 0 {
+		WriteError(w, NewMissingFieldError("statements"))
+		log.Printf("[ERROR] Missing required field: statements")
+		return
+	}
+
+	if len(req.Statements) > query.MaxTransactionStatements {
+		WriteError(w, NewTooManyStatementsError(len(req.Statements), query.MaxTransactionStatements))
+		log.Printf("[ERROR] Too many statements in transaction: %d", len(req.Statements))
+		return
+	}
+
+	if !isValidFormat(req.Format) {
+		WriteError(w, NewInvalidFormatError(req.Format))
+		log.Printf("[ERROR] Invalid result format: %s", req.Format)
+		return
+	}
+
+	policy := h.policyFor(r)
+	statements, ok := h.readStatements(w, req.Statements, policy)
+	if !ok {
+		return
+	}
+
+	log.Printf("[INFO] Executing transaction with %d statements", len(statements))
+
+	opts := query.Options{
+		NumericAsString: req.NumericAsString,
+		ReadOnly:        policy.ReadOnly,
+		Role:            policy.Role,
+	}
+
+	startTime := time.Now()
+	results, err := h.executor.ExecuteTransaction(statements, opts)
+	if err != nil {
+		var stmtErr *query.StatementError
+		if errors.As(err, &stmtErr) {
+			WriteStatementError(w, stmtErr.Err, stmtErr.Index)
+		} else {
+			WriteError(w, asVibeError(err))
+		}
+		log.Printf("[ERROR] Transaction rolled back: %v", err)
+		return
+	}
+
+	response := &TransactionResponse{
+		Success:       true,
+		Results:       make([]StatementResult, len(results)),
+		ExecutionTime: float64(time.Since(startTime).Microseconds()) / 1000.0,
+	}
+	for i, result := range results {
+		response.Results[i] = NewStatementResult(result, req.Format)
+	}
+
+	if err := WriteJSON(w, http.StatusOK, response); err != nil {
+		log.Printf("[ERROR] Failed to write response: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Transaction committed: %d statements in %.2fms", len(results), response.ExecutionTime)
+}
+
+// readStatements validates, safety-checks and binds the statements of a
+// multi-statement request, writing an error response identifying the
+// first rejected statement and returning ok=false if any is rejected
+func (h *Handler) readStatements(w http.ResponseWriter, reqs []StatementRequest, policy query.Policy) (statements []query.Statement, ok bool) {
+	statements = make([]query.Statement, len(reqs))
+	for i, stmt := range reqs {
+		if stmt.SQL == "" {
+			WriteStatementError(w, NewMissingFieldError("sql"), i)
+			log.Printf("[ERROR] Statement %d: missing required field: sql", i)
+			return nil, false
+		}
+
+		if err := h.limits.ValidateQuery(stmt.SQL); err != nil {
+			WriteStatementError(w, asVibeError(err), i)
+			log.Printf("[ERROR] Statement %d: query validation failed: %v", i, err)
+			return nil, false
+		}
+
+		if err := policy.Check(stmt.SQL); err != nil {
+			WriteStatementError(w, asVibeError(err), i)
+			log.Printf("[ERROR] Statement %d: query safety check failed (%s policy): %v", i, policy.Name(), err)
+			return nil, false
+		}
+
+		params, err := query.BindParams(stmt.Params)
+		if err != nil {
+			WriteStatementError(w, NewInvalidParamsError(err.Error()), i)
+			log.Printf("[ERROR] Statement %d: invalid query params: %v", i, err)
+			return nil, false
+		}
+		if err := query.CheckParams(stmt.SQL, params); err != nil {
+			WriteStatementError(w, NewInvalidParamsError(err.Error()), i)
+			log.Printf("[ERROR] Statement %d: invalid query params: %v", i, err)
+			return nil, false
+		}
+
+		statements[i] = query.Statement{SQL: stmt.SQL, Params: params}
+	}
+	return statements, true
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vibesql/vibe/internal/postgres"
+	"github.com/vibesql/vibe/internal/query"
+)
+
+type txExecutor struct {
+	mockExecutor
+	statements []query.Statement
+	failAt     int
+}
+
+func (e *txExecutor) ExecuteTransaction(statements []query.Statement, opts query.Options) ([]*query.ExecutionResult, error) {
+	e.statements = statements
+	if e.failAt >= 0 && e.failAt < len(statements) {
+		return nil, &query.StatementError{
+			Index: e.failAt,
+			Err:   postgres.NewVibeError(postgres.ErrorCodeInvalidSQL, "Invalid SQL syntax", "PostgreSQL error: relation \"missing\" does not exist"),
+		}
+	}
+
+	results := make([]*query.ExecutionResult, len(statements))
+	for i := range statements {
+		results[i] = &query.ExecutionResult{
+			Columns:  []query.Column{{Name: "balance", Type: "int4", TypeOID: 23}},
+			Rows:     []map[string]interface{}{{"balance": int64(i)}},
+			Values:   [][]interface{}{{int64(i)}},
+			RowCount: 1,
+		}
+	}
+	return results, nil
+}
+
+func postTransaction(t *testing.T, handler *Handler, body string) (*httptest.ResponseRecorder, TransactionResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/transaction", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.HandleTransaction(w, req)
+
+	var response TransactionResponse
+	if err := json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w, response
+}
+
+func TestHandleTransaction_Success(t *testing.T) {
+	executor := &txExecutor{failAt: -1}
+	handler := NewHandler(executor)
+
+	body := `{"statements": [
+		{"sql": "UPDATE accounts SET balance = balance - $1 WHERE id = $2 RETURNING balance", "params": [100, 1]},
+		{"sql": "UPDATE accounts SET balance = balance + $1 WHERE id = $2 RETURNING balance", "params": [100, 2]}
+	]}`
+
+	w, response := postTransaction(t, handler, body)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if !response.Success {
+		t.Fatalf("Expected success=true, got error %+v", response.Error)
+	}
+
+	if len(response.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(response.Results))
+	}
+
+	if len(executor.statements) != 2 {
+		t.Fatalf("Expected 2 statements passed to executor, got %d", len(executor.statements))
+	}
+
+	if executor.statements[1].Params[0] != int64(100) {
+		t.Errorf("Expected bound param int64(100), got %v (%T)", executor.statements[1].Params[0], executor.statements[1].Params[0])
+	}
+
+	if response.Results[1].RowCount != 1 || len(response.Results[1].Columns) != 1 {
+		t.Errorf("Expected result with 1 row and 1 column, got %+v", response.Results[1])
+	}
+}
+
+func TestHandleTransaction_StatementFailure(t *testing.T) {
+	executor := &txExecutor{failAt: 1}
+	handler := NewHandler(executor)
+
+	body := `{"statements": [{"sql": "INSERT INTO logs (msg) VALUES ('a')"}, {"sql": "SELECT * FROM missing"}]}`
+
+	w, response := postTransaction(t, handler, body)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	if response.Success {
+		t.Fatal("Expected success=false")
+	}
+
+	if response.Error == nil || response.Error.Statement == nil {
+		t.Fatalf("Expected error with failing statement index, got %+v", response.Error)
+	}
+
+	if *response.Error.Statement != 1 {
+		t.Errorf("Expected failing statement 1, got %d", *response.Error.Statement)
+	}
+
+	if response.Error.Code != postgres.ErrorCodeInvalidSQL {
+		t.Errorf("Expected error code %s, got %s", postgres.ErrorCodeInvalidSQL, response.Error.Code)
+	}
+}
+
+func TestHandleTransaction_UnsafeStatementRejected(t *testing.T) {
+	executor := &txExecutor{failAt: -1}
+	handler := NewHandler(executor)
+
+	body := `{"statements": [{"sql": "SELECT 1"}, {"sql": "DELETE FROM users"}]}`
+
+	w, response := postTransaction(t, handler, body)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	if response.Error == nil || response.Error.Code != postgres.ErrorCodeUnsafeQuery {
+		t.Fatalf("Expected UNSAFE_QUERY error, got %+v", response.Error)
+	}
+
+	if response.Error.Statement == nil || *response.Error.Statement != 1 {
+		t.Errorf("Expected failing statement 1, got %v", response.Error.Statement)
+	}
+
+	if executor.statements != nil {
+		t.Error("Executor should not run when a statement fails validation")
+	}
+}
+
+func TestHandleTransaction_TransactionControlRejected(t *testing.T) {
+	executor := &txExecutor{failAt: -1}
+	handler := NewHandler(executor)
+
+	w, response := postTransaction(t, handler, `{"statements": [{"sql": "BEGIN"}, {"sql": "SELECT 1"}]}`)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+
+	if response.Error == nil || response.Error.Statement == nil || *response.Error.Statement != 0 {
+		t.Errorf("Expected statement 0 to be rejected, got %+v", response.Error)
+	}
+}
+
+func TestHandleTransaction_MissingStatements(t *testing.T) {
+	handler := NewHandler(&txExecutor{failAt: -1})
+
+	w, response := postTransaction(t, handler, `{"statements": []}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	if response.Error == nil || response.Error.Code != postgres.ErrorCodeMissingRequiredField {
+		t.Errorf("Expected MISSING_REQUIRED_FIELD error, got %+v", response.Error)
+	}
+}
+
+func TestHandleTransaction_TooManyStatements(t *testing.T) {
+	handler := NewHandler(&txExecutor{failAt: -1})
+
+	statements := make([]StatementRequest, query.MaxTransactionStatements+1)
+	for i := range statements {
+		statements[i] = StatementRequest{SQL: "SELECT 1"}
+	}
+	body, _ := json.Marshal(TransactionRequest{Statements: statements})
+
+	w, _ := postTransaction(t, handler, string(body))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", w.Code)
+	}
+}
+
+func TestHandleTransaction_MethodNotAllowed(t *testing.T) {
+	handler := NewHandler(&txExecutor{failAt: -1})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/transaction", nil)
+	w := httptest.NewRecorder()
+
+	handler.HandleTransaction(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}